@@ -0,0 +1,81 @@
+// Package clientip finds the address of the client behind the proxies in front of the
+// servers.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Resolver believes the X-Forwarded-For entries added by trusted proxies. Loopback
+// addresses are always trusted, as the REST gateway calls the gRPC server through one.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// NewResolver trusts proxies, given as addresses or CIDR ranges.
+func NewResolver(proxies []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, p := range proxies {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(p)
+			if aerr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", p)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+	return r, nil
+}
+
+// Resolve returns the client address, given the remote address of the connection, with
+// or without a port, and the X-Forwarded-For values of the request. Entries are read
+// from the right for as long as they were added by a trusted proxy, since those further
+// left could have been sent by the client.
+func (r *Resolver) Resolve(remoteAddr string, forwardedFor []string) string {
+	addr, ok := parseAddr(remoteAddr)
+	if !ok {
+		return remoteAddr
+	}
+
+	var hops []string
+	for _, v := range forwardedFor {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0 && r.isTrusted(addr); i-- {
+		next, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			break
+		}
+		addr = next
+	}
+	return addr.String()
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	if addr.IsLoopback() {
+		return true
+	}
+	for _, p := range r.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
@@ -0,0 +1,117 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// maxComplexity bounds the work of a single operation. Every selected field costs 1,
+// and the selections below a paginated list are counted once per requested item, so
+// { todos(limit: 100) { owner { todos(limit: 100) { id } } } } costs well over it.
+const maxComplexity = 1000
+
+var errTooComplex = fmt.Errorf("query is too complex: the maximum complexity is %d", maxComplexity)
+
+// analyzer parses operations with gqlparser, which exposes the resolved selection
+// tree that graph-gophers keeps internal.
+type analyzer struct {
+	schema *ast.Schema
+}
+
+func newAnalyzer(sdl string) (*analyzer, error) {
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: sdl})
+	if err != nil {
+		return nil, err
+	}
+	return &analyzer{schema: schema}, nil
+}
+
+// operation returns the selected operation of query. ok is false for invalid
+// documents, which are left for the executor to reject with a proper error.
+func (a *analyzer) operation(query, operationName string) (op *ast.OperationDefinition, ok bool) {
+	doc, errs := gqlparser.LoadQuery(a.schema, query)
+	if len(errs) > 0 {
+		return nil, false
+	}
+
+	if operationName == "" && len(doc.Operations) != 1 {
+		return nil, false
+	}
+	if operationName == "" {
+		return doc.Operations[0], true
+	}
+
+	op = doc.Operations.ForName(operationName)
+	return op, op != nil
+}
+
+func (a *analyzer) checkComplexity(op *ast.OperationDefinition, vars map[string]any) (err error) {
+	// ArgumentMap panics on values that don't match their variable types.
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("invalid variables")
+		}
+	}()
+
+	w := &complexityWalker{vars: vars}
+	if w.selectionSet(op.SelectionSet) > maxComplexity || w.visited > maxComplexity {
+		return errTooComplex
+	}
+	return nil
+}
+
+type complexityWalker struct {
+	vars map[string]any
+	// visited stops the walk early, since fragments can make the document much smaller
+	// than the tree it describes.
+	visited int
+}
+
+func (w *complexityWalker) selectionSet(set ast.SelectionSet) int {
+	total := 0
+	for _, sel := range set {
+		if w.visited > maxComplexity {
+			return total
+		}
+
+		switch sel := sel.(type) {
+		case *ast.Field:
+			w.visited++
+			total += 1 + w.selectionSet(sel.SelectionSet)*w.multiplier(sel)
+		case *ast.InlineFragment:
+			total += w.selectionSet(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				total += w.selectionSet(sel.Definition.SelectionSet)
+			}
+		}
+
+		if total > maxComplexity {
+			return total
+		}
+	}
+	return total
+}
+
+func (w *complexityWalker) multiplier(f *ast.Field) int {
+	if f.Definition == nil || f.Definition.Type.Elem == nil {
+		return 1
+	}
+
+	switch limit := f.ArgumentMap(w.vars)["limit"].(type) {
+	case int64:
+		return clampMultiplier(int(limit))
+	case float64:
+		return clampMultiplier(int(limit))
+	case int:
+		return clampMultiplier(limit)
+	}
+	return 1
+}
+
+func clampMultiplier(n int) int {
+	return max(1, min(n, maxPageSize))
+}
@@ -0,0 +1,96 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	loaderWait     = 2 * time.Millisecond
+	loaderMaxBatch = 100
+)
+
+// loader batches the keys requested by concurrently executing resolvers into a single
+// fetch, and caches the results for the rest of the request. GraphQL resolves the
+// fields of list elements in parallel, so this turns N lookups into one query.
+type loader[K comparable, V any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	cache   map[K]*loadResult[V]
+	pending []K
+}
+
+type loadResult[V any] struct {
+	done  chan struct{}
+	value V
+	found bool
+	err   error
+}
+
+func newLoader[K comparable, V any](ctx context.Context, fetch func(context.Context, []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		ctx:   ctx,
+		fetch: fetch,
+		cache: make(map[K]*loadResult[V]),
+	}
+}
+
+// load returns the value for key, and whether it exists.
+func (l *loader[K, V]) load(ctx context.Context, key K) (V, bool, error) {
+	l.mu.Lock()
+	res, ok := l.cache[key]
+	if !ok {
+		res = &loadResult[V]{done: make(chan struct{})}
+		l.cache[key] = res
+		l.pending = append(l.pending, key)
+
+		switch len(l.pending) {
+		case loaderMaxBatch:
+			l.dispatchLocked()
+		case 1:
+			time.AfterFunc(loaderWait, l.dispatch)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-res.done:
+		return res.value, res.found, res.err
+	case <-ctx.Done():
+		var zero V
+		return zero, false, ctx.Err()
+	}
+}
+
+func (l *loader[K, V]) dispatch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.dispatchLocked()
+}
+
+func (l *loader[K, V]) dispatchLocked() {
+	if len(l.pending) == 0 {
+		return
+	}
+
+	keys := l.pending
+	l.pending = nil
+	results := make([]*loadResult[V], len(keys))
+	for i, key := range keys {
+		results[i] = l.cache[key]
+	}
+
+	go func() {
+		values, err := l.fetch(l.ctx, keys)
+		for i, key := range keys {
+			res := results[i]
+			res.value, res.found = values[key]
+			res.err = err
+			close(res.done)
+		}
+	}()
+}
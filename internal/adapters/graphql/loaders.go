@@ -0,0 +1,62 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+)
+
+// loaders are created for every request, so that cached values never outlive it.
+type loaders struct {
+	todos *loader[models.ToDoID, models.ToDo]
+	users *loader[models.UserID, models.User]
+}
+
+func newLoaders(ctx context.Context, todoUC *usecase.TodoUsecase, userUC *usecase.UserUseCase) *loaders {
+	return &loaders{
+		todos: newLoader(ctx, func(ctx context.Context, ids []models.ToDoID) (map[models.ToDoID]models.ToDo, error) {
+			todos, err := todoUC.GetTodosByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+
+			byID := make(map[models.ToDoID]models.ToDo, len(todos))
+			for _, todo := range todos {
+				byID[todo.ID] = todo
+			}
+			return byID, nil
+		}),
+		users: newLoader(ctx, func(ctx context.Context, ids []models.UserID) (map[models.UserID]models.User, error) {
+			users, err := userUC.GetUsersByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+
+			byID := make(map[models.UserID]models.User, len(users))
+			for _, user := range users {
+				byID[user.ID] = user
+			}
+			return byID, nil
+		}),
+	}
+}
+
+type requestKey struct{}
+
+type request struct {
+	userID models.UserID
+	// readOnly is set for read-only tokens, which can't run mutations.
+	readOnly bool
+	// scopes are those of a personal access token, and nil for logins.
+	scopes  []models.Scope
+	loaders *loaders
+}
+
+func withRequest(ctx context.Context, req *request) context.Context {
+	return context.WithValue(ctx, requestKey{}, req)
+}
+
+func requestFrom(ctx context.Context) *request {
+	return ctx.Value(requestKey{}).(*request)
+}
@@ -0,0 +1,331 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/stream"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+)
+
+const maxPageSize = 100
+
+var (
+	errForbidden     = errors.New("forbidden")
+	errInvalidLimit  = errors.New("limit must be between 1 and 100")
+	errInvalidOffset = errors.New("offset must not be negative")
+	errShuttingDown  = errors.New("server is shutting down")
+)
+
+type resolver struct {
+	todoUC *usecase.TodoUsecase
+	userUC *usecase.UserUseCase
+	hub    *stream.Hub
+}
+
+type pageArgs struct {
+	Limit  int32
+	Offset int32
+}
+
+func parseID(id graphql.ID) (int64, error) {
+	n, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, e.ErrInvalidIdentifier
+	}
+	return n, nil
+}
+
+func formatID(id int64) graphql.ID {
+	return graphql.ID(strconv.FormatInt(id, 10))
+}
+
+func (r *resolver) Me(ctx context.Context) (*userResolver, error) {
+	req := requestFrom(ctx)
+	return r.user(ctx, req.loaders, req.userID)
+}
+
+func (r *resolver) user(ctx context.Context, l *loaders, id models.UserID) (*userResolver, error) {
+	user, ok, err := l.users.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, e.ErrUserNotFound
+	}
+	return &userResolver{root: r, user: user}, nil
+}
+
+// Todo returns null for todos of other users, so that their IDs can't be probed.
+func (r *resolver) Todo(ctx context.Context, args struct{ ID graphql.ID }) (*todoResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	req := requestFrom(ctx)
+	todo, ok, err := req.loaders.todos.load(ctx, models.ToDoID(id))
+	if err != nil {
+		return nil, err
+	}
+	if !ok || todo.UserID != req.userID {
+		return nil, nil
+	}
+	return &todoResolver{root: r, loaders: req.loaders, todo: todo}, nil
+}
+
+func (r *resolver) Todos(ctx context.Context, args pageArgs) ([]*todoResolver, error) {
+	return r.listTodos(ctx, requestFrom(ctx).userID, args)
+}
+
+func (r *resolver) listTodos(ctx context.Context, userID models.UserID, args pageArgs) ([]*todoResolver, error) {
+	if args.Limit < 1 || args.Limit > maxPageSize {
+		return nil, errInvalidLimit
+	}
+	if args.Offset < 0 {
+		return nil, errInvalidOffset
+	}
+
+	todos, err := r.todoUC.ListTodos(ctx, dto.GetListTodosRequest{
+		UserID: userID,
+		Limit:  int(args.Limit),
+		Offset: int(args.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l := requestFrom(ctx).loaders
+	res := make([]*todoResolver, len(todos))
+	for i, todo := range todos {
+		res[i] = &todoResolver{root: r, loaders: l, todo: todo}
+	}
+	return res, nil
+}
+
+func (r *resolver) ownTodo(ctx context.Context, id graphql.ID) (models.ToDo, error) {
+	n, err := parseID(id)
+	if err != nil {
+		return models.ToDo{}, err
+	}
+
+	todo, err := r.todoUC.GetTodoByID(ctx, models.ToDoID(n))
+	if err != nil {
+		return models.ToDo{}, err
+	}
+	if todo.UserID != requestFrom(ctx).userID {
+		return models.ToDo{}, e.ErrTodoNotFound
+	}
+	return todo, nil
+}
+
+type createTodoInput struct {
+	Title       string
+	Description *string
+}
+
+func (r *resolver) CreateTodo(ctx context.Context, args struct{ Input createTodoInput }) (*todoResolver, error) {
+	if err := writable(ctx); err != nil {
+		return nil, err
+	}
+
+	req := dto.CreateTodoRequest{
+		UserID: requestFrom(ctx).userID,
+		Title:  args.Input.Title,
+	}
+	if args.Input.Description != nil {
+		req.Description = *args.Input.Description
+	}
+
+	id, err := r.todoUC.CreateTodo(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	todo, err := r.todoUC.GetTodoByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &todoResolver{root: r, loaders: requestFrom(ctx).loaders, todo: todo}, nil
+}
+
+func writable(ctx context.Context) error {
+	req := requestFrom(ctx)
+	if req.readOnly {
+		return e.ErrEmailNotVerified
+	}
+	if req.scopes != nil && !slices.Contains(req.scopes, models.ScopeTodosWrite) {
+		return e.ErrInsufficientScope
+	}
+	return nil
+}
+
+type updateTodoInput struct {
+	Title       string
+	Description *string
+	Completed   *bool
+}
+
+func (r *resolver) UpdateTodo(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input updateTodoInput
+}) (*todoResolver, error) {
+	if err := writable(ctx); err != nil {
+		return nil, err
+	}
+
+	todo, err := r.ownTodo(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	todo.Title = args.Input.Title
+	todo.Description = ""
+	if args.Input.Description != nil {
+		todo.Description = *args.Input.Description
+	}
+	todo.Completed = args.Input.Completed != nil && *args.Input.Completed
+
+	if err := r.todoUC.UpdateTodo(ctx, todo); err != nil {
+		return nil, err
+	}
+
+	todo, err = r.todoUC.GetTodoByID(ctx, todo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &todoResolver{root: r, loaders: requestFrom(ctx).loaders, todo: todo}, nil
+}
+
+func (r *resolver) DeleteTodo(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if err := writable(ctx); err != nil {
+		return false, err
+	}
+
+	todo, err := r.ownTodo(ctx, args.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.todoUC.DeleteTodoByID(ctx, todo.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TodoChanged ends when the client falls behind or the server shuts down; clients
+// resubscribe and reload, as with the other live APIs.
+func (r *resolver) TodoChanged(ctx context.Context) (<-chan *todoEventResolver, error) {
+	sub, _, _, ok := r.hub.Subscribe(requestFrom(ctx).userID, 0)
+	if !ok {
+		return nil, errShuttingDown
+	}
+
+	ch := make(chan *todoEventResolver)
+	go func() {
+		defer close(ch)
+		defer sub.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-sub.Events:
+				if !ok {
+					return
+				}
+
+				var data models.TodoEventData
+				if err := json.Unmarshal(event.Payload, &data); err != nil {
+					continue
+				}
+
+				// Every event is resolved with fresh loaders, so that it doesn't see
+				// values cached for an earlier one.
+				res := &todoEventResolver{
+					root:    r,
+					loaders: newLoaders(ctx, r.todoUC, r.userUC),
+					event:   event,
+					data:    data,
+				}
+
+				select {
+				case ch <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+type todoResolver struct {
+	root    *resolver
+	loaders *loaders
+	todo    models.ToDo
+}
+
+func (t *todoResolver) ID() graphql.ID          { return formatID(int64(t.todo.ID)) }
+func (t *todoResolver) Title() string           { return t.todo.Title }
+func (t *todoResolver) Description() string     { return t.todo.Description }
+func (t *todoResolver) Completed() bool         { return t.todo.Completed }
+func (t *todoResolver) CreatedAt() graphql.Time { return graphql.Time{Time: t.todo.CreatedAt} }
+func (t *todoResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: t.todo.UpdatedAt} }
+
+func (t *todoResolver) Owner(ctx context.Context) (*userResolver, error) {
+	return t.root.user(ctx, t.loaders, t.todo.UserID)
+}
+
+type userResolver struct {
+	root *resolver
+	user models.User
+}
+
+func (u *userResolver) ID() graphql.ID   { return formatID(int64(u.user.ID)) }
+func (u *userResolver) Username() string { return u.user.Username }
+
+func (u *userResolver) Email(ctx context.Context) *string {
+	if u.user.ID != requestFrom(ctx).userID {
+		return nil
+	}
+	return &u.user.Email
+}
+
+func (u *userResolver) Todos(ctx context.Context, args pageArgs) ([]*todoResolver, error) {
+	if u.user.ID != requestFrom(ctx).userID {
+		return nil, errForbidden
+	}
+	return u.root.listTodos(ctx, u.user.ID, args)
+}
+
+type todoEventResolver struct {
+	root    *resolver
+	loaders *loaders
+	event   models.Event
+	data    models.TodoEventData
+}
+
+func (t *todoEventResolver) Sequence() graphql.ID     { return formatID(t.event.Sequence) }
+func (t *todoEventResolver) Type() string             { return string(t.event.Type) }
+func (t *todoEventResolver) OccurredAt() graphql.Time { return graphql.Time{Time: t.event.OccurredAt} }
+
+func (t *todoEventResolver) Todo() *todoResolver {
+	return &todoResolver{root: t.root, loaders: t.loaders, todo: models.ToDo{
+		ID:          t.data.ID,
+		UserID:      t.data.UserID,
+		Title:       t.data.Title,
+		Description: t.data.Description,
+		Completed:   t.data.Completed,
+		CreatedAt:   t.data.CreatedAt,
+		UpdatedAt:   t.data.UpdatedAt,
+	}}
+}
@@ -0,0 +1,186 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/stream"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.uber.org/zap"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const maxDepth = 10
+
+// Server is the GraphQL API. Queries and mutations are served over HTTP, and all
+// operations, including subscriptions, over WebSocket with the graphql-transport-ws
+// protocol.
+type Server struct {
+	schema   *graphql.Schema
+	analyzer *analyzer
+	todoUC   *usecase.TodoUsecase
+	userUC   *usecase.UserUseCase
+	tokens   *usecase.AccessTokenUsecase
+	logger   *zap.Logger
+	upgrader websocket.Upgrader
+
+	mu     sync.Mutex
+	conns  map[*wsConn]struct{}
+	closed bool
+}
+
+func NewServer(todoUC *usecase.TodoUsecase, userUC *usecase.UserUseCase, hub *stream.Hub, tokens *usecase.AccessTokenUsecase, logger *zap.Logger) (*Server, error) {
+	schema, err := graphql.ParseSchema(schemaSDL,
+		&resolver{todoUC: todoUC, userUC: userUC, hub: hub},
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(maxDepth),
+		graphql.Logger(panicLogger{logger}),
+		graphql.PanicHandler(panicLogger{logger}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzer, err := newAnalyzer(schemaSDL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		schema:   schema,
+		analyzer: analyzer,
+		todoUC:   todoUC,
+		userUC:   userUC,
+		tokens:   tokens,
+		logger:   logger,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			Subprotocols:    []string{subprotocol},
+		},
+		conns: make(map[*wsConn]struct{}),
+	}, nil
+}
+
+type gqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Handle serves queries and mutations over HTTP. It expects the JWT middleware to have
+// authenticated the caller.
+func (s *Server) Handle(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req gqlRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	op, err := s.check(req)
+	if err != nil {
+		c.JSON(http.StatusOK, errorResponse(err))
+		return
+	}
+	if op != nil && op.Operation == ast.Subscription {
+		c.JSON(http.StatusBadRequest, errorResponse(errors.New("subscriptions are only supported over WebSocket")))
+		return
+	}
+
+	value, _ := c.Get("scopes")
+	scopes, _ := value.([]models.Scope)
+	ctx := s.requestContext(c.Request.Context(), userID.(models.UserID), c.GetBool("read_only"), scopes)
+	res := s.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	c.JSON(http.StatusOK, s.present(res))
+}
+
+// check enforces the complexity limit. It returns a nil operation for documents that
+// the analyzer can't resolve; the executor reports their errors.
+func (s *Server) check(req gqlRequest) (*ast.OperationDefinition, error) {
+	op, ok := s.analyzer.operation(req.Query, req.OperationName)
+	if !ok {
+		return nil, nil
+	}
+	if err := s.analyzer.checkComplexity(op, req.Variables); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (s *Server) requestContext(ctx context.Context, userID models.UserID, readOnly bool, scopes []models.Scope) context.Context {
+	return withRequest(ctx, &request{
+		userID:   userID,
+		readOnly: readOnly,
+		scopes:   scopes,
+		loaders:  newLoaders(ctx, s.todoUC, s.userUC),
+	})
+}
+
+func errorResponse(err error) *graphql.Response {
+	return &graphql.Response{Errors: []*gqlerrors.QueryError{{Message: err.Error()}}}
+}
+
+// present hides the details of unexpected resolver errors from clients.
+func (s *Server) present(res *graphql.Response) *graphql.Response {
+	for _, err := range res.Errors {
+		if err.ResolverError == nil || isClientError(err.ResolverError) {
+			continue
+		}
+
+		s.logger.Error("graphql resolver failed", zap.Any("path", err.Path), zap.Error(err.ResolverError))
+		err.Message = "internal error"
+	}
+	return res
+}
+
+func isClientError(err error) bool {
+	for _, target := range []error{
+		e.ErrTodoNotFound,
+		e.ErrTodoTitleRequired,
+		e.ErrTodoTitleTooLong,
+		e.ErrUserNotFound,
+		e.ErrInvalidIdentifier,
+		e.ErrEmailNotVerified,
+		e.ErrInsufficientScope,
+		errForbidden,
+		errInvalidLimit,
+		errInvalidOffset,
+		errShuttingDown,
+		context.Canceled,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+type panicLogger struct {
+	logger *zap.Logger
+}
+
+func (l panicLogger) LogPanic(_ context.Context, value any) {
+	l.logger.Error("graphql resolver panicked", zap.Any("panic", value))
+}
+
+func (l panicLogger) MakePanicError(_ context.Context, _ any) *gqlerrors.QueryError {
+	return &gqlerrors.QueryError{Message: "internal error"}
+}
@@ -0,0 +1,438 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.uber.org/zap"
+)
+
+// subprotocol is the graphql-transport-ws protocol used by graphql-ws clients:
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const subprotocol = "graphql-transport-ws"
+
+const (
+	writeTimeout   = 10 * time.Second
+	pongTimeout    = 60 * time.Second
+	pingInterval   = pongTimeout * 9 / 10
+	initTimeout    = 10 * time.Second
+	maxMessageSize = 64 * 1024
+	sendBuffer     = 64
+	maxOperations  = 100
+	// authCheckInterval is how often the token of a connection is checked again.
+	authCheckInterval = time.Minute
+)
+
+const (
+	msgConnectionInit = "connection_init"
+	msgConnectionAck  = "connection_ack"
+	msgPing           = "ping"
+	msgPong           = "pong"
+	msgSubscribe      = "subscribe"
+	msgNext           = "next"
+	msgError          = "error"
+	msgComplete       = "complete"
+)
+
+// Close codes defined by the protocol.
+const (
+	closeBadRequest          = 4400
+	closeUnauthorized        = 4401
+	closeForbidden           = 4403
+	closeSubprotocol         = 4406
+	closeInitTimeout         = 4408
+	closeSubscriberExists    = 4409
+	closeTooManyInitRequests = 4429
+	closeTooManyOperations   = 4430
+	closeServiceUnavailable  = websocket.CloseTryAgainLater
+	closeServerShuttingDown  = websocket.CloseGoingAway
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type wsConn struct {
+	srv  *Server
+	conn *websocket.Conn
+
+	send      chan wsMessage
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// userID, readOnly and scopes are set by connection_init and only accessed by the
+	// read loop.
+	userID   models.UserID
+	readOnly bool
+	scopes   []models.Scope
+
+	mu  sync.Mutex
+	ops map[string]context.CancelFunc
+}
+
+// HandleWebSocket serves the graphql-transport-ws protocol. Clients authenticate with
+// the access token in the connection_init payload, as "Authorization": "Bearer <token>"
+// or "access_token": "<token>".
+func (s *Server) HandleWebSocket(c *gin.Context) {
+	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an error response.
+		return
+	}
+
+	if conn.Subprotocol() != subprotocol {
+		closeConn(conn, closeSubprotocol, "Subprotocol not acceptable")
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		closeConn(conn, closeServerShuttingDown, "server shutting down")
+		return
+	}
+	wc := &wsConn{
+		srv:  s,
+		conn: conn,
+		send: make(chan wsMessage, sendBuffer),
+		done: make(chan struct{}),
+		ops:  make(map[string]context.CancelFunc),
+	}
+	s.conns[wc] = struct{}{}
+	s.mu.Unlock()
+
+	go wc.writeLoop()
+	wc.readLoop(c.Request.Context())
+}
+
+// Close disconnects every WebSocket client, which http.Server.Shutdown doesn't track.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	conns := make([]*wsConn, 0, len(s.conns))
+	for wc := range s.conns {
+		conns = append(conns, wc)
+	}
+	s.mu.Unlock()
+
+	for _, wc := range conns {
+		wc.close(closeServerShuttingDown, "server shutting down")
+	}
+}
+
+func closeConn(conn *websocket.Conn, code int, reason string) {
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+	_ = conn.Close()
+}
+
+func (c *wsConn) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		closeConn(c.conn, code, reason)
+
+		c.mu.Lock()
+		ops := c.ops
+		c.ops = nil
+		c.mu.Unlock()
+
+		for _, cancel := range ops {
+			cancel()
+		}
+
+		c.srv.mu.Lock()
+		delete(c.srv.conns, c)
+		c.srv.mu.Unlock()
+	})
+}
+
+func (c *wsConn) enqueue(msg wsMessage) {
+	select {
+	case <-c.done:
+	case c.send <- msg:
+	default:
+		c.close(closeServiceUnavailable, "client too slow")
+	}
+}
+
+func (c *wsConn) writeLoop() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		}
+	}
+}
+
+func (c *wsConn) readLoop(ctx context.Context) {
+	defer c.close(websocket.CloseNormalClosure, "")
+
+	c.conn.SetReadLimit(maxMessageSize)
+	// The connection must be initialised in time; afterwards the deadline only
+	// detects dead peers.
+	_ = c.conn.SetReadDeadline(time.Now().Add(initTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		if c.userID == 0 {
+			return nil
+		}
+		return c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			var (
+				closeErr *websocket.CloseError
+				netErr   interface{ Timeout() bool }
+			)
+			switch {
+			case errors.As(err, &closeErr), errors.Is(err, websocket.ErrCloseSent):
+			case c.userID == 0 && errors.As(err, &netErr) && netErr.Timeout():
+				c.close(closeInitTimeout, "Connection initialisation timeout")
+			case errors.As(err, new(*json.SyntaxError)), errors.As(err, new(*json.UnmarshalTypeError)):
+				c.close(closeBadRequest, "Invalid message received")
+			default:
+				c.srv.logger.Debug("graphql websocket read failed", zap.Error(err))
+			}
+			return
+		}
+		if c.userID != 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		}
+
+		if !c.handle(ctx, msg) {
+			return
+		}
+	}
+}
+
+// handle returns false once the connection has been closed.
+func (c *wsConn) handle(ctx context.Context, msg wsMessage) bool {
+	switch msg.Type {
+	case msgConnectionInit:
+		if c.userID != 0 {
+			c.close(closeTooManyInitRequests, "Too many initialisation requests")
+			return false
+		}
+		if err := c.init(ctx, msg.Payload); err != nil {
+			c.close(closeForbidden, "Forbidden")
+			return false
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		c.enqueue(wsMessage{Type: msgConnectionAck})
+	case msgPing:
+		c.enqueue(wsMessage{Type: msgPong})
+	case msgPong:
+	case msgSubscribe:
+		if c.userID == 0 {
+			c.close(closeUnauthorized, "Unauthorized")
+			return false
+		}
+		return c.subscribe(ctx, msg)
+	case msgComplete:
+		c.mu.Lock()
+		cancel, ok := c.ops[msg.ID]
+		delete(c.ops, msg.ID)
+		c.mu.Unlock()
+		if ok {
+			cancel()
+		}
+	default:
+		c.close(closeBadRequest, "Invalid message received")
+		return false
+	}
+	return true
+}
+
+func (c *wsConn) init(ctx context.Context, payload json.RawMessage) error {
+	var params struct {
+		Authorization string `json:"Authorization"`
+		AccessToken   string `json:"access_token"`
+	}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &params); err != nil {
+			return err
+		}
+	}
+
+	token := params.AccessToken
+	if params.Authorization != "" {
+		var ok bool
+		token, ok = strings.CutPrefix(params.Authorization, "Bearer ")
+		if !ok {
+			return errors.New("invalid authorization")
+		}
+	}
+	if token == "" {
+		return errors.New("missing token")
+	}
+
+	caller, err := c.srv.tokens.Authenticate(ctx, token)
+	if err != nil {
+		return err
+	}
+	// Like POST /graphql, personal access tokens need todos:read, and the resolvers
+	// check todos:write for mutations.
+	if !caller.Allows(models.ScopeTodosRead) {
+		return e.ErrInsufficientScope
+	}
+	c.userID = caller.UserID
+	c.readOnly = caller.ReadOnly
+	c.scopes = caller.Scopes
+	go c.watchAuth(ctx, token, caller.ExpiresAt)
+	return nil
+}
+
+// watchAuth closes the connection when its token expires, and authenticates the token
+// again every authCheckInterval, so that logging out, resetting the password, and
+// disabling or deleting the user also end running subscriptions.
+func (c *wsConn) watchAuth(ctx context.Context, token string, expiresAt time.Time) {
+	var expired <-chan time.Time
+	if !expiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(expiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	ticker := time.NewTicker(authCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-expired:
+			c.close(closeUnauthorized, "Token expired")
+			return
+		case <-ticker.C:
+			_, err := c.srv.tokens.Authenticate(ctx, token)
+			switch {
+			case err == nil:
+			case errors.Is(err, e.ErrInvalidAccessToken), errors.Is(err, e.ErrUserDisabled), errors.Is(err, e.ErrEmailNotVerified):
+				c.close(closeUnauthorized, "Unauthorized")
+				return
+			default:
+				c.srv.logger.Warn("graphql websocket token check failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (c *wsConn) subscribe(ctx context.Context, msg wsMessage) bool {
+	var req gqlRequest
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil || req.Query == "" {
+		c.close(closeBadRequest, "Invalid message received")
+		return false
+	}
+
+	c.mu.Lock()
+	if _, ok := c.ops[msg.ID]; ok {
+		c.mu.Unlock()
+		c.close(closeSubscriberExists, "Subscriber for "+msg.ID+" already exists")
+		return false
+	}
+	if len(c.ops) >= maxOperations {
+		c.mu.Unlock()
+		c.close(closeTooManyOperations, "Too many operations")
+		return false
+	}
+	opCtx, cancel := context.WithCancel(c.srv.requestContext(ctx, c.userID, c.readOnly, c.scopes))
+	c.ops[msg.ID] = cancel
+	c.mu.Unlock()
+
+	op, err := c.srv.check(req)
+	if err != nil {
+		c.finish(msg.ID, errorResponse(err))
+		return true
+	}
+
+	go c.run(opCtx, msg.ID, op, req)
+	return true
+}
+
+func (c *wsConn) run(ctx context.Context, id string, op *ast.OperationDefinition, req gqlRequest) {
+	responses, err := c.srv.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		c.finish(id, errorResponse(err))
+		return
+	}
+
+	first := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case v, ok := <-responses:
+			if !ok {
+				c.finish(id, nil)
+				return
+			}
+
+			res := c.srv.present(v.(*graphql.Response))
+			// Errors without data before anything was sent reject the operation,
+			// e.g. because it failed validation.
+			if first && res.Data == nil && len(res.Errors) > 0 && (op == nil || op.Operation == ast.Subscription) {
+				c.finish(id, res)
+				return
+			}
+			first = false
+
+			payload, err := json.Marshal(res)
+			if err != nil {
+				c.srv.logger.Error("failed to marshal graphql response", zap.Error(err))
+				continue
+			}
+			c.enqueue(wsMessage{ID: id, Type: msgNext, Payload: payload})
+		}
+	}
+}
+
+// finish ends an operation with complete, or with error if res is set, unless the
+// client has already completed it.
+func (c *wsConn) finish(id string, res *graphql.Response) {
+	c.mu.Lock()
+	cancel, ok := c.ops[id]
+	delete(c.ops, id)
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	cancel()
+
+	if res == nil {
+		c.enqueue(wsMessage{ID: id, Type: msgComplete})
+		return
+	}
+
+	payload, err := json.Marshal(res.Errors)
+	if err != nil {
+		c.srv.logger.Error("failed to marshal graphql errors", zap.Error(err))
+		payload = []byte(`[{"message":"internal error"}]`)
+	}
+	c.enqueue(wsMessage{ID: id, Type: msgError, Payload: payload})
+}
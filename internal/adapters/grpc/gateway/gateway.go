@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	adminv1 "github.com/mrxacker/go-to-do-app/api/admin/v1"
+	todov1 "github.com/mrxacker/go-to-do-app/api/todo/v1"
+	userv1 "github.com/mrxacker/go-to-do-app/api/user/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const rateLimitHeaderPrefix = "ratelimit-"
+
+// successCodes are the response codes of methods that don't answer with 200 OK.
+var successCodes = map[string]int{
+	todov1.TodoService_CreateTodo_FullMethodName:                http.StatusCreated,
+	todov1.TodoService_DeleteTodo_FullMethodName:                http.StatusNoContent,
+	todov1.TodoService_ShareList_FullMethodName:                 http.StatusAccepted,
+	todov1.TodoService_UnshareList_FullMethodName:               http.StatusNoContent,
+	userv1.UserService_Register_FullMethodName:                  http.StatusCreated,
+	userv1.UserService_Logout_FullMethodName:                    http.StatusNoContent,
+	userv1.UserService_ForgotPassword_FullMethodName:            http.StatusAccepted,
+	userv1.UserService_ResetPassword_FullMethodName:             http.StatusNoContent,
+	userv1.UserService_VerifyEmail_FullMethodName:               http.StatusNoContent,
+	userv1.UserService_ResendVerification_FullMethodName:        http.StatusAccepted,
+	userv1.UserService_UnlockAccount_FullMethodName:             http.StatusNoContent,
+	userv1.UserService_ChangeEmail_FullMethodName:               http.StatusNoContent,
+	userv1.UserService_DisableTOTP_FullMethodName:               http.StatusNoContent,
+	userv1.UserService_FinishPasskeyRegistration_FullMethodName: http.StatusCreated,
+	userv1.UserService_DeletePasskey_FullMethodName:             http.StatusNoContent,
+	userv1.UserService_CreateAccessToken_FullMethodName:         http.StatusCreated,
+	userv1.UserService_DeleteAccessToken_FullMethodName:         http.StatusNoContent,
+	adminv1.AdminService_DisableUser_FullMethodName:             http.StatusNoContent,
+	adminv1.AdminService_EnableUser_FullMethodName:              http.StatusNoContent,
+	adminv1.AdminService_DeleteUser_FullMethodName:              http.StatusNoContent,
+	adminv1.AdminService_SetUserRole_FullMethodName:             http.StatusNoContent,
+}
+
+// NewHandler returns the REST API transcoded from the HTTP annotations of the proto
+// services. Requests are forwarded over conn, so they go through the same interceptors
+// as gRPC clients.
+func NewHandler(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, newJSONMarshaler()),
+		runtime.WithErrorHandler(writeError),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeader),
+		runtime.WithForwardResponseOption(writeSuccessCode),
+	)
+
+	if err := todov1.RegisterTodoServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+	if err := userv1.RegisterUserServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+	if err := adminv1.RegisterAdminServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+
+	// The API has always been served with and without a trailing slash.
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.Path) > 1 {
+			r.URL.Path = strings.TrimSuffix(r.URL.Path, "/")
+		}
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+func writeSuccessCode(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	method, ok := runtime.RPCMethod(ctx)
+	if !ok {
+		return nil
+	}
+
+	if code, ok := successCodes[method]; ok {
+		w.WriteHeader(code)
+	}
+	return nil
+}
+
+// outgoingHeader sends the rate limits reported by the gRPC server as RateLimit-*
+// headers, and other header metadata with the usual Grpc-Metadata- prefix.
+func outgoingHeader(key string) (string, bool) {
+	if strings.HasPrefix(key, rateLimitHeaderPrefix) {
+		return key, true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// writeError answers with {"error": "..."} like the rest of the HTTP API. A RetryInfo
+// detail is sent as Retry-After, in whole seconds.
+func writeError(ctx context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+	st := status.Convert(err)
+
+	// Errors are sent without the header metadata, but the rate limits apply all the same.
+	if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+		for _, m := range []metadata.MD{md.HeaderMD, md.TrailerMD} {
+			for k, v := range m {
+				if strings.HasPrefix(k, rateLimitHeaderPrefix) && len(v) > 0 {
+					w.Header().Set(k, v[0])
+				}
+			}
+		}
+	}
+
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			secs := (info.GetRetryDelay().AsDuration() + time.Second - 1) / time.Second
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(secs), 10))
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(runtime.HTTPStatusFromCode(st.Code()))
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": st.Message()})
+}
@@ -0,0 +1,160 @@
+package gateway
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// jsonMarshaler writes responses in the JSON shapes of the original REST API: field
+// names as declared in the proto files (or their json_name), 64-bit integers as
+// numbers rather than strings, zero values included, and no body for empty messages.
+// Requests are decoded with protojson, ignoring unknown fields.
+type jsonMarshaler struct {
+	runtime.JSONPb
+}
+
+func newJSONMarshaler() *jsonMarshaler {
+	return &jsonMarshaler{
+		JSONPb: runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		},
+	}
+}
+
+func (m *jsonMarshaler) Marshal(v any) ([]byte, error) {
+	if msg, ok := v.(proto.Message); ok && msg.ProtoReflect().Descriptor().Fields().Len() == 0 {
+		return nil, nil
+	}
+
+	value, err := jsonValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
+
+// jsonValue converts messages and slices of messages, as returned for response_body
+// fields, to values that encoding/json renders in the REST shapes.
+func jsonValue(v any) (any, error) {
+	if msg, ok := v.(proto.Message); ok {
+		return messageValue(msg.ProtoReflect())
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return v, nil
+	}
+
+	list := make([]any, rv.Len())
+	for i := range list {
+		item, err := jsonValue(rv.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		list[i] = item
+	}
+	return list, nil
+}
+
+func messageValue(m protoreflect.Message) (any, error) {
+	// Well-known types such as Timestamp have their own JSON representation.
+	if m.Descriptor().FullName().Parent() == "google.protobuf" {
+		b, err := protojson.Marshal(m.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(b), nil
+	}
+
+	out := make(map[string]any)
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+
+		name := string(fd.Name())
+		if fd.JSONName() != defaultJSONName(name) {
+			name = fd.JSONName()
+		}
+
+		// Unset messages and optional fields are null, rather than zero values.
+		if fd.HasPresence() && !m.Has(fd) {
+			out[name] = nil
+			continue
+		}
+
+		value, err := fieldValue(fd, m.Get(fd))
+		if err != nil {
+			return nil, err
+		}
+		out[name] = value
+	}
+
+	return out, nil
+}
+
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (any, error) {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		out := make([]any, list.Len())
+		for i := range out {
+			item, err := scalarValue(fd, list.Get(i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = item
+		}
+		return out, nil
+	case fd.IsMap():
+		out := make(map[string]any)
+		var err error
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			out[k.String()], err = scalarValue(fd.MapValue(), v)
+			return err == nil
+		})
+		return out, err
+	default:
+		return scalarValue(fd, v)
+	}
+}
+
+func scalarValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (any, error) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageValue(v.Message())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name()), nil
+		}
+		return int32(v.Enum()), nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// defaultJSONName is the JSON name protoc derives when a field has no json_name option.
+func defaultJSONName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,192 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+
+	adminv1 "github.com/mrxacker/go-to-do-app/api/admin/v1"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AdminServer serves AdminService. The permissions of its methods are checked by the
+// permission interceptors.
+type AdminServer struct {
+	adminv1.UnimplementedAdminServiceServer
+
+	uc *usecase.AdminUsecase
+}
+
+func NewAdminServer(uc *usecase.AdminUsecase) *AdminServer {
+	return &AdminServer{uc: uc}
+}
+
+func (s *AdminServer) ListUsers(ctx context.Context, req *adminv1.ListUsersRequest) (*adminv1.ListUsersResponse, error) {
+	actor, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.uc.ListUsers(ctx, actor, req.GetQuery(), int(req.GetLimit()), int(req.GetOffset()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &adminv1.ListUsersResponse{Users: make([]*adminv1.User, len(users))}
+	for i, user := range users {
+		res.Users[i] = toProtoAdminUser(user)
+	}
+	return res, nil
+}
+
+func (s *AdminServer) GetUser(ctx context.Context, req *adminv1.GetUserRequest) (*adminv1.GetUserResponse, error) {
+	actor, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.uc.GetUser(ctx, actor, models.UserID(req.GetId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &adminv1.GetUserResponse{User: toProtoAdminUser(user)}, nil
+}
+
+func (s *AdminServer) DisableUser(ctx context.Context, req *adminv1.DisableUserRequest) (*adminv1.DisableUserResponse, error) {
+	actor, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.SetUserDisabled(ctx, actor, models.UserID(req.GetId()), true); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &adminv1.DisableUserResponse{}, nil
+}
+
+func (s *AdminServer) EnableUser(ctx context.Context, req *adminv1.EnableUserRequest) (*adminv1.EnableUserResponse, error) {
+	actor, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.SetUserDisabled(ctx, actor, models.UserID(req.GetId()), false); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &adminv1.EnableUserResponse{}, nil
+}
+
+func (s *AdminServer) DeleteUser(ctx context.Context, req *adminv1.DeleteUserRequest) (*adminv1.DeleteUserResponse, error) {
+	actor, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.DeleteUser(ctx, actor, models.UserID(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &adminv1.DeleteUserResponse{}, nil
+}
+
+func (s *AdminServer) SetUserRole(ctx context.Context, req *adminv1.SetUserRoleRequest) (*adminv1.SetUserRoleResponse, error) {
+	actor, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.SetUserRole(ctx, actor, models.UserID(req.GetId()), models.Role(req.GetRole())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &adminv1.SetUserRoleResponse{}, nil
+}
+
+func (s *AdminServer) GetStats(ctx context.Context, _ *adminv1.GetStatsRequest) (*adminv1.GetStatsResponse, error) {
+	actor, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.uc.Stats(ctx, actor)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &adminv1.Stats{
+		Users:         stats.Users,
+		VerifiedUsers: stats.VerifiedUsers,
+		DisabledUsers: stats.DisabledUsers,
+		UsersByRole:   make(map[string]int64, len(stats.UsersByRole)),
+		Todos:         stats.Todos,
+	}
+	for role, n := range stats.UsersByRole {
+		res.UsersByRole[string(role)] = n
+	}
+	return &adminv1.GetStatsResponse{Stats: res}, nil
+}
+
+func (s *AdminServer) ListAuditLog(ctx context.Context, req *adminv1.ListAuditLogRequest) (*adminv1.ListAuditLogResponse, error) {
+	actor, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.uc.ListAuditEntries(ctx, actor, int(req.GetLimit()), int(req.GetOffset()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &adminv1.ListAuditLogResponse{Entries: make([]*adminv1.AuditEntry, len(entries))}
+	for i, entry := range entries {
+		res.Entries[i], err = toProtoAuditEntry(entry)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+	}
+	return res, nil
+}
+
+func toProtoAdminUser(user models.User) *adminv1.User {
+	res := &adminv1.User{
+		Id:            int64(user.ID),
+		Username:      user.Username,
+		Email:         user.Email,
+		Role:          string(user.Role),
+		EmailVerified: user.EmailVerified(),
+		CreatedAt:     timestamppb.New(user.CreatedAt),
+	}
+	if user.DisabledAt != nil {
+		res.DisabledAt = timestamppb.New(*user.DisabledAt)
+	}
+	return res
+}
+
+func toProtoAuditEntry(entry models.AuditEntry) (*adminv1.AuditEntry, error) {
+	var details map[string]any
+	if err := json.Unmarshal(entry.Details, &details); err != nil {
+		return nil, err
+	}
+	detailsPB, err := structpb.NewStruct(details)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &adminv1.AuditEntry{
+		Id:        int64(entry.ID),
+		ActorId:   int64(entry.ActorID),
+		Action:    string(entry.Action),
+		Details:   detailsPB,
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+	}
+	if entry.TargetUserID != nil {
+		target := int64(*entry.TargetUserID)
+		res.TargetUserId = &target
+	}
+	return res, nil
+}
@@ -0,0 +1,71 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mrxacker/go-to-do-app/internal/adapters/grpc/interceptors"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// toStatus maps domain errors to gRPC status codes. Unknown errors are reported as
+// Internal without their message, as REST handlers do.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var retry *e.RetryError
+	if errors.As(err, &retry) {
+		st, derr := status.New(codes.ResourceExhausted, err.Error()).
+			WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retry.RetryAfter)})
+		if derr != nil {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return st.Err()
+	}
+
+	switch {
+	case errors.Is(err, e.ErrTodoNotFound), errors.Is(err, e.ErrUserNotFound), errors.Is(err, e.ErrPasskeyNotFound),
+		errors.Is(err, e.ErrUnknownOIDCProvider), errors.Is(err, e.ErrAccessTokenNotFound), errors.Is(err, e.ErrListMemberNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, e.ErrTodoTitleRequired), errors.Is(err, e.ErrTodoTitleTooLong), errors.Is(err, e.ErrInvalidIdentifier),
+		errors.Is(err, e.ErrInvalidResetToken), errors.Is(err, e.ErrInvalidVerifyToken), errors.Is(err, e.ErrInvalidUnlockToken),
+		errors.Is(err, e.ErrInvalidMFACode), errors.Is(err, e.ErrInvalidPasskey), errors.Is(err, e.ErrInvalidWebAuthnSession),
+		errors.Is(err, e.ErrInvalidOIDCState), errors.Is(err, e.ErrInvalidScope), errors.Is(err, e.ErrInvalidRole),
+		errors.Is(err, e.ErrShareWithOwner):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, e.ErrEmailNotVerified), errors.Is(err, e.ErrOIDCEmailNotVerified), errors.Is(err, e.ErrUserDisabled),
+		errors.Is(err, e.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, e.ErrInvalidMFAToken), errors.Is(err, e.ErrOIDCLoginFailed):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, e.ErrMFALocked):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, e.ErrMFAUnavailable), errors.Is(err, e.ErrTOTPNotEnabled),
+		errors.Is(err, e.ErrOIDCNoAccount), errors.Is(err, e.ErrAdminSelfAction):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, e.ErrUserAlreadyExists), errors.Is(err, e.ErrTOTPAlreadyEnabled), errors.Is(err, e.ErrPasskeyAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, e.ErrInvalidRefreshToken), errors.Is(err, e.ErrRefreshTokenReused):
+		// Reuse isn't reported as such, so that a thief doesn't learn that it was detected.
+		return status.Error(codes.Unauthenticated, e.ErrInvalidRefreshToken.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func callerID(ctx context.Context) (models.UserID, error) {
+	userID, ok := interceptors.UserIDFromContext(ctx)
+	if !ok {
+		return 0, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	return userID, nil
+}
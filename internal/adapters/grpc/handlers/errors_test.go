@@ -0,0 +1,65 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{e.ErrTodoNotFound, codes.NotFound, e.ErrTodoNotFound.Error()},
+		{fmt.Errorf("get todo: %w", e.ErrTodoNotFound), codes.NotFound, "get todo: " + e.ErrTodoNotFound.Error()},
+		{e.ErrListMemberNotFound, codes.NotFound, e.ErrListMemberNotFound.Error()},
+		{e.ErrTodoTitleRequired, codes.InvalidArgument, e.ErrTodoTitleRequired.Error()},
+		{e.ErrInvalidIdentifier, codes.InvalidArgument, e.ErrInvalidIdentifier.Error()},
+		{e.ErrShareWithOwner, codes.InvalidArgument, e.ErrShareWithOwner.Error()},
+		{e.ErrUserDisabled, codes.PermissionDenied, e.ErrUserDisabled.Error()},
+		{e.ErrPermissionDenied, codes.PermissionDenied, e.ErrPermissionDenied.Error()},
+		{e.ErrInvalidMFAToken, codes.Unauthenticated, e.ErrInvalidMFAToken.Error()},
+		{e.ErrMFALocked, codes.ResourceExhausted, e.ErrMFALocked.Error()},
+		{e.ErrAdminSelfAction, codes.FailedPrecondition, e.ErrAdminSelfAction.Error()},
+		{e.ErrUserAlreadyExists, codes.AlreadyExists, e.ErrUserAlreadyExists.Error()},
+		{e.ErrRefreshTokenReused, codes.Unauthenticated, e.ErrInvalidRefreshToken.Error()},
+		{context.Canceled, codes.Canceled, context.Canceled.Error()},
+		{fmt.Errorf("list todos: %w", context.DeadlineExceeded), codes.DeadlineExceeded, "list todos: " + context.DeadlineExceeded.Error()},
+		{errors.New("pq: connection refused"), codes.Internal, "internal error"},
+		{status.Error(codes.Aborted, "aborted"), codes.Aborted, "aborted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			st := status.Convert(toStatus(tt.err))
+			if st.Code() != tt.code || st.Message() != tt.message {
+				t.Fatalf("got %v %q, want %v %q", st.Code(), st.Message(), tt.code, tt.message)
+			}
+		})
+	}
+}
+
+func TestToStatusRetryInfo(t *testing.T) {
+	err := toStatus(fmt.Errorf("login: %w", &e.RetryError{Err: e.ErrMFALocked, RetryAfter: 90 * time.Second}))
+
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("got %v, want %v", st.Code(), codes.ResourceExhausted)
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("got details %v", details)
+	}
+	info, ok := details[0].(*errdetails.RetryInfo)
+	if !ok || info.GetRetryDelay().AsDuration() != 90*time.Second {
+		t.Fatalf("got %v, want a retry delay of 90s", details[0])
+	}
+}
@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	todov1 "github.com/mrxacker/go-to-do-app/api/todo/v1"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *TodoServer) ShareList(ctx context.Context, req *todov1.ShareListRequest) (*todov1.ShareListResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.GetEmail()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	if err := s.lists.ShareList(ctx, userID, req.GetEmail()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.ShareListResponse{}, nil
+}
+
+func (s *TodoServer) ListListMembers(ctx context.Context, _ *todov1.ListListMembersRequest) (*todov1.ListListMembersResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.lists.ListMembers(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &todov1.ListListMembersResponse{Members: make([]*todov1.ListMember, len(members))}
+	for i, m := range members {
+		res.Members[i] = toProtoListMember(m)
+	}
+	return res, nil
+}
+
+func (s *TodoServer) UnshareList(ctx context.Context, req *todov1.UnshareListRequest) (*todov1.UnshareListResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.lists.UnshareList(ctx, userID, models.UserID(req.GetUserId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.UnshareListResponse{}, nil
+}
+
+func (s *TodoServer) ListSharedLists(ctx context.Context, _ *todov1.ListSharedListsRequest) (*todov1.ListSharedListsResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := s.lists.ListSharedLists(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &todov1.ListSharedListsResponse{Lists: make([]*todov1.SharedList, len(lists))}
+	for i, l := range lists {
+		res.Lists[i] = &todov1.SharedList{
+			ListId:     int64(l.OwnerID),
+			OwnerEmail: l.OwnerEmail,
+			SharedAt:   timestamppb.New(l.SharedAt),
+		}
+	}
+	return res, nil
+}
+
+func toProtoListMember(m models.ListMember) *todov1.ListMember {
+	return &todov1.ListMember{
+		UserId:    int64(m.UserID),
+		Email:     m.Email,
+		CreatedAt: timestamppb.New(m.CreatedAt),
+	}
+}
@@ -0,0 +1,140 @@
+package grpc
+
+import (
+	"context"
+
+	todov1 "github.com/mrxacker/go-to-do-app/api/todo/v1"
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/stream"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type TodoServer struct {
+	todov1.UnimplementedTodoServiceServer
+
+	uc    *usecase.TodoUsecase
+	lists *usecase.ListUsecase
+	hub   *stream.Hub
+}
+
+func NewTodoServer(uc *usecase.TodoUsecase, lists *usecase.ListUsecase, hub *stream.Hub) *TodoServer {
+	return &TodoServer{uc: uc, lists: lists, hub: hub}
+}
+
+func (s *TodoServer) CreateTodo(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.CreateTodoResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.uc.CreateTodo(ctx, dto.CreateTodoRequest{
+		UserID:      userID,
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.CreateTodoResponse{Id: int64(id)}, nil
+}
+
+func (s *TodoServer) GetTodo(ctx context.Context, req *todov1.GetTodoRequest) (*todov1.GetTodoResponse, error) {
+	todo, err := s.ownTodo(ctx, models.ToDoID(req.GetId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.GetTodoResponse{Todo: toProtoTodo(todo)}, nil
+}
+
+func (s *TodoServer) ListTodos(ctx context.Context, req *todov1.ListTodosRequest) (*todov1.ListTodosResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	todos, err := s.uc.ListTodos(ctx, dto.GetListTodosRequest{
+		UserID: userID,
+		Limit:  int(req.GetLimit()),
+		Offset: int(req.GetOffset()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &todov1.ListTodosResponse{Todos: make([]*todov1.Todo, len(todos))}
+	for i, todo := range todos {
+		res.Todos[i] = toProtoTodo(todo)
+	}
+
+	return res, nil
+}
+
+func (s *TodoServer) UpdateTodo(ctx context.Context, req *todov1.UpdateTodoRequest) (*todov1.UpdateTodoResponse, error) {
+	if _, err := s.ownTodo(ctx, models.ToDoID(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	err := s.uc.UpdateTodo(ctx, models.ToDo{
+		ID:          models.ToDoID(req.GetId()),
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		Completed:   req.GetCompleted(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.UpdateTodoResponse{}, nil
+}
+
+func (s *TodoServer) DeleteTodo(ctx context.Context, req *todov1.DeleteTodoRequest) (*todov1.DeleteTodoResponse, error) {
+	if _, err := s.ownTodo(ctx, models.ToDoID(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := s.uc.DeleteTodoByID(ctx, models.ToDoID(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.DeleteTodoResponse{}, nil
+}
+
+// ownTodo reports todos of other users as not found, so that callers can't probe for
+// their IDs.
+func (s *TodoServer) ownTodo(ctx context.Context, id models.ToDoID) (models.ToDo, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return models.ToDo{}, err
+	}
+
+	if id <= 0 {
+		return models.ToDo{}, e.ErrInvalidIdentifier
+	}
+
+	todo, err := s.uc.GetTodoByID(ctx, id)
+	if err != nil {
+		return models.ToDo{}, err
+	}
+	if todo.UserID != userID {
+		return models.ToDo{}, e.ErrTodoNotFound
+	}
+
+	return todo, nil
+}
+
+func toProtoTodo(todo models.ToDo) *todov1.Todo {
+	return &todov1.Todo{
+		Id:          int64(todo.ID),
+		UserId:      int64(todo.UserID),
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   todo.Completed,
+		CreatedAt:   timestamppb.New(todo.CreatedAt),
+		UpdatedAt:   timestamppb.New(todo.UpdatedAt),
+	}
+}
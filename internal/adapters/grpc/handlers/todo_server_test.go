@@ -0,0 +1,521 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	todov1 "github.com/mrxacker/go-to-do-app/api/todo/v1"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/grpc/interceptors"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/stream"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	alice models.UserID = 1
+	bob   models.UserID = 2
+	// carol's email isn't verified, so carol's tokens are read-only.
+	carol models.UserID = 3
+)
+
+type fakeTodoRepo struct {
+	repository.TodoRepository
+
+	mu     sync.Mutex
+	todos  map[models.ToDoID]models.ToDo
+	nextID models.ToDoID
+
+	// failCreates makes the failCreates-th call of CreateTodos fail.
+	failCreates int
+}
+
+func newFakeTodoRepo() *fakeTodoRepo {
+	return &fakeTodoRepo{todos: make(map[models.ToDoID]models.ToDo)}
+}
+
+func (r *fakeTodoRepo) CreateTodo(_ context.Context, todo models.ToDo) (models.ToDoID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	todo.ID = r.nextID
+	r.todos[todo.ID] = todo
+	return todo.ID, nil
+}
+
+func (r *fakeTodoRepo) CreateTodos(_ context.Context, todos []models.ToDo) ([]models.ToDoID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failCreates > 0 {
+		r.failCreates--
+		if r.failCreates == 0 {
+			return nil, errors.New("connection reset")
+		}
+	}
+
+	ids := make([]models.ToDoID, len(todos))
+	for i, todo := range todos {
+		r.nextID++
+		todo.ID = r.nextID
+		r.todos[todo.ID] = todo
+		ids[i] = todo.ID
+	}
+	return ids, nil
+}
+
+func (r *fakeTodoRepo) GetTodoByID(_ context.Context, id models.ToDoID) (models.ToDo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, ok := r.todos[id]
+	if !ok {
+		return models.ToDo{}, e.ErrTodoNotFound
+	}
+	return todo, nil
+}
+
+func (r *fakeTodoRepo) ListTodos(_ context.Context, userID models.UserID, _, _ int) ([]models.ToDo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todos := make([]models.ToDo, 0)
+	for id := models.ToDoID(1); id <= r.nextID; id++ {
+		if todo, ok := r.todos[id]; ok && todo.UserID == userID {
+			todos = append(todos, todo)
+		}
+	}
+	return todos, nil
+}
+
+func (r *fakeTodoRepo) UpdateTodo(_ context.Context, todo models.ToDo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := r.todos[todo.ID]
+	stored.Title, stored.Description, stored.Completed = todo.Title, todo.Description, todo.Completed
+	r.todos[todo.ID] = stored
+	return nil
+}
+
+func (r *fakeTodoRepo) DeleteTodoByID(_ context.Context, id models.ToDoID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.todos, id)
+	return nil
+}
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[models.UserID]models.User
+}
+
+func (r *fakeUserRepo) GetUserByID(_ context.Context, id models.UserID) (models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, e.ErrUserNotFound
+	}
+	return user, nil
+}
+
+type fakeAccessTokenRepo struct {
+	repository.AccessTokenRepository
+	tokens map[string]models.AccessToken
+}
+
+func (r *fakeAccessTokenRepo) GetAccessTokenByHash(_ context.Context, hash string) (models.AccessToken, error) {
+	t, ok := r.tokens[hash]
+	if !ok {
+		return models.AccessToken{}, e.ErrInvalidAccessToken
+	}
+	return t, nil
+}
+
+func (r *fakeAccessTokenRepo) TouchAccessToken(context.Context, models.AccessTokenID) error {
+	return nil
+}
+
+type testServer struct {
+	client todov1.TodoServiceClient
+	repo   *fakeTodoRepo
+	hub    *stream.Hub
+	jwt    *auth.JWTService
+	users  *fakeUserRepo
+	tokens *fakeAccessTokenRepo
+}
+
+// newTestServer serves TodoService over bufconn behind the auth interceptors.
+func newTestServer(t *testing.T, replaySize int) *testServer {
+	t.Helper()
+
+	verified := time.Now()
+	ts := &testServer{
+		repo: newFakeTodoRepo(),
+		hub:  stream.NewHub(replaySize),
+		jwt:  auth.NewJWTService(auth.NewHMACKeySet("test-secret"), "test", "test", time.Hour),
+		users: &fakeUserRepo{users: map[models.UserID]models.User{
+			alice: {ID: alice, Email: "alice@example.com", EmailVerifiedAt: &verified, Role: models.RoleUser},
+			bob:   {ID: bob, Email: "bob@example.com", EmailVerifiedAt: &verified, Role: models.RoleUser},
+			carol: {ID: carol, Email: "carol@example.com", Role: models.RoleUser},
+		}},
+		tokens: &fakeAccessTokenRepo{tokens: make(map[string]models.AccessToken)},
+	}
+	tokenUC := usecase.NewAccessTokenUsecase(ts.tokens, ts.users, nil, nil, ts.jwt, usecase.VerificationReadOnly)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(interceptors.AuthUnaryInterceptor(tokenUC)),
+		grpc.ChainStreamInterceptor(interceptors.AuthStreamInterceptor(tokenUC)),
+	)
+	todov1.RegisterTodoServiceServer(srv, NewTodoServer(usecase.NewTodoUsecase(ts.repo), nil, ts.hub))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(func() {
+		ts.hub.Close()
+		srv.Stop()
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ts.client = todov1.NewTodoServiceClient(conn)
+	return ts
+}
+
+// as returns a context carrying the access token of a login of userID.
+func (ts *testServer) as(t *testing.T, userID models.UserID) context.Context {
+	t.Helper()
+
+	user := ts.users.users[userID]
+	token, err := ts.jwt.GenerateToken(user, !user.EmailVerified(), "")
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	return bearer(t, token)
+}
+
+// withAccessToken returns a context carrying a personal access token of userID.
+func (ts *testServer) withAccessToken(t *testing.T, userID models.UserID, scopes ...models.Scope) context.Context {
+	t.Helper()
+
+	token, hash, err := auth.GenerateOpaqueToken("pat_")
+	if err != nil {
+		t.Fatalf("generate access token: %v", err)
+	}
+	ts.tokens.tokens[hash] = models.AccessToken{ID: models.AccessTokenID(len(ts.tokens.tokens) + 1), UserID: userID, Scopes: scopes}
+	return bearer(t, token)
+}
+
+func bearer(t *testing.T, token string) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func wantCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+
+	if got := status.Code(err); got != code {
+		t.Fatalf("got %v (%v), want %v", got, err, code)
+	}
+}
+
+func TestTodoServerOwnership(t *testing.T) {
+	ts := newTestServer(t, stream.DefaultReplaySize)
+
+	created, err := ts.client.CreateTodo(ts.as(t, alice), &todov1.CreateTodoRequest{Title: "Buy milk"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	id := created.GetId()
+
+	got, err := ts.client.GetTodo(ts.as(t, alice), &todov1.GetTodoRequest{Id: id})
+	if err != nil {
+		t.Fatalf("get own todo: %v", err)
+	}
+	if got.GetTodo().GetUserId() != int64(alice) || got.GetTodo().GetTitle() != "Buy milk" {
+		t.Fatalf("got %v", got.GetTodo())
+	}
+
+	// Other users' todos are reported as missing, whatever the method.
+	_, err = ts.client.GetTodo(ts.as(t, bob), &todov1.GetTodoRequest{Id: id})
+	wantCode(t, err, codes.NotFound)
+	_, err = ts.client.UpdateTodo(ts.as(t, bob), &todov1.UpdateTodoRequest{Id: id, Title: "Stolen"})
+	wantCode(t, err, codes.NotFound)
+	_, err = ts.client.DeleteTodo(ts.as(t, bob), &todov1.DeleteTodoRequest{Id: id})
+	wantCode(t, err, codes.NotFound)
+
+	if todo := ts.repo.todos[models.ToDoID(id)]; todo.Title != "Buy milk" {
+		t.Fatalf("todo changed by another user: %v", todo)
+	}
+
+	list, err := ts.client.ListTodos(ts.as(t, bob), &todov1.ListTodosRequest{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.GetTodos()) != 0 {
+		t.Fatalf("bob sees %v", list.GetTodos())
+	}
+
+	_, err = ts.client.GetTodo(ts.as(t, alice), &todov1.GetTodoRequest{Id: 0})
+	wantCode(t, err, codes.InvalidArgument)
+
+	if _, err := ts.client.DeleteTodo(ts.as(t, alice), &todov1.DeleteTodoRequest{Id: id}); err != nil {
+		t.Fatalf("delete own todo: %v", err)
+	}
+	_, err = ts.client.GetTodo(ts.as(t, alice), &todov1.GetTodoRequest{Id: id})
+	wantCode(t, err, codes.NotFound)
+}
+
+func TestTodoServerErrors(t *testing.T) {
+	ts := newTestServer(t, stream.DefaultReplaySize)
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+		code codes.Code
+	}{
+		{"missing title", func(ctx context.Context) error {
+			_, err := ts.client.CreateTodo(ctx, &todov1.CreateTodoRequest{Title: " "})
+			return err
+		}, codes.InvalidArgument},
+		{"title too long", func(ctx context.Context) error {
+			_, err := ts.client.CreateTodo(ctx, &todov1.CreateTodoRequest{Title: strings.Repeat("a", 201)})
+			return err
+		}, codes.InvalidArgument},
+		{"missing todo", func(ctx context.Context) error {
+			_, err := ts.client.UpdateTodo(ctx, &todov1.UpdateTodoRequest{Id: 42, Title: "x"})
+			return err
+		}, codes.NotFound},
+		{"negative id", func(ctx context.Context) error {
+			_, err := ts.client.DeleteTodo(ctx, &todov1.DeleteTodoRequest{Id: -1})
+			return err
+		}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantCode(t, tt.call(ts.as(t, alice)), tt.code)
+		})
+	}
+}
+
+func TestTodoServerAuth(t *testing.T) {
+	ts := newTestServer(t, stream.DefaultReplaySize)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := ts.client.ListTodos(ctx, &todov1.ListTodosRequest{})
+	wantCode(t, err, codes.Unauthenticated)
+
+	_, err = ts.client.ListTodos(bearer(t, "not-a-token"), &todov1.ListTodosRequest{})
+	wantCode(t, err, codes.Unauthenticated)
+
+	// Read-only callers can read but not write.
+	if _, err := ts.client.ListTodos(ts.as(t, carol), &todov1.ListTodosRequest{}); err != nil {
+		t.Fatalf("read-only list: %v", err)
+	}
+	_, err = ts.client.CreateTodo(ts.as(t, carol), &todov1.CreateTodoRequest{Title: "x"})
+	wantCode(t, err, codes.PermissionDenied)
+
+	// Personal access tokens are limited to their scopes, and to the methods that have one.
+	readToken := ts.withAccessToken(t, alice, models.ScopeTodosRead)
+	if _, err := ts.client.ListTodos(readToken, &todov1.ListTodosRequest{}); err != nil {
+		t.Fatalf("list with todos:read: %v", err)
+	}
+	_, err = ts.client.CreateTodo(readToken, &todov1.CreateTodoRequest{Title: "x"})
+	wantCode(t, err, codes.PermissionDenied)
+	_, err = ts.client.ShareList(ts.withAccessToken(t, alice, models.ScopeTodosRead, models.ScopeTodosWrite),
+		&todov1.ShareListRequest{Email: "bob@example.com"})
+	wantCode(t, err, codes.PermissionDenied)
+
+	writeToken := ts.withAccessToken(t, alice, models.ScopeTodosWrite)
+	if _, err := ts.client.CreateTodo(writeToken, &todov1.CreateTodoRequest{Title: "x"}); err != nil {
+		t.Fatalf("create with todos:write: %v", err)
+	}
+}
+
+func TestWatchTodos(t *testing.T) {
+	ts := newTestServer(t, 4)
+
+	publish := func(seq int64, userID models.UserID, title string) {
+		t.Helper()
+		event, err := models.NewTodoEvent(models.EventTodoCreated, models.ToDo{ID: models.ToDoID(seq), UserID: userID, Title: title})
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		event.Sequence = seq
+		if err := ts.hub.Publish(context.Background(), event); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	recv := func(stream todov1.TodoService_WatchTodosClient) *todov1.WatchTodosResponse {
+		t.Helper()
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		return res
+	}
+
+	publish(1, alice, "first")
+	publish(2, bob, "bob's")
+	publish(3, alice, "second")
+
+	// Resuming replays the caller's events after the sequence, and nobody else's. Once
+	// the replay arrived, the stream is subscribed to live events.
+	watch, err := ts.client.WatchTodos(ts.as(t, alice), &todov1.WatchTodosRequest{AfterSequence: 1})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if res := recv(watch); res.GetResync() || res.GetEvent().GetSequence() != 3 || res.GetEvent().GetTodo().GetTitle() != "second" {
+		t.Fatalf("got %v, want replay of event 3", res)
+	}
+
+	publish(4, bob, "bob's again")
+	publish(5, alice, "live")
+	if res := recv(watch); res.GetEvent().GetSequence() != 5 || res.GetEvent().GetType() != string(models.EventTodoCreated) {
+		t.Fatalf("got %v, want live event 5", res)
+	}
+
+	// Event 1 was evicted from the replay buffer of 4, so a client resuming after it
+	// must reload.
+	publish(6, alice, "evicts")
+	resumed, err := ts.client.WatchTodos(ts.as(t, alice), &todov1.WatchTodosRequest{AfterSequence: 1})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if res := recv(resumed); !res.GetResync() {
+		t.Fatalf("got %v, want resync", res)
+	}
+
+	invalid, err := ts.client.WatchTodos(ts.as(t, alice), &todov1.WatchTodosRequest{AfterSequence: -1})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	_, err = invalid.Recv()
+	wantCode(t, err, codes.InvalidArgument)
+
+	// Closing the hub ends streams, so that clients reconnect to another instance.
+	ts.hub.Close()
+	for {
+		_, err := watch.Recv()
+		if err != nil {
+			wantCode(t, err, codes.Unavailable)
+			break
+		}
+	}
+}
+
+func TestImportTodos(t *testing.T) {
+	ts := newTestServer(t, stream.DefaultReplaySize)
+
+	const total = importBatchSize + 50
+	invalid := map[int64]string{3: "", importBatchSize + 20: strings.Repeat("a", 201)}
+
+	stream, err := ts.client.ImportTodos(ts.as(t, alice))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	for i := int64(0); i < total; i++ {
+		title, ok := invalid[i]
+		if !ok {
+			title = "todo"
+		}
+		if err := stream.Send(&todov1.ImportTodosRequest{Title: title}); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("close send: %v", err)
+	}
+
+	var results []*todov1.ImportTodoResult
+	var batches int
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		batches++
+		results = append(results, res.GetResults()...)
+	}
+
+	if batches != 2 || len(results) != total {
+		t.Fatalf("got %d results in %d batches, want %d in 2", len(results), batches, total)
+	}
+	for i, result := range results {
+		if result.GetIndex() != int64(i) {
+			t.Fatalf("result %d has index %d", i, result.GetIndex())
+		}
+		_, rejected := invalid[int64(i)]
+		if rejected != (result.GetError() != "") || rejected != (result.GetId() == 0) {
+			t.Fatalf("result %d: %v", i, result)
+		}
+	}
+	if len(ts.repo.todos) != total-len(invalid) {
+		t.Fatalf("stored %d todos, want %d", len(ts.repo.todos), total-len(invalid))
+	}
+	for _, todo := range ts.repo.todos {
+		if todo.UserID != alice {
+			t.Fatalf("imported todo owned by %d", todo.UserID)
+		}
+	}
+}
+
+func TestImportTodosStoreFailure(t *testing.T) {
+	ts := newTestServer(t, stream.DefaultReplaySize)
+	ts.repo.failCreates = 2
+
+	stream, err := ts.client.ImportTodos(ts.as(t, alice))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	go func() {
+		for i := 0; i < importBatchSize*2; i++ {
+			if err := stream.Send(&todov1.ImportTodosRequest{Title: "todo"}); err != nil {
+				return
+			}
+		}
+		_ = stream.CloseSend()
+	}()
+
+	// The first batch is acknowledged and stays created; the second fails the stream.
+	res, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	if len(res.GetResults()) != importBatchSize {
+		t.Fatalf("got %d results, want %d", len(res.GetResults()), importBatchSize)
+	}
+	_, err = stream.Recv()
+	wantCode(t, err, codes.Internal)
+	if status.Convert(err).Message() != "internal error" {
+		t.Fatalf("internal error leaked: %v", err)
+	}
+	if len(ts.repo.todos) != importBatchSize {
+		t.Fatalf("stored %d todos, want %d", len(ts.repo.todos), importBatchSize)
+	}
+}
@@ -0,0 +1,150 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	todov1 "github.com/mrxacker/go-to-do-app/api/todo/v1"
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const importBatchSize = 100
+
+// WatchTodos ends with Unavailable when the stream is interrupted, either because the
+// client fell behind or because the server is shutting down. Clients reconnect with the
+// sequence of the last event they received.
+func (s *TodoServer) WatchTodos(req *todov1.WatchTodosRequest, stream grpc.ServerStreamingServer[todov1.WatchTodosResponse]) error {
+	ctx := stream.Context()
+	userID, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+	if req.GetAfterSequence() < 0 {
+		return status.Error(codes.InvalidArgument, "invalid after_sequence")
+	}
+
+	sub, replay, complete, ok := s.hub.Subscribe(userID, req.GetAfterSequence())
+	if !ok {
+		return status.Error(codes.Unavailable, "server is shutting down")
+	}
+	defer sub.Close()
+
+	if !complete {
+		if err := stream.Send(&todov1.WatchTodosResponse{Resync: true}); err != nil {
+			return err
+		}
+	}
+	for _, event := range replay {
+		if err := sendTodoEvent(stream, event); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-sub.Events:
+			if !ok {
+				return status.Error(codes.Unavailable, "event stream interrupted")
+			}
+			if err := sendTodoEvent(stream, event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func sendTodoEvent(stream grpc.ServerStreamingServer[todov1.WatchTodosResponse], event models.Event) error {
+	var data models.TodoEventData
+	if err := json.Unmarshal(event.Payload, &data); err != nil {
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return stream.Send(&todov1.WatchTodosResponse{
+		Event: &todov1.TodoEvent{
+			Sequence: event.Sequence,
+			Id:       event.ID,
+			Type:     string(event.Type),
+			Todo: toProtoTodo(models.ToDo{
+				ID:          data.ID,
+				UserID:      data.UserID,
+				Title:       data.Title,
+				Description: data.Description,
+				Completed:   data.Completed,
+				CreatedAt:   data.CreatedAt,
+				UpdatedAt:   data.UpdatedAt,
+			}),
+			OccurredAt: timestamppb.New(event.OccurredAt),
+		},
+	})
+}
+
+// ImportTodos acknowledges every importBatchSize todos, and the remainder once the
+// client closes its side of the stream. Todos of acknowledged batches stay created if
+// the stream fails later.
+func (s *TodoServer) ImportTodos(stream grpc.BidiStreamingServer[todov1.ImportTodosRequest, todov1.ImportTodosResponse]) error {
+	ctx := stream.Context()
+	userID, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		batch []dto.CreateTodoRequest
+		index int64
+	)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		results, err := s.uc.ImportTodos(ctx, batch)
+		if err != nil {
+			return toStatus(err)
+		}
+
+		res := &todov1.ImportTodosResponse{Results: make([]*todov1.ImportTodoResult, len(results))}
+		first := index - int64(len(batch))
+		for i, result := range results {
+			res.Results[i] = &todov1.ImportTodoResult{Index: first + int64(i), Id: int64(result.ID)}
+			if result.Err != nil {
+				res.Results[i].Error = result.Err.Error()
+			}
+		}
+		batch = batch[:0]
+
+		return stream.Send(res)
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, dto.CreateTodoRequest{
+			UserID:      userID,
+			Title:       req.GetTitle(),
+			Description: req.GetDescription(),
+			Completed:   req.GetCompleted(),
+		})
+		index++
+
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
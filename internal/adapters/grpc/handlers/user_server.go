@@ -0,0 +1,509 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	userv1 "github.com/mrxacker/go-to-do-app/api/user/v1"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/grpc/interceptors"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	minPasswordLength        = 6
+	maxPasskeyNameLength     = 64
+	maxAccessTokenNameLength = 64
+)
+
+type UserServer struct {
+	userv1.UnimplementedUserServiceServer
+
+	uc        *usecase.UserUseCase
+	resetUC   *usecase.PasswordResetUsecase
+	verifyUC  *usecase.EmailVerificationUsecase
+	mfaUC     *usecase.MFAUsecase
+	passkeyUC *usecase.PasskeyUsecase
+	oidcUC    *usecase.OIDCUsecase
+	tokenUC   *usecase.AccessTokenUsecase
+	throttle  *usecase.LoginThrottle
+}
+
+func NewUserServer(uc *usecase.UserUseCase, resetUC *usecase.PasswordResetUsecase, verifyUC *usecase.EmailVerificationUsecase, mfaUC *usecase.MFAUsecase, passkeyUC *usecase.PasskeyUsecase, oidcUC *usecase.OIDCUsecase, tokenUC *usecase.AccessTokenUsecase, throttle *usecase.LoginThrottle) *UserServer {
+	return &UserServer{uc: uc, resetUC: resetUC, verifyUC: verifyUC, mfaUC: mfaUC, passkeyUC: passkeyUC, oidcUC: oidcUC, tokenUC: tokenUC, throttle: throttle}
+}
+
+func (s *UserServer) Register(ctx context.Context, req *userv1.RegisterRequest) (*userv1.RegisterResponse, error) {
+	if strings.TrimSpace(req.GetUsername()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "username is required")
+	}
+	if _, err := mail.ParseAddress(req.GetEmail()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid email")
+	}
+	if len(req.GetPassword()) < minPasswordLength {
+		return nil, status.Error(codes.InvalidArgument, "password is too short")
+	}
+
+	id, err := s.uc.CreateUser(ctx, models.User{
+		Username:     req.GetUsername(),
+		Email:        req.GetEmail(),
+		PasswordHash: req.GetPassword(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.RegisterResponse{Id: int64(id)}, nil
+}
+
+func (s *UserServer) Login(ctx context.Context, req *userv1.LoginRequest) (*userv1.LoginResponse, error) {
+	if req.GetEmail() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and password are required")
+	}
+
+	ip, _ := interceptors.ClientIPFromContext(ctx)
+	res, err := s.uc.LoginUser(ctx, req.GetEmail(), req.GetPassword(), ip)
+	if err != nil {
+		if errors.Is(err, e.ErrInvalidIdentifier) {
+			return nil, status.Error(codes.Unauthenticated, "invalid email or password")
+		}
+		return nil, toStatus(err)
+	}
+
+	return &userv1.LoginResponse{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		TokenType:    res.TokenType,
+		ExpiresIn:    res.ExpiresIn,
+		MfaToken:     res.MFAToken,
+	}, nil
+}
+
+func (s *UserServer) VerifyMFA(ctx context.Context, req *userv1.VerifyMFARequest) (*userv1.VerifyMFAResponse, error) {
+	if req.GetMfaToken() == "" || req.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "mfa_token and code are required")
+	}
+
+	res, err := s.uc.CompleteMFALogin(ctx, req.GetMfaToken(), req.GetCode())
+	if err != nil {
+		if errors.Is(err, e.ErrInvalidMFACode) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, toStatus(err)
+	}
+
+	return &userv1.VerifyMFAResponse{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		TokenType:    res.TokenType,
+		ExpiresIn:    res.ExpiresIn,
+	}, nil
+}
+
+func (s *UserServer) BeginPasskeyLogin(ctx context.Context, _ *userv1.BeginPasskeyLoginRequest) (*userv1.BeginPasskeyLoginResponse, error) {
+	ceremony, err := s.passkeyUC.BeginLogin(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	options, err := structpb.NewStruct(ceremony.Options)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.BeginPasskeyLoginResponse{SessionId: ceremony.SessionID, Options: options}, nil
+}
+
+func (s *UserServer) FinishPasskeyLogin(ctx context.Context, req *userv1.FinishPasskeyLoginRequest) (*userv1.FinishPasskeyLoginResponse, error) {
+	if req.GetSessionId() == "" || req.GetCredential() == nil {
+		return nil, status.Error(codes.InvalidArgument, "session_id and credential are required")
+	}
+
+	response, err := req.GetCredential().MarshalJSON()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid credential")
+	}
+
+	res, err := s.uc.LoginWithPasskey(ctx, req.GetSessionId(), response)
+	if err != nil {
+		if errors.Is(err, e.ErrInvalidPasskey) || errors.Is(err, e.ErrInvalidWebAuthnSession) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, toStatus(err)
+	}
+
+	return &userv1.FinishPasskeyLoginResponse{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		TokenType:    res.TokenType,
+		ExpiresIn:    res.ExpiresIn,
+	}, nil
+}
+
+func (s *UserServer) BeginOIDCLogin(ctx context.Context, req *userv1.BeginOIDCLoginRequest) (*userv1.BeginOIDCLoginResponse, error) {
+	authURL, err := s.oidcUC.BeginLogin(ctx, req.GetProvider())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.BeginOIDCLoginResponse{AuthorizationUrl: authURL}, nil
+}
+
+func (s *UserServer) FinishOIDCLogin(ctx context.Context, req *userv1.FinishOIDCLoginRequest) (*userv1.FinishOIDCLoginResponse, error) {
+	if req.GetState() == "" || req.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "state and code are required")
+	}
+
+	res, err := s.uc.LoginWithOIDC(ctx, req.GetProvider(), req.GetState(), req.GetCode())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.FinishOIDCLoginResponse{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		TokenType:    res.TokenType,
+		ExpiresIn:    res.ExpiresIn,
+		MfaToken:     res.MFAToken,
+	}, nil
+}
+
+func (s *UserServer) Refresh(ctx context.Context, req *userv1.RefreshRequest) (*userv1.RefreshResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+
+	res, err := s.uc.RefreshTokens(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.RefreshResponse{
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		TokenType:    res.TokenType,
+		ExpiresIn:    res.ExpiresIn,
+	}, nil
+}
+
+func (s *UserServer) Logout(ctx context.Context, req *userv1.LogoutRequest) (*userv1.LogoutResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+
+	if err := s.uc.Logout(ctx, req.GetRefreshToken()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.LogoutResponse{}, nil
+}
+
+func (s *UserServer) ForgotPassword(ctx context.Context, req *userv1.ForgotPasswordRequest) (*userv1.ForgotPasswordResponse, error) {
+	if _, err := mail.ParseAddress(req.GetEmail()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid email")
+	}
+
+	s.resetUC.RequestReset(ctx, req.GetEmail())
+
+	return &userv1.ForgotPasswordResponse{}, nil
+}
+
+func (s *UserServer) ResetPassword(ctx context.Context, req *userv1.ResetPasswordRequest) (*userv1.ResetPasswordResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if len(req.GetNewPassword()) < minPasswordLength {
+		return nil, status.Error(codes.InvalidArgument, "password is too short")
+	}
+
+	if err := s.resetUC.ResetPassword(ctx, req.GetToken(), req.GetNewPassword()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.ResetPasswordResponse{}, nil
+}
+
+func (s *UserServer) VerifyEmail(ctx context.Context, req *userv1.VerifyEmailRequest) (*userv1.VerifyEmailResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	if err := s.verifyUC.Verify(ctx, req.GetToken()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.VerifyEmailResponse{}, nil
+}
+
+func (s *UserServer) ResendVerification(ctx context.Context, req *userv1.ResendVerificationRequest) (*userv1.ResendVerificationResponse, error) {
+	if _, err := mail.ParseAddress(req.GetEmail()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid email")
+	}
+
+	s.verifyUC.Resend(ctx, req.GetEmail())
+
+	return &userv1.ResendVerificationResponse{}, nil
+}
+
+func (s *UserServer) UnlockAccount(ctx context.Context, req *userv1.UnlockAccountRequest) (*userv1.UnlockAccountResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	if err := s.throttle.Unlock(ctx, req.GetToken()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.UnlockAccountResponse{}, nil
+}
+
+func (s *UserServer) ChangeEmail(ctx context.Context, req *userv1.ChangeEmailRequest) (*userv1.ChangeEmailResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mail.ParseAddress(req.GetEmail()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid email")
+	}
+	if req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	if err := s.uc.ChangeEmail(ctx, userID, req.GetPassword(), req.GetEmail()); err != nil {
+		if errors.Is(err, e.ErrInvalidIdentifier) {
+			return nil, status.Error(codes.PermissionDenied, "invalid password")
+		}
+		return nil, toStatus(err)
+	}
+
+	return &userv1.ChangeEmailResponse{}, nil
+}
+
+func (s *UserServer) EnrollTOTP(ctx context.Context, _ *userv1.EnrollTOTPRequest) (*userv1.EnrollTOTPResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.mfaUC.EnrollTOTP(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.EnrollTOTPResponse{
+		Secret:     enrollment.Secret,
+		OtpauthUri: enrollment.OTPAuthURI,
+		QrPng:      enrollment.QRCodePNG,
+	}, nil
+}
+
+func (s *UserServer) ConfirmTOTP(ctx context.Context, req *userv1.ConfirmTOTPRequest) (*userv1.ConfirmTOTPResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	recoveryCodes, err := s.mfaUC.ConfirmTOTP(ctx, userID, req.GetCode())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.ConfirmTOTPResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (s *UserServer) DisableTOTP(ctx context.Context, req *userv1.DisableTOTPRequest) (*userv1.DisableTOTPResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	if err := s.mfaUC.DisableTOTP(ctx, userID, req.GetPassword()); err != nil {
+		if errors.Is(err, e.ErrInvalidIdentifier) {
+			return nil, status.Error(codes.PermissionDenied, "invalid password")
+		}
+		return nil, toStatus(err)
+	}
+
+	return &userv1.DisableTOTPResponse{}, nil
+}
+
+func (s *UserServer) BeginPasskeyRegistration(ctx context.Context, _ *userv1.BeginPasskeyRegistrationRequest) (*userv1.BeginPasskeyRegistrationResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ceremony, err := s.passkeyUC.BeginRegistration(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	options, err := structpb.NewStruct(ceremony.Options)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.BeginPasskeyRegistrationResponse{SessionId: ceremony.SessionID, Options: options}, nil
+}
+
+func (s *UserServer) FinishPasskeyRegistration(ctx context.Context, req *userv1.FinishPasskeyRegistrationRequest) (*userv1.FinishPasskeyRegistrationResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetSessionId() == "" || req.GetCredential() == nil {
+		return nil, status.Error(codes.InvalidArgument, "session_id and credential are required")
+	}
+	name := strings.TrimSpace(req.GetName())
+	if name == "" || len(name) > maxPasskeyNameLength {
+		return nil, status.Error(codes.InvalidArgument, "name must be 1 to 64 characters")
+	}
+
+	response, err := req.GetCredential().MarshalJSON()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid credential")
+	}
+
+	passkey, err := s.passkeyUC.FinishRegistration(ctx, userID, req.GetSessionId(), name, response)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.FinishPasskeyRegistrationResponse{Passkey: toProtoPasskey(passkey)}, nil
+}
+
+func (s *UserServer) ListPasskeys(ctx context.Context, _ *userv1.ListPasskeysRequest) (*userv1.ListPasskeysResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := s.passkeyUC.ListPasskeys(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &userv1.ListPasskeysResponse{Passkeys: make([]*userv1.Passkey, len(passkeys))}
+	for i, p := range passkeys {
+		res.Passkeys[i] = toProtoPasskey(p)
+	}
+	return res, nil
+}
+
+func (s *UserServer) DeletePasskey(ctx context.Context, req *userv1.DeletePasskeyRequest) (*userv1.DeletePasskeyResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.passkeyUC.DeletePasskey(ctx, userID, models.PasskeyID(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.DeletePasskeyResponse{}, nil
+}
+
+func (s *UserServer) CreateAccessToken(ctx context.Context, req *userv1.CreateAccessTokenRequest) (*userv1.CreateAccessTokenResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.GetName())
+	if name == "" || len(name) > maxAccessTokenNameLength {
+		return nil, status.Error(codes.InvalidArgument, "name must be 1 to 64 characters")
+	}
+
+	var expiresAt *time.Time
+	if req.GetExpiresAt() != nil {
+		t := req.GetExpiresAt().AsTime()
+		if !t.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "expires_at must be in the future")
+		}
+		expiresAt = &t
+	}
+
+	scopes := make([]models.Scope, len(req.GetScopes()))
+	for i, scope := range req.GetScopes() {
+		scopes[i] = models.Scope(scope)
+	}
+
+	token, created, err := s.tokenUC.CreateAccessToken(ctx, userID, name, scopes, expiresAt)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.CreateAccessTokenResponse{Token: token, AccessToken: toProtoAccessToken(created)}, nil
+}
+
+func (s *UserServer) ListAccessTokens(ctx context.Context, _ *userv1.ListAccessTokensRequest) (*userv1.ListAccessTokensResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.tokenUC.ListAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &userv1.ListAccessTokensResponse{AccessTokens: make([]*userv1.AccessToken, len(tokens))}
+	for i, t := range tokens {
+		res.AccessTokens[i] = toProtoAccessToken(t)
+	}
+	return res, nil
+}
+
+func (s *UserServer) DeleteAccessToken(ctx context.Context, req *userv1.DeleteAccessTokenRequest) (*userv1.DeleteAccessTokenResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tokenUC.DeleteAccessToken(ctx, userID, models.AccessTokenID(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userv1.DeleteAccessTokenResponse{}, nil
+}
+
+func toProtoAccessToken(t models.AccessToken) *userv1.AccessToken {
+	res := &userv1.AccessToken{
+		Id:        int64(t.ID),
+		Name:      t.Name,
+		Scopes:    make([]string, len(t.Scopes)),
+		CreatedAt: timestamppb.New(t.CreatedAt),
+	}
+	for i, scope := range t.Scopes {
+		res.Scopes[i] = string(scope)
+	}
+	if t.ExpiresAt != nil {
+		res.ExpiresAt = timestamppb.New(*t.ExpiresAt)
+	}
+	if t.LastUsedAt != nil {
+		res.LastUsedAt = timestamppb.New(*t.LastUsedAt)
+	}
+	return res
+}
+
+func toProtoPasskey(p models.Passkey) *userv1.Passkey {
+	return &userv1.Passkey{
+		Id:        int64(p.ID),
+		Name:      p.Name,
+		BackedUp:  p.BackedUp(),
+		CreatedAt: timestamppb.New(p.CreatedAt),
+	}
+}
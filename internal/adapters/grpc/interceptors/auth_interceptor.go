@@ -0,0 +1,182 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	todov1 "github.com/mrxacker/go-to-do-app/api/todo/v1"
+	userv1 "github.com/mrxacker/go-to-do-app/api/user/v1"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods don't require a token. They are the public routes of the API, which
+// the gateway serves.
+var publicMethods = map[string]bool{
+	userv1.UserService_Register_FullMethodName:           true,
+	userv1.UserService_Login_FullMethodName:              true,
+	userv1.UserService_Refresh_FullMethodName:            true,
+	userv1.UserService_Logout_FullMethodName:             true,
+	userv1.UserService_ForgotPassword_FullMethodName:     true,
+	userv1.UserService_ResetPassword_FullMethodName:      true,
+	userv1.UserService_VerifyEmail_FullMethodName:        true,
+	userv1.UserService_ResendVerification_FullMethodName: true,
+	userv1.UserService_UnlockAccount_FullMethodName:      true,
+	userv1.UserService_VerifyMFA_FullMethodName:          true,
+	userv1.UserService_BeginPasskeyLogin_FullMethodName:  true,
+	userv1.UserService_FinishPasskeyLogin_FullMethodName: true,
+	userv1.UserService_BeginOIDCLogin_FullMethodName:     true,
+	userv1.UserService_FinishOIDCLogin_FullMethodName:    true,
+}
+
+// readOnlyMethods can be called with read-only tokens. ChangeEmail is allowed so that
+// users who mistyped their email can fix it and get verified.
+var readOnlyMethods = map[string]bool{
+	todov1.TodoService_GetTodo_FullMethodName:         true,
+	todov1.TodoService_ListTodos_FullMethodName:       true,
+	todov1.TodoService_WatchTodos_FullMethodName:      true,
+	todov1.TodoService_ListListMembers_FullMethodName: true,
+	todov1.TodoService_ListSharedLists_FullMethodName: true,
+	userv1.UserService_ChangeEmail_FullMethodName:     true,
+}
+
+// methodScopes are the scopes personal access tokens need to call a method. Methods
+// missing here can only be called with the access token of a login, so that a token
+// can't be used to take over the account or share the user's list.
+var methodScopes = map[string]models.Scope{
+	todov1.TodoService_CreateTodo_FullMethodName:      models.ScopeTodosWrite,
+	todov1.TodoService_GetTodo_FullMethodName:         models.ScopeTodosRead,
+	todov1.TodoService_ListTodos_FullMethodName:       models.ScopeTodosRead,
+	todov1.TodoService_UpdateTodo_FullMethodName:      models.ScopeTodosWrite,
+	todov1.TodoService_DeleteTodo_FullMethodName:      models.ScopeTodosWrite,
+	todov1.TodoService_WatchTodos_FullMethodName:      models.ScopeTodosRead,
+	todov1.TodoService_ImportTodos_FullMethodName:     models.ScopeTodosWrite,
+	todov1.TodoService_ListListMembers_FullMethodName: models.ScopeTodosRead,
+	todov1.TodoService_ListSharedLists_FullMethodName: models.ScopeTodosRead,
+}
+
+// publicServices are infrastructure services used by probes and debugging tools.
+// Reflection and channelz are only registered when enabled in the config.
+var publicServices = map[string]bool{
+	healthpb.Health_ServiceDesc.ServiceName:                      true,
+	reflectionpb.ServerReflection_ServiceDesc.ServiceName:        true,
+	reflectionv1alphapb.ServerReflection_ServiceDesc.ServiceName: true,
+	channelzpb.Channelz_ServiceDesc.ServiceName:                  true,
+}
+
+func isPublic(fullMethod string) bool {
+	if publicMethods[fullMethod] {
+		return true
+	}
+
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return publicServices[service]
+}
+
+type userIDKey struct{}
+
+func WithUserID(ctx context.Context, userID models.UserID) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+func UserIDFromContext(ctx context.Context) (models.UserID, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(models.UserID)
+	return userID, ok
+}
+
+type callerKey struct{}
+
+// CallerFromContext returns who the request was authenticated as.
+func CallerFromContext(ctx context.Context) (models.Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(models.Caller)
+	return caller, ok
+}
+
+// AuthUnaryInterceptor validates the bearer token in the "authorization" metadata and
+// stores the caller's user ID in the context. Personal access tokens are accepted for
+// the methods their scopes allow.
+func AuthUnaryInterceptor(tokens *usecase.AccessTokenUsecase) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if isPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ctx, err := authenticate(ctx, tokens, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor is the streaming counterpart of AuthUnaryInterceptor.
+func AuthStreamInterceptor(tokens *usecase.AccessTokenUsecase) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublic(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx, err := authenticate(ss.Context(), tokens, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, tokens *usecase.AccessTokenUsecase, fullMethod string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing token")
+	}
+
+	parts := strings.Split(values[0], " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, status.Error(codes.Unauthenticated, "invalid token format")
+	}
+
+	caller, err := tokens.Authenticate(ctx, parts[1])
+	if err != nil {
+		switch {
+		case errors.Is(err, e.ErrInvalidAccessToken):
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		case errors.Is(err, e.ErrEmailNotVerified):
+			return nil, status.Error(codes.PermissionDenied, "email not verified")
+		case errors.Is(err, e.ErrUserDisabled):
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		default:
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+	if scope, ok := methodScopes[fullMethod]; !caller.Session() && (!ok || !caller.Allows(scope)) {
+		return nil, status.Error(codes.PermissionDenied, e.ErrInsufficientScope.Error())
+	}
+	if caller.ReadOnly && !readOnlyMethods[fullMethod] {
+		return nil, status.Error(codes.PermissionDenied, "email not verified")
+	}
+
+	ctx = context.WithValue(ctx, callerKey{}, caller)
+	return WithUserID(ctx, caller.UserID), nil
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,354 @@
+package interceptors
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	adminv1 "github.com/mrxacker/go-to-do-app/api/admin/v1"
+	todov1 "github.com/mrxacker/go-to-do-app/api/todo/v1"
+	userv1 "github.com/mrxacker/go-to-do-app/api/user/v1"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[models.UserID]models.User
+}
+
+func (r *fakeUserRepo) GetUserByID(_ context.Context, id models.UserID) (models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, e.ErrUserNotFound
+	}
+	return user, nil
+}
+
+type fakeAccessTokenRepo struct {
+	repository.AccessTokenRepository
+	tokens map[string]models.AccessToken
+}
+
+func (r *fakeAccessTokenRepo) GetAccessTokenByHash(_ context.Context, hash string) (models.AccessToken, error) {
+	t, ok := r.tokens[hash]
+	if !ok {
+		return models.AccessToken{}, e.ErrInvalidAccessToken
+	}
+	return t, nil
+}
+
+func (r *fakeAccessTokenRepo) TouchAccessToken(context.Context, models.AccessTokenID) error {
+	return nil
+}
+
+// authFixture holds an interceptor and the tokens of a verified user, an unverified
+// user and a personal access token with every scope.
+type authFixture struct {
+	users    *fakeUserRepo
+	tokens   *fakeAccessTokenRepo
+	jwt      *auth.JWTService
+	unary    grpc.UnaryServerInterceptor
+	stream   grpc.StreamServerInterceptor
+	session  string
+	readOnly string
+	pat      string
+}
+
+func newAuthFixture(t *testing.T) authFixture {
+	t.Helper()
+
+	verified := time.Now()
+	users := &fakeUserRepo{users: map[models.UserID]models.User{
+		1: {ID: 1, Email: "alice@example.com", EmailVerifiedAt: &verified, Role: models.RoleUser},
+		2: {ID: 2, Email: "carol@example.com", Role: models.RoleUser},
+	}}
+	tokens := &fakeAccessTokenRepo{tokens: make(map[string]models.AccessToken)}
+	jwt := auth.NewJWTService(auth.NewHMACKeySet("test-secret"), "test", "test", time.Hour)
+	uc := usecase.NewAccessTokenUsecase(tokens, users, nil, nil, jwt, usecase.VerificationReadOnly)
+
+	session, err := jwt.GenerateToken(users.users[1], false, "")
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	readOnly, err := jwt.GenerateToken(users.users[2], true, "")
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	pat, hash, err := auth.GenerateOpaqueToken("pat_")
+	if err != nil {
+		t.Fatalf("generate access token: %v", err)
+	}
+	tokens.tokens[hash] = models.AccessToken{ID: 1, UserID: 1, Scopes: models.Scopes}
+
+	return authFixture{
+		users:    users,
+		tokens:   tokens,
+		jwt:      jwt,
+		unary:    AuthUnaryInterceptor(uc),
+		stream:   AuthStreamInterceptor(uc),
+		session:  session,
+		readOnly: readOnly,
+		pat:      pat,
+	}
+}
+
+// call runs fullMethod through the unary interceptor and reports whether the handler
+// was reached.
+func (f authFixture) call(t *testing.T, fullMethod, token string) (bool, error) {
+	t.Helper()
+
+	ctx := context.Background()
+	if token != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+	}
+
+	var reached bool
+	_, err := f.unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: fullMethod}, func(ctx context.Context, _ any) (any, error) {
+		reached = true
+		if _, ok := CallerFromContext(ctx); !ok && !isPublic(fullMethod) {
+			t.Errorf("%s: handler called without a caller", fullMethod)
+		}
+		return nil, nil
+	})
+	return reached, err
+}
+
+func fullMethods(descs ...grpc.ServiceDesc) []string {
+	var methods []string
+	for _, desc := range descs {
+		for _, m := range desc.Methods {
+			methods = append(methods, "/"+desc.ServiceName+"/"+m.MethodName)
+		}
+		for _, s := range desc.Streams {
+			methods = append(methods, "/"+desc.ServiceName+"/"+s.StreamName)
+		}
+	}
+	return methods
+}
+
+func TestAuthInterceptorMethodTables(t *testing.T) {
+	f := newAuthFixture(t)
+
+	methods := fullMethods(todov1.TodoService_ServiceDesc, userv1.UserService_ServiceDesc, adminv1.AdminService_ServiceDesc)
+	known := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		known[m] = true
+	}
+	for _, table := range []map[string]bool{publicMethods, readOnlyMethods} {
+		for m := range table {
+			if !known[m] {
+				t.Errorf("%s isn't a method of the API", m)
+			}
+		}
+	}
+	for m := range methodScopes {
+		if !known[m] {
+			t.Errorf("%s isn't a method of the API", m)
+		}
+	}
+
+	for _, m := range methods {
+		t.Run(m, func(t *testing.T) {
+			reached, err := f.call(t, m, "")
+			if publicMethods[m] {
+				if !reached || err != nil {
+					t.Fatalf("public method refused without a token: %v", err)
+				}
+				return
+			}
+			if reached || status.Code(err) != codes.Unauthenticated {
+				t.Fatalf("got %v without a token, want %v", err, codes.Unauthenticated)
+			}
+
+			if reached, err := f.call(t, m, f.session); !reached || err != nil {
+				t.Fatalf("refused with a session token: %v", err)
+			}
+
+			reached, err = f.call(t, m, f.readOnly)
+			if reached != readOnlyMethods[m] {
+				t.Fatalf("read-only token reached the handler: %v, want %v (%v)", reached, readOnlyMethods[m], err)
+			}
+			if !reached && status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("got %v with a read-only token, want %v", err, codes.PermissionDenied)
+			}
+
+			_, scoped := methodScopes[m]
+			reached, err = f.call(t, m, f.pat)
+			if reached != scoped {
+				t.Fatalf("access token reached the handler: %v, want %v (%v)", reached, scoped, err)
+			}
+			if !reached && status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("got %v with an access token, want %v", err, codes.PermissionDenied)
+			}
+
+			reached, err = f.call(t, m, "not-a-token")
+			if reached || status.Code(err) != codes.Unauthenticated {
+				t.Fatalf("got %v with an invalid token, want %v", err, codes.Unauthenticated)
+			}
+		})
+	}
+}
+
+func TestAuthInterceptorRefusesMethodsOutsideScope(t *testing.T) {
+	f := newAuthFixture(t)
+	methods := fullMethods(todov1.TodoService_ServiceDesc, userv1.UserService_ServiceDesc, adminv1.AdminService_ServiceDesc)
+
+	for i, scope := range models.Scopes {
+		pat, hash, err := auth.GenerateOpaqueToken("pat_")
+		if err != nil {
+			t.Fatalf("generate access token: %v", err)
+		}
+		f.tokens.tokens[hash] = models.AccessToken{ID: models.AccessTokenID(i + 2), UserID: 1, Scopes: []models.Scope{scope}}
+
+		for _, m := range methods {
+			if isPublic(m) {
+				continue
+			}
+			t.Run(string(scope)+m, func(t *testing.T) {
+				want := methodScopes[m] == scope
+				reached, err := f.call(t, m, pat)
+				if reached != want {
+					t.Fatalf("token reached the handler: %v, want %v (%v)", reached, want, err)
+				}
+				if !reached && status.Code(err) != codes.PermissionDenied {
+					t.Fatalf("got %v, want %v", err, codes.PermissionDenied)
+				}
+			})
+		}
+	}
+}
+
+func TestAuthInterceptorSensitiveMethods(t *testing.T) {
+	// Methods that take over an account or hand out access must never be callable
+	// without a login, and never with a personal access token.
+	for _, m := range []string{
+		userv1.UserService_BeginPasskeyRegistration_FullMethodName,
+		userv1.UserService_DisableTOTP_FullMethodName,
+		userv1.UserService_ChangeEmail_FullMethodName,
+		userv1.UserService_CreateAccessToken_FullMethodName,
+		todov1.TodoService_ShareList_FullMethodName,
+		todov1.TodoService_UnshareList_FullMethodName,
+		adminv1.AdminService_DeleteUser_FullMethodName,
+	} {
+		if isPublic(m) {
+			t.Errorf("%s is public", m)
+		}
+		if _, ok := methodScopes[m]; ok {
+			t.Errorf("%s can be called with an access token", m)
+		}
+	}
+	for _, m := range []string{
+		userv1.UserService_Login_FullMethodName,
+		userv1.UserService_ResetPassword_FullMethodName,
+		"/" + healthpb.Health_ServiceDesc.ServiceName + "/Check",
+	} {
+		if !isPublic(m) {
+			t.Errorf("%s isn't public", m)
+		}
+	}
+}
+
+func TestAuthInterceptorChecksUserOfLogin(t *testing.T) {
+	f := newAuthFixture(t)
+	method := adminv1.AdminService_ListUsers_FullMethodName
+	callerOf := func() (models.Caller, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+f.session))
+		var caller models.Caller
+		_, err := f.unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, _ any) (any, error) {
+			caller, _ = CallerFromContext(ctx)
+			return nil, nil
+		})
+		return caller, err
+	}
+
+	// The role and permissions are the user's current ones, not those the token was
+	// issued with.
+	user := f.users.users[1]
+	user.Role = models.RoleAdmin
+	user.Permissions = []models.Permission{models.PermissionUsersRead}
+	f.users.users[1] = user
+	caller, err := callerOf()
+	if err != nil || caller.Role != models.RoleAdmin || !slices.Equal(caller.Permissions, user.Permissions) {
+		t.Fatalf("got %+v, %v", caller, err)
+	}
+
+	// Resetting the password ends logins from before it. Issue times are truncated to
+	// seconds, so a reset in the second the token was issued keeps it.
+	claims, err := f.jwt.ParseToken(f.session)
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	changedAt := claims.IssuedAt.Add(999 * time.Millisecond)
+	user.PasswordChangedAt = &changedAt
+	f.users.users[1] = user
+	if _, err := callerOf(); err != nil {
+		t.Fatalf("got %v for a reset in the second of the login", err)
+	}
+	changedAt = changedAt.Add(time.Second)
+	if _, err := callerOf(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("got %v after a password reset, want %v", err, codes.Unauthenticated)
+	}
+	user.PasswordChangedAt = nil
+
+	disabledAt := time.Now()
+	user.DisabledAt = &disabledAt
+	f.users.users[1] = user
+	if _, err := callerOf(); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("got %v for a disabled user, want %v", err, codes.PermissionDenied)
+	}
+
+	delete(f.users.users, 1)
+	if _, err := callerOf(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("got %v for a deleted user, want %v", err, codes.Unauthenticated)
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestAuthStreamInterceptor(t *testing.T) {
+	f := newAuthFixture(t)
+
+	run := func(fullMethod, token string) (models.Caller, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+		var caller models.Caller
+		err := f.stream(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: fullMethod}, func(_ any, ss grpc.ServerStream) error {
+			caller, _ = CallerFromContext(ss.Context())
+			return nil
+		})
+		return caller, err
+	}
+
+	caller, err := run(todov1.TodoService_WatchTodos_FullMethodName, f.readOnly)
+	if err != nil || caller.UserID != 2 || !caller.ReadOnly {
+		t.Fatalf("got %+v, %v", caller, err)
+	}
+	_, err = run(todov1.TodoService_ImportTodos_FullMethodName, f.readOnly)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("got %v, want %v", err, codes.PermissionDenied)
+	}
+	caller, err = run(todov1.TodoService_ImportTodos_FullMethodName, f.pat)
+	if err != nil || caller.UserID != 1 || caller.Session() {
+		t.Fatalf("got %+v, %v", caller, err)
+	}
+	_, err = run(todov1.TodoService_WatchTodos_FullMethodName, "Bearer")
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("got %v, want %v", err, codes.Unauthenticated)
+	}
+}
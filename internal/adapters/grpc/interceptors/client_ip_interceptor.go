@@ -0,0 +1,44 @@
+package interceptors
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/adapters/clientip"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type clientIPKey struct{}
+
+// ClientIPFromContext returns the address of the client, as found by the ClientIP
+// interceptors.
+func ClientIPFromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPKey{}).(string)
+	return ip, ok
+}
+
+// ClientIPUnaryInterceptor stores the client's address in the context. Calls from the
+// REST gateway carry it in the "x-forwarded-for" metadata.
+func ClientIPUnaryInterceptor(resolver *clientip.Resolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withClientIP(ctx, resolver), req)
+	}
+}
+
+// ClientIPStreamInterceptor is the streaming counterpart of ClientIPUnaryInterceptor.
+func ClientIPStreamInterceptor(resolver *clientip.Resolver) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withClientIP(ss.Context(), resolver)})
+	}
+}
+
+func withClientIP(ctx context.Context, resolver *clientip.Resolver) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ctx
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	return context.WithValue(ctx, clientIPKey{}, resolver.Resolve(p.Addr.String(), md.Get("x-forwarded-for")))
+}
@@ -0,0 +1,46 @@
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func LoggingUnaryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func LoggingStreamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(logger *zap.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	level := zapcore.InfoLevel
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		level = zapcore.ErrorLevel
+	}
+
+	logger.Log(level, "grpc request",
+		zap.String("method", method),
+		zap.String("code", code.String()),
+		zap.Duration("duration", time.Since(start)),
+	)
+}
@@ -0,0 +1,66 @@
+package interceptors
+
+import (
+	"context"
+
+	adminv1 "github.com/mrxacker/go-to-do-app/api/admin/v1"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// methodPermissions are the permissions the caller's role needs to call a method.
+// Methods missing here need none.
+var methodPermissions = map[string]models.Permission{
+	adminv1.AdminService_ListUsers_FullMethodName:    models.PermissionUsersRead,
+	adminv1.AdminService_GetUser_FullMethodName:      models.PermissionUsersRead,
+	adminv1.AdminService_DisableUser_FullMethodName:  models.PermissionUsersDisable,
+	adminv1.AdminService_EnableUser_FullMethodName:   models.PermissionUsersDisable,
+	adminv1.AdminService_DeleteUser_FullMethodName:   models.PermissionUsersDelete,
+	adminv1.AdminService_SetUserRole_FullMethodName:  models.PermissionUsersRoles,
+	adminv1.AdminService_GetStats_FullMethodName:     models.PermissionStatsRead,
+	adminv1.AdminService_ListAuditLog_FullMethodName: models.PermissionAuditRead,
+}
+
+// RequirePermissionUnaryInterceptor refuses calls of methods whose permission the
+// caller's role doesn't grant. It runs after AuthUnaryInterceptor, which stores the
+// caller.
+func RequirePermissionUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := requirePermission(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// RequirePermissionStreamInterceptor is the streaming counterpart of
+// RequirePermissionUnaryInterceptor.
+func RequirePermissionStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := requirePermission(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func requirePermission(ctx context.Context, fullMethod string) error {
+	permission, ok := methodPermissions[fullMethod]
+	if !ok {
+		return nil
+	}
+
+	caller, ok := CallerFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	if !caller.Can(permission) {
+		return status.Error(codes.PermissionDenied, e.ErrPermissionDenied.Error())
+	}
+	return nil
+}
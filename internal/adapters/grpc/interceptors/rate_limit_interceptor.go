@@ -0,0 +1,142 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type rateLimitKey struct{}
+
+// RateLimitByIPUnaryInterceptor refuses calls over the rate limits of the client address
+// with RESOURCE_EXHAUSTED and a RetryInfo. Public methods are in the auth group, and the
+// infrastructure services aren't limited. It runs after the ClientIP interceptor and
+// before the Auth interceptor, so that calls with bad tokens are limited too, and
+// without checking them.
+func RateLimitByIPUnaryInterceptor(limiter *usecase.RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, md, err := rateLimitByIP(ctx, limiter, info.FullMethod)
+		if err != nil {
+			_ = grpc.SetHeader(ctx, md)
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitByIPStreamInterceptor is the streaming counterpart of
+// RateLimitByIPUnaryInterceptor. Streams are counted once, when they are opened.
+func RateLimitByIPStreamInterceptor(limiter *usecase.RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, md, err := rateLimitByIP(ss.Context(), limiter, info.FullMethod)
+		if err != nil {
+			_ = ss.SetHeader(md)
+			return err
+		}
+
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// RateLimitByCallerUnaryInterceptor applies the rate limits by user and by access token
+// after the Auth interceptor, and reports the more restrictive of those and the limits
+// of RateLimitByIPUnaryInterceptor in "ratelimit-*" header metadata.
+func RateLimitByCallerUnaryInterceptor(limiter *usecase.RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, err := rateLimitByCaller(ctx, limiter, info.FullMethod)
+		if md != nil {
+			_ = grpc.SetHeader(ctx, md)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitByCallerStreamInterceptor is the streaming counterpart of
+// RateLimitByCallerUnaryInterceptor.
+func RateLimitByCallerStreamInterceptor(limiter *usecase.RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, err := rateLimitByCaller(ss.Context(), limiter, info.FullMethod)
+		if md != nil {
+			_ = ss.SetHeader(md)
+		}
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// rateLimitGroup returns the group of fullMethod, or false if it isn't limited.
+func rateLimitGroup(fullMethod string) (string, bool) {
+	switch {
+	case publicMethods[fullMethod]:
+		return usecase.RateLimitGroupAuth, true
+	case isPublic(fullMethod):
+		return "", false
+	default:
+		return usecase.RateLimitGroupAPI, true
+	}
+}
+
+// rateLimitByIP keeps the result in the returned context for rateLimitByCaller, which
+// reports it unless the call is refused here.
+func rateLimitByIP(ctx context.Context, limiter *usecase.RateLimiter, fullMethod string) (context.Context, metadata.MD, error) {
+	group, ok := rateLimitGroup(fullMethod)
+	if !ok {
+		return ctx, nil, nil
+	}
+	ip, _ := ClientIPFromContext(ctx)
+
+	res := limiter.Allow(ctx, group, nil, ip)
+	md, err := rateLimitResult(res)
+	return context.WithValue(ctx, rateLimitKey{}, res), md, err
+}
+
+func rateLimitByCaller(ctx context.Context, limiter *usecase.RateLimiter, fullMethod string) (metadata.MD, error) {
+	group, ok := rateLimitGroup(fullMethod)
+	if !ok {
+		return nil, nil
+	}
+
+	res, _ := ctx.Value(rateLimitKey{}).(usecase.RateLimitResult)
+	if c, ok := CallerFromContext(ctx); ok {
+		res = res.Combine(limiter.Allow(ctx, group, &c, ""))
+	}
+	return rateLimitResult(res)
+}
+
+// rateLimitResult returns the header metadata of res, and the error of refused calls.
+func rateLimitResult(res usecase.RateLimitResult) (metadata.MD, error) {
+	if res.Limit == 0 {
+		return nil, nil
+	}
+
+	md := metadata.MD{}
+	for k, v := range res.Headers() {
+		md.Set(strings.ToLower(k), v)
+	}
+	if res.Allowed {
+		return md, nil
+	}
+
+	st, err := status.New(codes.ResourceExhausted, e.ErrRateLimited.Error()).
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(res.RetryAfter)})
+	if err != nil {
+		return md, status.Error(codes.ResourceExhausted, e.ErrRateLimited.Error())
+	}
+	return md, st.Err()
+}
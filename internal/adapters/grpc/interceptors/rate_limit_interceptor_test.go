@@ -0,0 +1,53 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	todov1 "github.com/mrxacker/go-to-do-app/api/todo/v1"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/ratelimit"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitOrder(t *testing.T) {
+	f := newAuthFixture(t)
+	limiter := usecase.NewRateLimiter(ratelimit.NewMemoryStore(), []usecase.RateLimit{
+		{Group: usecase.RateLimitGroupAPI, Kind: usecase.RateLimitIP, Requests: 2, Period: time.Hour},
+		{Group: usecase.RateLimitGroupAPI, Kind: usecase.RateLimitUser, Requests: 1, Period: time.Hour},
+	}, zap.NewNop())
+	byIP := RateLimitByIPUnaryInterceptor(limiter)
+	byCaller := RateLimitByCallerUnaryInterceptor(limiter)
+
+	// call runs the interceptors in the order of the server.
+	call := func(ip, token string) error {
+		ctx := context.WithValue(context.Background(), clientIPKey{}, ip)
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		info := &grpc.UnaryServerInfo{FullMethod: todov1.TodoService_ListTodos_FullMethodName}
+		_, err := byIP(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+			return f.unary(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return byCaller(ctx, req, info, func(context.Context, any) (any, error) { return nil, nil })
+			})
+		})
+		return err
+	}
+
+	// Calls with bad tokens count against the address before they are authenticated.
+	for _, want := range []codes.Code{codes.Unauthenticated, codes.Unauthenticated, codes.ResourceExhausted} {
+		if err := call("192.0.2.1", "not-a-token"); status.Code(err) != want {
+			t.Fatalf("got %v with a bad token, want %v", err, want)
+		}
+	}
+
+	// The limits by user apply once the caller is known.
+	for _, want := range []codes.Code{codes.OK, codes.ResourceExhausted} {
+		if err := call("192.0.2.2", f.session); status.Code(err) != want {
+			t.Fatalf("got %v with a session token, want %v", err, want)
+		}
+	}
+}
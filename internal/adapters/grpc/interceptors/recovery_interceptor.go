@@ -0,0 +1,37 @@
+package interceptors
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryUnaryInterceptor turns a panicking handler into an Internal error instead of
+// crashing the server.
+func RecoveryUnaryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ any, err error) {
+		defer recoverPanic(logger, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+func RecoveryStreamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverPanic(logger, info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+func recoverPanic(logger *zap.Logger, method string, err *error) {
+	if r := recover(); r != nil {
+		logger.Error("grpc handler panicked",
+			zap.String("method", method),
+			zap.Any("panic", r),
+			zap.Stack("stack"),
+		)
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+)
+
+// JWKSHandler publishes the public keys access tokens are verified with.
+type JWKSHandler struct {
+	jwtService *auth.JWTService
+}
+
+func NewJWKSHandler(jwtService *auth.JWTService) *JWKSHandler {
+	return &JWKSHandler{jwtService: jwtService}
+}
+
+func (h *JWKSHandler) RegisterRoutes(r gin.IRoutes) {
+	r.GET("/.well-known/jwks.json", h.JWKS)
+}
+
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	// Verifiers refetch the set when they meet an unknown kid, so a short max-age is
+	// enough to keep rotation working.
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.jwtService.JWKS())
+}
@@ -0,0 +1,303 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+)
+
+// OAuthHandler serves the authorization server for third-party clients, and the API
+// users register clients and consent to their requests with.
+type OAuthHandler struct {
+	uc *usecase.OAuthUsecase
+}
+
+func NewOAuthHandler(uc *usecase.OAuthUsecase) *OAuthHandler {
+	return &OAuthHandler{uc: uc}
+}
+
+// RegisterRoutes registers the endpoints called by clients, which authenticate on
+// their own.
+func (h *OAuthHandler) RegisterRoutes(r gin.IRoutes) {
+	r.GET("/oauth/authorize", h.Authorize)
+	r.POST("/oauth/token", h.Token)
+	r.POST("/oauth/revoke", h.Revoke)
+	r.POST("/oauth/introspect", h.Introspect)
+}
+
+// RegisterAPIRoutes registers the endpoints called by users, behind the JWT middleware.
+func (h *OAuthHandler) RegisterAPIRoutes(rg *gin.RouterGroup) {
+	rg.POST("/clients", h.CreateClient)
+	rg.GET("/clients", h.ListClients)
+	rg.DELETE("/clients/:client_id", h.DeleteClient)
+	rg.GET("/consent", h.DescribeConsent)
+	rg.POST("/consent", h.Consent)
+}
+
+func toOAuthClientItem(client models.OAuthClient) dto.OAuthClientItem {
+	return dto.OAuthClientItem{
+		ClientID:     client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Public:       !client.Confidential(),
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+func writeOAuthClientError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, e.ErrOAuthClientNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "client not found"})
+	case errors.Is(err, e.ErrInvalidRedirectURI), errors.Is(err, e.ErrInvalidOAuthRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// writeOAuthError writes an error response of the client endpoints in the form of
+// RFC 6749 5.2.
+func writeOAuthError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, e.ErrInvalidClient):
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		c.JSON(http.StatusUnauthorized, dto.OAuthError{Error: "invalid_client"})
+	case errors.Is(err, e.ErrInvalidGrant):
+		c.JSON(http.StatusBadRequest, dto.OAuthError{Error: "invalid_grant", ErrorDescription: err.Error()})
+	case errors.Is(err, e.ErrInvalidScope):
+		c.JSON(http.StatusBadRequest, dto.OAuthError{Error: "invalid_scope", ErrorDescription: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, dto.OAuthError{Error: "server_error"})
+	}
+}
+
+// clientCredentials returns the client's credentials from HTTP Basic authentication,
+// whose parts are form-encoded (RFC 6749 2.3.1), or else from the form.
+func clientCredentials(c *gin.Context, clientID, clientSecret string) (string, string, bool) {
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		return clientID, clientSecret, clientID != ""
+	}
+
+	id, err := url.QueryUnescape(id)
+	if err != nil {
+		return "", "", false
+	}
+	secret, err = url.QueryUnescape(secret)
+	if err != nil {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req dto.OAuthAuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.OAuthError{Error: "invalid_request"})
+		return
+	}
+
+	location, err := h.uc.Authorize(c.Request.Context(), req)
+	if err != nil {
+		// The user is not sent back to a redirect URI that isn't registered.
+		switch {
+		case errors.Is(err, e.ErrOAuthClientNotFound), errors.Is(err, e.ErrInvalidRedirectURI):
+			c.JSON(http.StatusBadRequest, dto.OAuthError{Error: "invalid_request", ErrorDescription: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, dto.OAuthError{Error: "server_error"})
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, location)
+}
+
+func (h *OAuthHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req dto.OAuthTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.OAuthError{Error: "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := clientCredentials(c, req.ClientID, req.ClientSecret)
+	if !ok {
+		writeOAuthError(c, e.ErrInvalidClient)
+		return
+	}
+
+	var (
+		res dto.OAuthTokenResponse
+		err error
+	)
+	switch req.GrantType {
+	case "authorization_code":
+		res, err = h.uc.ExchangeCode(c.Request.Context(), clientID, clientSecret, req.Code, req.RedirectURI, req.CodeVerifier)
+	case "refresh_token":
+		res, err = h.uc.RefreshToken(c.Request.Context(), clientID, clientSecret, req.RefreshToken, req.Scope)
+	default:
+		c.JSON(http.StatusBadRequest, dto.OAuthError{Error: "unsupported_grant_type"})
+		return
+	}
+	if err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	var req dto.OAuthTokenForm
+	if err := c.ShouldBind(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, dto.OAuthError{Error: "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := clientCredentials(c, req.ClientID, req.ClientSecret)
+	if !ok {
+		writeOAuthError(c, e.ErrInvalidClient)
+		return
+	}
+
+	if err := h.uc.Revoke(c.Request.Context(), clientID, clientSecret, req.Token); err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	var req dto.OAuthTokenForm
+	if err := c.ShouldBind(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, dto.OAuthError{Error: "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := clientCredentials(c, req.ClientID, req.ClientSecret)
+	if !ok {
+		writeOAuthError(c, e.ErrInvalidClient)
+		return
+	}
+
+	res, err := h.uc.Introspect(c.Request.Context(), clientID, clientSecret, req.Token)
+	if err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *OAuthHandler) CreateClient(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req dto.CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	client, secret, err := h.uc.RegisterClient(c.Request.Context(), userID.(models.UserID), req.Name, req.RedirectURIs, !req.Public)
+	if err != nil {
+		writeOAuthClientError(c, err, "Failed to create client")
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.CreateOAuthClientResponse{
+		OAuthClientItem: toOAuthClientItem(client),
+		ClientSecret:    secret,
+	})
+}
+
+func (h *OAuthHandler) ListClients(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	clients, err := h.uc.ListClients(c.Request.Context(), userID.(models.UserID))
+	if err != nil {
+		writeOAuthClientError(c, err, "Failed to list clients")
+		return
+	}
+
+	res := make([]dto.OAuthClientItem, len(clients))
+	for i, client := range clients {
+		res[i] = toOAuthClientItem(client)
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *OAuthHandler) DeleteClient(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var uri dto.OAuthClientURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid client ID"})
+		return
+	}
+
+	if err := h.uc.DeleteClient(c.Request.Context(), userID.(models.UserID), uri.ClientID); err != nil {
+		writeOAuthClientError(c, err, "Failed to delete client")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *OAuthHandler) DescribeConsent(c *gin.Context) {
+	var query dto.OAuthConsentQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	res, err := h.uc.DescribeRequest(c.Request.Context(), query.Request)
+	if err != nil {
+		writeOAuthClientError(c, err, "Failed to describe request")
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *OAuthHandler) Consent(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req dto.OAuthConsentDecision
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	location, err := h.uc.Consent(c.Request.Context(), userID.(models.UserID), req.Request, req.Approve)
+	if err != nil {
+		writeOAuthClientError(c, err, "Failed to record consent")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.OAuthRedirect{RedirectURI: location})
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Todo API</title>
+</head>
+<body>
+  <redoc spec-url="/openapi.json"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/v2.5.0/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+// OpenAPIHandler serves the OpenAPI document and a page rendering it.
+type OpenAPIHandler struct {
+	spec []byte
+}
+
+func NewOpenAPIHandler(spec *openapi3.T) (*OpenAPIHandler, error) {
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{spec: b}, nil
+}
+
+func (h *OpenAPIHandler) RegisterRoutes(r gin.IRoutes) {
+	r.GET("/openapi.json", h.Spec)
+	r.GET("/docs", h.Docs)
+}
+
+func (h *OpenAPIHandler) Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.spec)
+}
+
+func (h *OpenAPIHandler) Docs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/stream"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+const (
+	sseHeartbeatInterval = 15 * time.Second
+	sseRetryMillis       = 3000
+)
+
+type StreamHandler struct {
+	hub *stream.Hub
+}
+
+func NewStreamHandler(hub *stream.Hub) *StreamHandler {
+	return &StreamHandler{hub: hub}
+}
+
+func (h *StreamHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/stream", h.StreamTodos)
+}
+
+// StreamTodos sends the caller's todo changes as Server-Sent Events. The event ID is
+// the event sequence, so a reconnecting client resumes with Last-Event-ID. When the
+// events after that ID are no longer buffered, a "reset" event tells the client to
+// reload its todos.
+func (h *StreamHandler) StreamTodos(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userID := userIDValue.(models.UserID)
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	var lastSeq int64
+	if lastEventID != "" {
+		seq, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || seq < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		lastSeq = seq
+	}
+
+	sub, replay, complete, ok := h.hub.Subscribe(userID, lastSeq)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server is shutting down"})
+		return
+	}
+	defer sub.Close()
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis); err != nil {
+		return
+	}
+	if !complete {
+		if _, err := fmt.Fprint(w, "event: reset\ndata: {}\n\n"); err != nil {
+			return
+		}
+	}
+	for _, event := range replay {
+		if err := writeSSEEvent(w, event); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := writeSSEEvent(w, event); err != nil {
+				return
+			}
+		}
+		w.Flush()
+	}
+}
+
+func writeSSEEvent(w gin.ResponseWriter, event models.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, event.Payload)
+	return err
+}
@@ -0,0 +1,256 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/webhook"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+)
+
+type WebhookHandler struct {
+	uc *usecase.WebhookUsecase
+}
+
+func NewWebhookHandler(uc *usecase.WebhookUsecase) *WebhookHandler {
+	return &WebhookHandler{uc: uc}
+}
+
+func (h *WebhookHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.POST("/", h.CreateWebhook)
+	rg.GET("/", h.ListWebhooks)
+	rg.GET("/:id", h.GetWebhook)
+	rg.PUT("/:id", h.UpdateWebhook)
+	rg.DELETE("/:id", h.DeleteWebhook)
+	rg.GET("/:id/deliveries", h.ListDeliveries)
+	rg.POST("/:id/deliveries/:delivery_id/redeliver", h.Redeliver)
+}
+
+func toWebhookItem(hook models.Webhook) dto.WebhookItem {
+	return dto.WebhookItem{
+		ID:                  hook.ID,
+		URL:                 hook.URL,
+		Events:              hook.Events,
+		Active:              hook.Active,
+		ConsecutiveFailures: hook.ConsecutiveFailures,
+		DisabledAt:          hook.DisabledAt,
+		CreatedAt:           hook.CreatedAt,
+	}
+}
+
+func toWebhookDeliveryItem(d models.WebhookDelivery) dto.WebhookDeliveryItem {
+	item := dto.WebhookDeliveryItem{
+		ID:              d.ID,
+		EventID:         d.EventID,
+		EventType:       d.EventType,
+		Status:          d.Status,
+		Attempts:        d.Attempts,
+		LastAttemptAt:   d.LastAttemptAt,
+		RequestExcerpt:  webhook.Excerpt(d.Payload),
+		ResponseStatus:  d.ResponseStatus,
+		ResponseExcerpt: d.ResponseBody,
+		Error:           d.Error,
+		RedeliveredFrom: d.RedeliveredFrom,
+		CreatedAt:       d.CreatedAt,
+	}
+
+	if d.Status == models.DeliveryPending {
+		item.NextAttemptAt = &d.NextAttemptAt
+	}
+
+	return item
+}
+
+func writeWebhookError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, e.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+	case errors.Is(err, e.ErrWebhookDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+	case errors.Is(err, e.ErrInvalidWebhookURL), errors.Is(err, e.ErrInvalidWebhookEvent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	hook, err := h.uc.CreateWebhook(c.Request.Context(), userID.(models.UserID), req.URL, req.Events)
+	if err != nil {
+		writeWebhookError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.CreateWebhookResponse{
+		WebhookItem: toWebhookItem(hook),
+		Secret:      hook.Secret,
+	})
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	hooks, err := h.uc.ListWebhooks(c.Request.Context(), userID.(models.UserID))
+	if err != nil {
+		writeWebhookError(c, err, "Failed to list webhooks")
+		return
+	}
+
+	res := make([]dto.WebhookItem, len(hooks))
+	for i, hook := range hooks {
+		res[i] = toWebhookItem(hook)
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var uri dto.WebhookURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	hook, err := h.uc.GetWebhook(c.Request.Context(), userID.(models.UserID), uri.ID)
+	if err != nil {
+		writeWebhookError(c, err, "Failed to get webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, toWebhookItem(hook))
+}
+
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var uri dto.WebhookURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	hook, err := h.uc.UpdateWebhook(c.Request.Context(), userID.(models.UserID), models.Webhook{
+		ID:     uri.ID,
+		URL:    req.URL,
+		Events: req.Events,
+		Active: req.Active,
+	})
+	if err != nil {
+		writeWebhookError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, toWebhookItem(hook))
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var uri dto.WebhookURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	if err := h.uc.DeleteWebhook(c.Request.Context(), userID.(models.UserID), uri.ID); err != nil {
+		writeWebhookError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var uri dto.WebhookURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	var req dto.ListWebhookDeliveriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	deliveries, err := h.uc.ListDeliveries(c.Request.Context(), userID.(models.UserID), uri.ID, req.Limit, req.Offset)
+	if err != nil {
+		writeWebhookError(c, err, "Failed to list deliveries")
+		return
+	}
+
+	res := make([]dto.WebhookDeliveryItem, len(deliveries))
+	for i, d := range deliveries {
+		res[i] = toWebhookDeliveryItem(d)
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var uri dto.WebhookDeliveryURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	d, err := h.uc.Redeliver(c.Request.Context(), userID.(models.UserID), uri.ID, uri.DeliveryID)
+	if err != nil {
+		writeWebhookError(c, err, "Failed to redeliver")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, toWebhookDeliveryItem(d))
+}
@@ -1,27 +1,37 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
 )
 
-func JWTMiddleware(jwtSvc *auth.JWTService) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		path := c.FullPath()
+// routeScopes are the scopes personal access tokens need for a route, by method and
+// path. Routes missing here can only be called with the access token of a login.
+var routeScopes = map[string]models.Scope{
+	"GET /api/v1/todos/stream":                                    models.ScopeTodosRead,
+	"POST /api/v1/webhooks/":                                      models.ScopeWebhooksWrite,
+	"GET /api/v1/webhooks/":                                       models.ScopeWebhooksRead,
+	"GET /api/v1/webhooks/:id":                                    models.ScopeWebhooksRead,
+	"PUT /api/v1/webhooks/:id":                                    models.ScopeWebhooksWrite,
+	"DELETE /api/v1/webhooks/:id":                                 models.ScopeWebhooksWrite,
+	"GET /api/v1/webhooks/:id/deliveries":                         models.ScopeWebhooksRead,
+	"POST /api/v1/webhooks/:id/deliveries/:delivery_id/redeliver": models.ScopeWebhooksWrite,
 
-		// List of routes to skip
-		skipPaths := map[string]bool{
-			"/api/v1/users/login":    true,
-			"/api/v1/users/register": true,
-		}
+	// Mutations also need todos:write, which the GraphQL server checks.
+	"POST /graphql": models.ScopeTodosRead,
+}
 
-		if skipPaths[path] {
-			c.Next() // skip middleware
-			return
-		}
+// JWTMiddleware authenticates the bearer token of the request, which is either the
+// access token of a login or a personal access token with the scope of the route.
+func JWTMiddleware(tokens *usecase.AccessTokenUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.FullPath()
 
 		auth := c.GetHeader("Authorization")
 		if auth == "" {
@@ -35,16 +45,45 @@ func JWTMiddleware(jwtSvc *auth.JWTService) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := jwtSvc.ParseToken(parts[1])
+		caller, err := tokens.Authenticate(c.Request.Context(), parts[1])
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			switch {
+			case errors.Is(err, e.ErrInvalidAccessToken):
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			case errors.Is(err, e.ErrEmailNotVerified):
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "email not verified"})
+			case errors.Is(err, e.ErrUserDisabled):
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			default:
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			}
+			return
+		}
+
+		if scope, ok := routeScopes[c.Request.Method+" "+path]; !caller.Session() && (!ok || !caller.Allows(scope)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": e.ErrInsufficientScope.Error()})
+			return
+		}
+
+		// GraphQL mutations are POSTed like queries, so the GraphQL server rejects them
+		// itself.
+		if caller.ReadOnly && !isSafeMethod(c.Request.Method) && path != "/graphql" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "email not verified"})
 			return
 		}
 
 		// Store user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
+		c.Set("user_id", caller.UserID)
+		c.Set("email", caller.Email)
+		c.Set("read_only", caller.ReadOnly)
+		c.Set("scopes", caller.Scopes)
+		c.Set("caller_key", caller.Key)
+		c.Set("permissions", caller.Permissions)
 
 		c.Next()
 	}
 }
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
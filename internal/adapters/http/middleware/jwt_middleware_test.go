@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+)
+
+// sessionRoutes are routes behind the middleware that only the access token of a login
+// may call.
+var sessionRoutes = []string{
+	"POST /api/v1/oauth/clients",
+	"GET /api/v1/oauth/clients",
+	"DELETE /api/v1/oauth/clients/:client_id",
+	"GET /api/v1/oauth/consent",
+	"POST /api/v1/oauth/consent",
+}
+
+var pathParam = regexp.MustCompile(`:[a-z_]+`)
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[models.UserID]models.User
+}
+
+func (r *fakeUserRepo) GetUserByID(_ context.Context, id models.UserID) (models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, e.ErrUserNotFound
+	}
+	return user, nil
+}
+
+type fakeAccessTokenRepo struct {
+	repository.AccessTokenRepository
+	tokens map[string]models.AccessToken
+}
+
+func (r *fakeAccessTokenRepo) GetAccessTokenByHash(_ context.Context, hash string) (models.AccessToken, error) {
+	t, ok := r.tokens[hash]
+	if !ok {
+		return models.AccessToken{}, e.ErrInvalidAccessToken
+	}
+	return t, nil
+}
+
+func (r *fakeAccessTokenRepo) TouchAccessToken(context.Context, models.AccessTokenID) error {
+	return nil
+}
+
+func TestJWTMiddlewareRefusesRoutesOutsideScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	verified := time.Now()
+	users := &fakeUserRepo{users: map[models.UserID]models.User{
+		1: {ID: 1, Email: "alice@example.com", EmailVerifiedAt: &verified, Role: models.RoleUser},
+	}}
+	tokens := &fakeAccessTokenRepo{tokens: make(map[string]models.AccessToken)}
+	jwt := auth.NewJWTService(auth.NewHMACKeySet("test-secret"), "test", "test", time.Hour)
+	uc := usecase.NewAccessTokenUsecase(tokens, users, nil, nil, jwt, usecase.VerificationOptional)
+
+	routes := append([]string{}, sessionRoutes...)
+	for route := range routeScopes {
+		routes = append(routes, route)
+	}
+	r := gin.New()
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		r.Handle(method, path, JWTMiddleware(uc), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+	}
+	do := func(route, token string) *httptest.ResponseRecorder {
+		method, path, _ := strings.Cut(route, " ")
+		req := httptest.NewRequest(method, pathParam.ReplaceAllString(path, "1"), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	session, err := jwt.GenerateToken(users.users[1], false, "")
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	for _, route := range routes {
+		if rec := do(route, session); rec.Code != http.StatusNoContent {
+			t.Errorf("%s: got %d with a session token, want %d", route, rec.Code, http.StatusNoContent)
+		}
+	}
+
+	for i, scope := range models.Scopes {
+		pat, hash, err := auth.GenerateOpaqueToken("pat_")
+		if err != nil {
+			t.Fatalf("generate access token: %v", err)
+		}
+		tokens.tokens[hash] = models.AccessToken{ID: models.AccessTokenID(i + 1), UserID: 1, Scopes: []models.Scope{scope}}
+
+		for _, route := range routes {
+			rec := do(route, pat)
+			want := http.StatusForbidden
+			if routeScopes[route] == scope {
+				want = http.StatusNoContent
+			}
+			if rec.Code != want {
+				t.Errorf("%s with %s: got %d, want %d", route, scope, rec.Code, want)
+			}
+			if want == http.StatusForbidden && !strings.Contains(rec.Body.String(), e.ErrInsufficientScope.Error()) {
+				t.Errorf("%s with %s: got %s, want %q", route, scope, rec.Body, e.ErrInsufficientScope)
+			}
+		}
+	}
+}
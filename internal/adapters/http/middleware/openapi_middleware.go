@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxValidatedResponse is the size up to which responses are validated; larger ones
+// are passed through unchecked.
+const maxValidatedResponse = 1 << 20
+
+func init() {
+	// Formats other than date-time aren't checked unless defined.
+	openapi3.DefineStringFormatValidator("email", openapi3.NewRegexpFormatValidator(openapi3.FormatOfStringForEmail))
+}
+
+// OpenAPIValidator rejects requests to routes of spec that don't match it with 400.
+// With validateResponses, it also checks the responses of those routes and logs the
+// ones that don't match, which is meant for development. Routes missing from spec
+// are passed through.
+func OpenAPIValidator(spec *openapi3.T, validateResponses bool, logger *zap.Logger) (gin.HandlerFunc, error) {
+	router, err := legacy.NewRouter(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}
+	opts.WithCustomSchemaErrorFunc(func(err *openapi3.SchemaError) string {
+		if path := err.JSONPointer(); len(path) > 0 {
+			return fmt.Sprintf("%s: %s", strings.Join(path, "."), err.Reason)
+		}
+		return err.Reason
+	})
+
+	resOpts := *opts
+	resOpts.IncludeResponseStatus = true
+
+	return func(c *gin.Context) {
+		// Routes are documented without the trailing slash that some groups register.
+		req := c.Request.Clone(c.Request.Context())
+		req.URL.Path = strings.TrimSuffix(req.URL.Path, "/")
+
+		route, pathParams, err := router.FindRoute(req)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    opts,
+		}
+		err = openapi3filter.ValidateRequest(c.Request.Context(), input)
+		// The validator replaces the body it has read.
+		c.Request.Body = req.Body
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": validationMessage(err)})
+			return
+		}
+
+		if !validateResponses {
+			c.Next()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		if w.Status() == http.StatusSwitchingProtocols || w.overflow ||
+			strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+			return
+		}
+
+		res := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 w.Status(),
+			Header:                 w.Header(),
+			Options:                &resOpts,
+		}
+		res.SetBodyBytes(w.body.Bytes())
+		if err := openapi3filter.ValidateResponse(c.Request.Context(), res); err != nil {
+			logger.Error("response doesn't match the OpenAPI spec",
+				zap.String("method", c.Request.Method),
+				zap.String("path", route.Path),
+				zap.Int("status", w.Status()),
+				zap.Error(err),
+			)
+		}
+	}, nil
+}
+
+func validationMessage(err error) string {
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.Error()
+	}
+	var routeErr *routers.RouteError
+	if errors.As(err, &routeErr) {
+		return routeErr.Reason
+	}
+	return "Invalid request"
+}
+
+// recordingWriter keeps a copy of the response body for validation while writing it
+// through, so that streamed responses aren't delayed.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.record(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recordingWriter) record(b []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(b) > maxValidatedResponse {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(b)
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+// RequirePermission refuses requests unless the caller's role grants permission. It
+// runs after JWTMiddleware, like RequirePermissionUnaryInterceptor does for gRPC.
+func RequirePermission(permission models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("permissions")
+		permissions, _ := value.([]models.Permission)
+		if !slices.Contains(permissions, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": e.ErrPermissionDenied.Error()})
+			return
+		}
+
+		c.Next()
+	}
+}
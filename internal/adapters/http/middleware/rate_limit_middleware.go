@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/clientip"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+)
+
+// rateLimitKey holds the result of RateLimitByIP, which RateLimitByCaller combines with
+// its own.
+const rateLimitKey = "rate_limit"
+
+// RateLimitByIP refuses requests over the rate limits of group by client address with
+// 429 and Retry-After, and reports the limits in RateLimit-* headers, like
+// RateLimitByIPUnaryInterceptor does for gRPC. It goes before JWTMiddleware, so that
+// requests with bad tokens are limited too, and without checking them.
+func RateLimitByIP(limiter *usecase.RateLimiter, clientIPs *clientip.Resolver, group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := clientIPs.Resolve(c.Request.RemoteAddr, c.Request.Header.Values("X-Forwarded-For"))
+
+		res := limiter.Allow(c.Request.Context(), group, nil, ip)
+		if !writeRateLimit(c, res) {
+			return
+		}
+
+		c.Set(rateLimitKey, res)
+		c.Next()
+	}
+}
+
+// RateLimitByCaller applies the rate limits of group by user and by access token. It
+// goes after JWTMiddleware, and reports the more restrictive of its limits and those of
+// RateLimitByIP.
+func RateLimitByCaller(limiter *usecase.RateLimiter, group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.Next()
+			return
+		}
+		caller := &models.Caller{UserID: userID.(models.UserID), Key: c.GetString("caller_key")}
+
+		res := limiter.Allow(c.Request.Context(), group, caller, "")
+		if byIP, ok := c.Get(rateLimitKey); ok {
+			res = byIP.(usecase.RateLimitResult).Combine(res)
+		}
+		if !writeRateLimit(c, res) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// writeRateLimit sets the headers of res, and aborts the request if it was refused.
+func writeRateLimit(c *gin.Context, res usecase.RateLimitResult) bool {
+	if res.Limit != 0 {
+		for k, v := range res.Headers() {
+			c.Header(k, v)
+		}
+	}
+	if !res.Allowed {
+		c.Header("Retry-After", strconv.FormatInt(res.RetryAfterSeconds(), 10))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": e.ErrRateLimited.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/clientip"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/ratelimit"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"go.uber.org/zap"
+)
+
+func TestRateLimitOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	users := &fakeUserRepo{users: map[models.UserID]models.User{
+		1: {ID: 1, Email: "alice@example.com", Role: models.RoleUser},
+	}}
+	jwt := auth.NewJWTService(auth.NewHMACKeySet("test-secret"), "test", "test", time.Hour)
+	tokens := usecase.NewAccessTokenUsecase(&fakeAccessTokenRepo{}, users, nil, nil, jwt, usecase.VerificationOptional)
+	limiter := usecase.NewRateLimiter(ratelimit.NewMemoryStore(), []usecase.RateLimit{
+		{Group: usecase.RateLimitGroupAPI, Kind: usecase.RateLimitIP, Requests: 2, Period: time.Hour},
+		{Group: usecase.RateLimitGroupAPI, Kind: usecase.RateLimitUser, Requests: 1, Period: time.Hour},
+	}, zap.NewNop())
+	clientIPs, err := clientip.NewResolver(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The order of the API routes.
+	r := gin.New()
+	r.GET("/api/v1/webhooks/",
+		RateLimitByIP(limiter, clientIPs, usecase.RateLimitGroupAPI),
+		JWTMiddleware(tokens),
+		RateLimitByCaller(limiter, usecase.RateLimitGroupAPI),
+		func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	do := func(ip, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/webhooks/", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Requests with bad tokens count against the address before they are authenticated.
+	for _, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if rec := do("192.0.2.1", "not-a-token"); rec.Code != want {
+			t.Fatalf("got %d with a bad token, want %d", rec.Code, want)
+		}
+	}
+
+	// The limits by user apply once the caller is known, and the headers report the
+	// tighter of the limits.
+	session, err := jwt.GenerateToken(users.users[1], false, "")
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	rec := do("192.0.2.2", session)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "1" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("got %d with limit %q and %q remaining, want %d with 1 and 0", rec.Code,
+			rec.Header().Get("RateLimit-Limit"), rec.Header().Get("RateLimit-Remaining"), http.StatusNoContent)
+	}
+	if rec := do("192.0.2.2", session); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("got %d, want %d with Retry-After", rec.Code, http.StatusTooManyRequests)
+	}
+}
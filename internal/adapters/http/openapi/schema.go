@@ -0,0 +1,160 @@
+package openapi
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// enums lists the values of the string types that only accept a fixed set.
+var enums = map[reflect.Type][]any{
+	reflect.TypeOf(models.EventType("")): enum(models.TodoEventTypes...),
+	reflect.TypeOf(models.DeliveryStatus("")): enum(
+		models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed,
+	),
+	reflect.TypeOf(models.Scope("")): enum(models.Scopes...),
+	reflect.TypeOf(models.Role("")):  enum(models.Roles...),
+}
+
+// enum converts values to plain strings, which is what validators compare against.
+func enum[T ~string](values ...T) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// schemaGenerator derives component schemas from the dto types, using their json
+// tags for property names and their binding tags for constraints.
+type schemaGenerator struct {
+	schemas openapi3.Schemas
+}
+
+// ref registers the schema of the struct type of v under its type name and returns a
+// reference to it. Request bodies require the fields bound as required; responses
+// require every field that isn't omitted when empty.
+func (g *schemaGenerator) ref(v any, request bool) *openapi3.SchemaRef {
+	t := reflect.TypeOf(v)
+	schema, ok := g.schemas[t.Name()]
+	if !ok {
+		schema = openapi3.NewSchemaRef("", g.object(t, request))
+		g.schemas[t.Name()] = schema
+	}
+	return openapi3.NewSchemaRef("#/components/schemas/"+t.Name(), schema.Value)
+}
+
+// list returns an array schema of the struct type of v.
+func (g *schemaGenerator) list(v any) *openapi3.SchemaRef {
+	schema := openapi3.NewArraySchema()
+	schema.Items = g.ref(v, false)
+	return schema.NewRef()
+}
+
+func (g *schemaGenerator) object(t reflect.Type, request bool) *openapi3.Schema {
+	schema := openapi3.NewObjectSchema()
+	g.addFields(schema, t, request)
+	return schema
+}
+
+func (g *schemaGenerator) addFields(schema *openapi3.Schema, t reflect.Type, request bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			g.addFields(schema, f.Type, request)
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		binding := strings.Split(f.Tag.Get("binding"), ",")
+		prop := g.field(f.Type, binding)
+		schema.WithPropertyRef(name, prop.NewRef())
+
+		required := !strings.Contains(opts, "omitempty")
+		if request {
+			required = slices.Contains(binding, "required")
+		}
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func (g *schemaGenerator) field(t reflect.Type, binding []string) *openapi3.Schema {
+	// Pointers are optional values, which the gateway renders as null when unset.
+	nullable := t.Kind() == reflect.Pointer
+	if nullable {
+		t = t.Elem()
+	}
+
+	var schema *openapi3.Schema
+	switch {
+	case t == timeType:
+		schema = openapi3.NewDateTimeSchema()
+	case t.Kind() == reflect.String:
+		schema = openapi3.NewStringSchema()
+		if values, ok := enums[t]; ok {
+			schema.WithEnum(values...)
+		}
+		if slices.Contains(binding, "email") {
+			schema.WithFormat("email")
+		}
+		if slices.Contains(binding, "url") {
+			schema.WithFormat("uri")
+		}
+	case t.Kind() == reflect.Bool:
+		schema = openapi3.NewBoolSchema()
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = openapi3.NewIntegerSchema()
+		if t.Kind() == reflect.Int64 {
+			schema.WithFormat("int64")
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		schema = openapi3.NewBytesSchema()
+	case t.Kind() == reflect.Slice:
+		schema = openapi3.NewArraySchema().WithItems(g.field(t.Elem(), nil))
+	case t.Kind() == reflect.Struct:
+		return g.object(t, false)
+	case t.Kind() == reflect.Map:
+		schema = openapi3.NewObjectSchema()
+	default:
+		panic("openapi: unsupported field type " + t.String())
+	}
+
+	schema.Nullable = nullable
+
+	for _, rule := range binding {
+		key, value, ok := strings.Cut(rule, "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case key == "min" && t.Kind() == reflect.String:
+			schema.WithMinLength(int64(n))
+		case key == "max" && t.Kind() == reflect.String:
+			schema.WithMaxLength(int64(n))
+		case key == "min" && t.Kind() == reflect.Slice:
+			schema.WithMinItems(int64(n))
+		case key == "max" && t.Kind() == reflect.Slice:
+			schema.WithMaxItems(int64(n))
+		}
+	}
+
+	return schema
+}
@@ -0,0 +1,675 @@
+package openapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+)
+
+const bearerAuth = "bearerAuth"
+
+// errorResponses are the shared error responses, referenced by status code.
+var errorResponses = map[int]string{
+	http.StatusBadRequest:          "The request is invalid.",
+	http.StatusUnauthorized:        "The access token is missing or invalid.",
+	http.StatusForbidden:           "The user is disabled or unverified, the access token is read-only or lacks the scope, or the user's role lacks the permission.",
+	http.StatusNotFound:            "The resource doesn't exist or belongs to another user.",
+	http.StatusConflict:            "The resource already exists.",
+	http.StatusTooManyRequests:     "Too many attempts or requests; try again after Retry-After seconds.",
+	http.StatusInternalServerError: "An unexpected error occurred.",
+	http.StatusServiceUnavailable:  "The server is shutting down.",
+}
+
+type builder struct {
+	doc    *openapi3.T
+	schema *schemaGenerator
+}
+
+// NewSpec returns the OpenAPI document of the REST API under /api/v1. Routes are
+// authenticated with a bearer access token unless marked public.
+func NewSpec() (*openapi3.T, error) {
+	b := &builder{
+		doc: &openapi3.T{
+			OpenAPI: "3.1.0",
+			Info: &openapi3.Info{
+				Title:   "Todo API",
+				Version: "1.0.0",
+				Description: "Requests are rate limited per user, access token and client address. " +
+					"Responses report the closest limit in RateLimit-Limit, RateLimit-Remaining, " +
+					"RateLimit-Reset and RateLimit-Policy headers.",
+			},
+			Paths: openapi3.NewPaths(),
+			Components: &openapi3.Components{
+				Schemas:   openapi3.Schemas{},
+				Responses: openapi3.ResponseBodies{},
+				SecuritySchemes: openapi3.SecuritySchemes{
+					bearerAuth: &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme()},
+				},
+			},
+			Security: openapi3.SecurityRequirements{openapi3.NewSecurityRequirement().Authenticate(bearerAuth)},
+		},
+	}
+	b.schema = &schemaGenerator{schemas: b.doc.Components.Schemas}
+
+	errRef := b.schema.ref(dto.ErrorResponse{}, false)
+	for code, desc := range errorResponses {
+		b.doc.Components.Responses[strconv.Itoa(code)] = &openapi3.ResponseRef{
+			Value: openapi3.NewResponse().WithDescription(desc).WithJSONSchemaRef(errRef),
+		}
+	}
+	b.doc.Components.Responses[strconv.Itoa(http.StatusTooManyRequests)].Value.Headers = openapi3.Headers{
+		"Retry-After": &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+			Description: "Seconds to wait before trying again.",
+			Schema:      openapi3.NewIntegerSchema().NewRef(),
+		}}},
+	}
+
+	b.users()
+	b.todos()
+	b.lists()
+	b.webhooks()
+	b.oauth()
+	b.admin()
+
+	if err := b.doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return b.doc, nil
+}
+
+func (b *builder) users() {
+	b.add(http.MethodPost, "/api/v1/users/register", public(jsonBody(&openapi3.Operation{
+		OperationID: "registerUser",
+		Summary:     "Register a user",
+		Tags:        []string{"users"},
+	}, b.schema.ref(dto.RegisterUserRequest{}, true))),
+		http.StatusCreated, created(b.schema.ref(dto.RegisterUserResponse{}, false)),
+		http.StatusBadRequest, http.StatusConflict)
+
+	b.add(http.MethodPost, "/api/v1/users/login", public(jsonBody(&openapi3.Operation{
+		OperationID: "loginUser",
+		Summary:     "Log in and get an access token",
+		Description: "Users with two-factor authentication get only an mfa_token, to be exchanged " +
+			"for tokens at /api/v1/users/login/mfa. After repeated failures for an email or from an " +
+			"address, logins wait longer and longer and are then refused for a while; the user is " +
+			"mailed a link to unlock their account.",
+		Tags: []string{"users"},
+	}, b.schema.ref(dto.LoginUserRequest{}, true))),
+		http.StatusOK, ok(b.schema.ref(dto.AuthResponse{}, false)),
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden)
+
+	b.add(http.MethodPost, "/api/v1/users/login/mfa", public(jsonBody(&openapi3.Operation{
+		OperationID: "verifyMFA",
+		Summary:     "Complete a login with a TOTP or recovery code",
+		Description: "Each code, and each mfa_token, can only be used once. After repeated wrong codes, codes " +
+			"are refused for a while.",
+		Tags: []string{"users"},
+	}, b.schema.ref(dto.VerifyMFARequest{}, true))),
+		http.StatusOK, ok(b.schema.ref(dto.AuthResponse{}, false)),
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden)
+
+	b.add(http.MethodPost, "/api/v1/users/login/passkey/begin", public(&openapi3.Operation{
+		OperationID: "beginPasskeyLogin",
+		Summary:     "Start logging in with a passkey",
+		Description: "Pass the options to navigator.credentials.get; the browser offers every passkey " +
+			"it has for the site.",
+		Tags: []string{"users"},
+	}),
+		http.StatusOK, ok(b.schema.ref(dto.PasskeyCeremony{}, false)))
+
+	b.add(http.MethodPost, "/api/v1/users/login/passkey/finish", public(jsonBody(&openapi3.Operation{
+		OperationID: "finishPasskeyLogin",
+		Summary:     "Log in with the browser's response to a passkey challenge",
+		Description: "Each challenge can only be answered once. Passkeys verify the user themselves, " +
+			"so no TOTP code is asked for.",
+		Tags: []string{"users"},
+	}, b.schema.ref(dto.FinishPasskeyLoginRequest{}, true))),
+		http.StatusOK, ok(b.schema.ref(dto.AuthResponse{}, false)),
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden)
+
+	b.add(http.MethodPost, "/api/v1/users/login/oidc/{provider}", public(withParams(&openapi3.Operation{
+		OperationID: "beginOIDCLogin",
+		Summary:     "Start logging in at an OpenID Connect provider",
+		Description: "Send the user to authorization_url. The provider redirects back with a state and " +
+			"a code for the callback.",
+		Tags: []string{"users"},
+	}, providerParam())),
+		http.StatusOK, ok(b.schema.ref(dto.OIDCLoginResponse{}, false)),
+		http.StatusNotFound)
+
+	b.add(http.MethodPost, "/api/v1/users/login/oidc/{provider}/callback", public(withParams(jsonBody(&openapi3.Operation{
+		OperationID: "finishOIDCLogin",
+		Summary:     "Log in with the code an OpenID Connect provider redirected back with",
+		Description: "On the first login the identity is linked to the user with the same email, which " +
+			"both the provider and the user must have verified. Users with two-factor authentication " +
+			"get only an mfa_token.",
+		Tags: []string{"users"},
+	}, b.schema.ref(dto.FinishOIDCLoginRequest{}, true)), providerParam())),
+		http.StatusOK, ok(b.schema.ref(dto.AuthResponse{}, false)),
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound)
+
+	b.add(http.MethodPost, "/api/v1/users/refresh", public(jsonBody(&openapi3.Operation{
+		OperationID: "refreshTokens",
+		Summary:     "Exchange a refresh token for new tokens",
+		Description: "Refresh tokens can only be used once. Presenting one again revokes every token " +
+			"issued from the same login.",
+		Tags: []string{"users"},
+	}, b.schema.ref(dto.RefreshTokenRequest{}, true))),
+		http.StatusOK, ok(b.schema.ref(dto.AuthResponse{}, false)),
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden)
+
+	b.add(http.MethodPost, "/api/v1/users/logout", public(jsonBody(&openapi3.Operation{
+		OperationID: "logout",
+		Summary:     "Revoke a refresh token and every token issued from the same login",
+		Tags:        []string{"users"},
+	}, b.schema.ref(dto.RefreshTokenRequest{}, true))),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest)
+
+	b.add(http.MethodPost, "/api/v1/users/password/forgot", public(jsonBody(&openapi3.Operation{
+		OperationID: "forgotPassword",
+		Summary:     "Mail a password reset link",
+		Description: "The request is accepted whether or not the email is registered.",
+		Tags:        []string{"users"},
+	}, b.schema.ref(dto.ForgotPasswordRequest{}, true))),
+		http.StatusAccepted, accepted(),
+		http.StatusBadRequest)
+
+	b.add(http.MethodPost, "/api/v1/users/password/reset", public(jsonBody(&openapi3.Operation{
+		OperationID: "resetPassword",
+		Summary:     "Set a new password with a token from a reset link",
+		Description: "Reset tokens can only be used once. Resetting the password ends every session of the user, " +
+			"including the access tokens of their logins, and revokes the access of their OAuth clients. Personal " +
+			"access tokens keep working; users can review and delete them at /api/v1/users/me/tokens.",
+		Tags: []string{"users"},
+	}, b.schema.ref(dto.ResetPasswordRequest{}, true))),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest)
+
+	b.add(http.MethodPost, "/api/v1/users/email/verify", public(jsonBody(&openapi3.Operation{
+		OperationID: "verifyEmail",
+		Summary:     "Verify an email with a token from a verification link",
+		Description: "Sessions get the permissions of a verified user on their next refresh.",
+		Tags:        []string{"users"},
+	}, b.schema.ref(dto.VerifyEmailRequest{}, true))),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest)
+
+	b.add(http.MethodPost, "/api/v1/users/email/resend", public(jsonBody(&openapi3.Operation{
+		OperationID: "resendVerification",
+		Summary:     "Mail a new verification link",
+		Description: "The request is accepted whether or not the email is registered. Links are mailed " +
+			"at most once every few minutes.",
+		Tags: []string{"users"},
+	}, b.schema.ref(dto.ResendVerificationRequest{}, true))),
+		http.StatusAccepted, accepted(),
+		http.StatusBadRequest)
+
+	b.add(http.MethodPost, "/api/v1/users/unlock", public(jsonBody(&openapi3.Operation{
+		OperationID: "unlockAccount",
+		Summary:     "Let logins resume with a token from an unlock link",
+		Description: "Unlock links are mailed when failed logins lock an account, and work once " +
+			"while that lockout lasts. Logins from an address that is locked itself still wait.",
+		Tags: []string{"users"},
+	}, b.schema.ref(dto.UnlockAccountRequest{}, true))),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest)
+
+	b.add(http.MethodPut, "/api/v1/users/me/email", jsonBody(&openapi3.Operation{
+		OperationID: "changeEmail",
+		Summary:     "Change the caller's email",
+		Description: "The new email has to be verified again. Read-only tokens may call this, so that " +
+			"a mistyped email can be fixed; 403 means the password is wrong. Wrong passwords count as failed " +
+			"logins of the account.",
+		Tags: []string{"users"},
+	}, b.schema.ref(dto.ChangeEmailRequest{}, true)),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusConflict)
+
+	b.add(http.MethodPost, "/api/v1/users/me/mfa/totp", &openapi3.Operation{
+		OperationID: "enrollTOTP",
+		Summary:     "Generate a TOTP secret for the caller",
+		Description: "Logins don't ask for codes until the secret is confirmed.",
+		Tags:        []string{"users"},
+	}, http.StatusOK, ok(b.schema.ref(dto.TOTPEnrollment{}, false)),
+		http.StatusBadRequest, http.StatusConflict)
+
+	b.add(http.MethodPost, "/api/v1/users/me/mfa/totp/confirm", jsonBody(&openapi3.Operation{
+		OperationID: "confirmTOTP",
+		Summary:     "Enable two-factor authentication with a first code",
+		Description: "Returns the recovery codes, which are not shown again.",
+		Tags:        []string{"users"},
+	}, b.schema.ref(dto.ConfirmTOTPRequest{}, true)),
+		http.StatusOK, ok(b.schema.ref(dto.ConfirmTOTPResponse{}, false)),
+		http.StatusBadRequest, http.StatusConflict)
+
+	b.add(http.MethodPost, "/api/v1/users/me/mfa/totp/disable", jsonBody(&openapi3.Operation{
+		OperationID: "disableTOTP",
+		Summary:     "Turn two-factor authentication off",
+		Description: "Wrong passwords count as failed logins of the account.",
+		Tags:        []string{"users"},
+	}, b.schema.ref(dto.DisableTOTPRequest{}, true)),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest)
+
+	b.add(http.MethodPost, "/api/v1/users/me/passkeys/begin", &openapi3.Operation{
+		OperationID: "beginPasskeyRegistration",
+		Summary:     "Start creating a passkey for the caller",
+		Description: "Pass the options to navigator.credentials.create.",
+		Tags:        []string{"users"},
+	}, http.StatusOK, ok(b.schema.ref(dto.PasskeyCeremony{}, false)))
+
+	b.add(http.MethodPost, "/api/v1/users/me/passkeys/finish", jsonBody(&openapi3.Operation{
+		OperationID: "finishPasskeyRegistration",
+		Summary:     "Store a passkey with the browser's response to the registration challenge",
+		Tags:        []string{"users"},
+	}, b.schema.ref(dto.FinishPasskeyRegistrationRequest{}, true)),
+		http.StatusCreated, created(b.schema.ref(dto.PasskeyItem{}, false)),
+		http.StatusBadRequest, http.StatusConflict)
+
+	b.add(http.MethodGet, "/api/v1/users/me/passkeys", &openapi3.Operation{
+		OperationID: "listPasskeys",
+		Summary:     "List the caller's passkeys",
+		Tags:        []string{"users"},
+	},
+		http.StatusOK, ok(b.schema.list(dto.PasskeyItem{})))
+
+	b.add(http.MethodDelete, "/api/v1/users/me/passkeys/{id}", withParams(&openapi3.Operation{
+		OperationID: "deletePasskey",
+		Summary:     "Delete a passkey",
+		Tags:        []string{"users"},
+	}, idParam("id")),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodPost, "/api/v1/users/me/tokens", jsonBody(&openapi3.Operation{
+		OperationID: "createAccessToken",
+		Summary:     "Create a personal access token",
+		Description: "The token is only returned here. It is sent as a bearer token and allows the routes " +
+			"its scopes cover; it can't be used to manage the account or other tokens.",
+		Tags: []string{"users"},
+	}, b.schema.ref(dto.CreateAccessTokenRequest{}, true)),
+		http.StatusCreated, created(b.schema.ref(dto.CreateAccessTokenResponse{}, false)),
+		http.StatusBadRequest)
+
+	b.add(http.MethodGet, "/api/v1/users/me/tokens", &openapi3.Operation{
+		OperationID: "listAccessTokens",
+		Summary:     "List the caller's personal access tokens",
+		Tags:        []string{"users"},
+	},
+		http.StatusOK, ok(b.schema.list(dto.AccessTokenItem{})))
+
+	b.add(http.MethodDelete, "/api/v1/users/me/tokens/{id}", withParams(&openapi3.Operation{
+		OperationID: "deleteAccessToken",
+		Summary:     "Revoke a personal access token",
+		Tags:        []string{"users"},
+	}, idParam("id")),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusNotFound)
+}
+
+func (b *builder) todos() {
+	b.add(http.MethodGet, "/api/v1/todos", withParams(&openapi3.Operation{
+		OperationID: "listTodos",
+		Summary:     "List the caller's todos",
+		Tags:        []string{"todos"},
+	}, pageParams()...),
+		http.StatusOK, ok(b.schema.list(dto.TodoItem{})),
+		http.StatusBadRequest)
+
+	b.add(http.MethodPost, "/api/v1/todos", jsonBody(&openapi3.Operation{
+		OperationID: "createTodo",
+		Summary:     "Create a todo",
+		Tags:        []string{"todos"},
+	}, b.schema.ref(dto.CreateTodoBody{}, true)),
+		http.StatusCreated, created(b.schema.ref(dto.CreateTodoResponse{}, false)),
+		http.StatusBadRequest)
+
+	b.add(http.MethodGet, "/api/v1/todos/{id}", withParams(&openapi3.Operation{
+		OperationID: "getTodo",
+		Summary:     "Get a todo",
+		Tags:        []string{"todos"},
+	}, idParam("id")),
+		http.StatusOK, ok(b.schema.ref(dto.TodoItem{}, false)),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodPut, "/api/v1/todos/{id}", withParams(jsonBody(&openapi3.Operation{
+		OperationID: "updateTodo",
+		Summary:     "Replace a todo",
+		Tags:        []string{"todos"},
+	}, b.schema.ref(dto.UpdateTodoBody{}, true)), idParam("id")),
+		http.StatusOK, openapi3.NewResponse().WithDescription("The todo was updated."),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodDelete, "/api/v1/todos/{id}", withParams(&openapi3.Operation{
+		OperationID: "deleteTodo",
+		Summary:     "Delete a todo",
+		Tags:        []string{"todos"},
+	}, idParam("id")),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodGet, "/api/v1/todos/stream", withParams(&openapi3.Operation{
+		OperationID: "streamTodos",
+		Summary:     "Stream changes to the caller's todos as Server-Sent Events",
+		Description: "Each event's ID is its sequence number. Reconnect with Last-Event-ID to resume; " +
+			"a reset event means the missed events are gone and the todos must be reloaded.",
+		Tags: []string{"todos"},
+	},
+		openapi3.NewHeaderParameter("Last-Event-ID").WithSchema(openapi3.NewStringSchema()),
+		openapi3.NewQueryParameter("last_event_id").WithSchema(openapi3.NewStringSchema()),
+	),
+		http.StatusOK, openapi3.NewResponse().WithDescription("An event stream.").
+			WithContent(openapi3.Content{"text/event-stream": openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema())}),
+		http.StatusBadRequest, http.StatusServiceUnavailable)
+
+	b.add(http.MethodGet, "/api/v1/ws", withParams(public(&openapi3.Operation{
+		OperationID: "todosWebSocket",
+		Summary:     "Open a WebSocket for live todo lists",
+		Description: "Request the todos.v1 subprotocol. Browsers can't set headers on WebSocket " +
+			"requests, so the access token may also be passed as a second subprotocol, bearer.<token>.",
+		Tags: []string{"todos"},
+	}), openapi3.NewHeaderParameter("Sec-WebSocket-Protocol").WithSchema(openapi3.NewStringSchema())),
+		http.StatusSwitchingProtocols, openapi3.NewResponse().WithDescription("Switching to the WebSocket protocol."),
+		http.StatusUnauthorized)
+}
+
+func (b *builder) lists() {
+	b.add(http.MethodGet, "/api/v1/lists/me/members", &openapi3.Operation{
+		OperationID: "listListMembers",
+		Summary:     "List the users the caller's list is shared with",
+		Tags:        []string{"lists"},
+	},
+		http.StatusOK, ok(b.schema.list(dto.ListMemberItem{})))
+
+	b.add(http.MethodPost, "/api/v1/lists/me/members", jsonBody(&openapi3.Operation{
+		OperationID: "shareList",
+		Summary:     "Share the caller's list with a user",
+		Description: "Members subscribe to the list over the WebSocket API, where they see its todos and " +
+			"viewers and can change its todos. Sharing needs the access token of a login. The request is " +
+			"accepted whether or not the email is registered.",
+		Tags: []string{"lists"},
+	}, b.schema.ref(dto.ShareListRequest{}, true)),
+		http.StatusAccepted, accepted(),
+		http.StatusBadRequest)
+
+	b.add(http.MethodDelete, "/api/v1/lists/me/members/{user_id}", withParams(&openapi3.Operation{
+		OperationID: "unshareList",
+		Summary:     "Stop sharing the caller's list with a user",
+		Tags:        []string{"lists"},
+	}, idParam("user_id")),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodGet, "/api/v1/lists/shared", &openapi3.Operation{
+		OperationID: "listSharedLists",
+		Summary:     "List the lists other users shared with the caller",
+		Tags:        []string{"lists"},
+	},
+		http.StatusOK, ok(b.schema.list(dto.SharedListItem{})))
+}
+
+func (b *builder) webhooks() {
+	b.add(http.MethodGet, "/api/v1/webhooks", &openapi3.Operation{
+		OperationID: "listWebhooks",
+		Summary:     "List the caller's webhooks",
+		Tags:        []string{"webhooks"},
+	},
+		http.StatusOK, ok(b.schema.list(dto.WebhookItem{})))
+
+	b.add(http.MethodPost, "/api/v1/webhooks", jsonBody(&openapi3.Operation{
+		OperationID: "createWebhook",
+		Summary:     "Create a webhook",
+		Description: "The response is the only one that includes the signing secret.",
+		Tags:        []string{"webhooks"},
+	}, b.schema.ref(dto.CreateWebhookRequest{}, true)),
+		http.StatusCreated, created(b.schema.ref(dto.CreateWebhookResponse{}, false)),
+		http.StatusBadRequest)
+
+	b.add(http.MethodGet, "/api/v1/webhooks/{id}", withParams(&openapi3.Operation{
+		OperationID: "getWebhook",
+		Summary:     "Get a webhook",
+		Tags:        []string{"webhooks"},
+	}, idParam("id")),
+		http.StatusOK, ok(b.schema.ref(dto.WebhookItem{}, false)),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodPut, "/api/v1/webhooks/{id}", withParams(jsonBody(&openapi3.Operation{
+		OperationID: "updateWebhook",
+		Summary:     "Replace a webhook",
+		Tags:        []string{"webhooks"},
+	}, b.schema.ref(dto.UpdateWebhookRequest{}, true)), idParam("id")),
+		http.StatusOK, ok(b.schema.ref(dto.WebhookItem{}, false)),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodDelete, "/api/v1/webhooks/{id}", withParams(&openapi3.Operation{
+		OperationID: "deleteWebhook",
+		Summary:     "Delete a webhook",
+		Tags:        []string{"webhooks"},
+	}, idParam("id")),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodGet, "/api/v1/webhooks/{id}/deliveries", withParams(&openapi3.Operation{
+		OperationID: "listWebhookDeliveries",
+		Summary:     "List the deliveries of a webhook, newest first",
+		Tags:        []string{"webhooks"},
+	}, append(pageParams(), idParam("id"))...),
+		http.StatusOK, ok(b.schema.list(dto.WebhookDeliveryItem{})),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodPost, "/api/v1/webhooks/{id}/deliveries/{delivery_id}/redeliver", withParams(&openapi3.Operation{
+		OperationID: "redeliverWebhookDelivery",
+		Summary:     "Send a delivery again",
+		Tags:        []string{"webhooks"},
+	}, idParam("id"), idParam("delivery_id")),
+		http.StatusAccepted, openapi3.NewResponse().WithDescription("The new delivery was queued.").
+			WithJSONSchemaRef(b.schema.ref(dto.WebhookDeliveryItem{}, false)),
+		http.StatusBadRequest, http.StatusNotFound)
+}
+
+func (b *builder) oauth() {
+	b.add(http.MethodGet, "/api/v1/oauth/clients", &openapi3.Operation{
+		OperationID: "listOAuthClients",
+		Summary:     "List the caller's OAuth clients",
+		Tags:        []string{"oauth"},
+	},
+		http.StatusOK, ok(b.schema.list(dto.OAuthClientItem{})))
+
+	b.add(http.MethodPost, "/api/v1/oauth/clients", jsonBody(&openapi3.Operation{
+		OperationID: "createOAuthClient",
+		Summary:     "Register an OAuth client",
+		Description: "Redirect URIs must use https, or http on localhost. The response is the only one " +
+			"that includes the secret of a confidential client.",
+		Tags: []string{"oauth"},
+	}, b.schema.ref(dto.CreateOAuthClientRequest{}, true)),
+		http.StatusCreated, created(b.schema.ref(dto.CreateOAuthClientResponse{}, false)),
+		http.StatusBadRequest)
+
+	b.add(http.MethodDelete, "/api/v1/oauth/clients/{client_id}", withParams(&openapi3.Operation{
+		OperationID: "deleteOAuthClient",
+		Summary:     "Delete an OAuth client and revoke its tokens",
+		Tags:        []string{"oauth"},
+	}, openapi3.NewPathParameter("client_id").WithSchema(openapi3.NewStringSchema())),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodGet, "/api/v1/oauth/consent", withParams(&openapi3.Operation{
+		OperationID: "describeOAuthConsent",
+		Summary:     "Describe a client's authorization request for the consent page",
+		Tags:        []string{"oauth"},
+	}, openapi3.NewQueryParameter("request").WithRequired(true).WithSchema(openapi3.NewStringSchema())),
+		http.StatusOK, ok(b.schema.ref(dto.OAuthConsentRequest{}, false)),
+		http.StatusBadRequest)
+
+	b.add(http.MethodPost, "/api/v1/oauth/consent", jsonBody(&openapi3.Operation{
+		OperationID: "consentOAuthRequest",
+		Summary:     "Approve or deny a client's authorization request",
+		Description: "The consent page sends the user to redirect_uri, which carries an authorization " +
+			"code or an access_denied error.",
+		Tags: []string{"oauth"},
+	}, b.schema.ref(dto.OAuthConsentDecision{}, true)),
+		http.StatusOK, ok(b.schema.ref(dto.OAuthRedirect{}, false)),
+		http.StatusBadRequest)
+}
+
+func (b *builder) admin() {
+	b.add(http.MethodGet, "/api/v1/admin/users", withParams(&openapi3.Operation{
+		OperationID: "adminListUsers",
+		Summary:     "List users, optionally those whose username or email contains query",
+		Description: "Requires the users:read permission.",
+		Tags:        []string{"admin"},
+	}, append(pageParams(), openapi3.NewQueryParameter("query").WithSchema(openapi3.NewStringSchema()))...),
+		http.StatusOK, ok(b.schema.list(dto.AdminUserItem{})),
+		http.StatusBadRequest, http.StatusForbidden)
+
+	b.add(http.MethodGet, "/api/v1/admin/users/{id}", withParams(&openapi3.Operation{
+		OperationID: "adminGetUser",
+		Summary:     "Get a user",
+		Description: "Requires the users:read permission.",
+		Tags:        []string{"admin"},
+	}, idParam("id")),
+		http.StatusOK, ok(b.schema.ref(dto.AdminUserItem{}, false)),
+		http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound)
+
+	b.add(http.MethodPost, "/api/v1/admin/users/{id}/disable", withParams(&openapi3.Operation{
+		OperationID: "adminDisableUser",
+		Summary:     "Disable a user",
+		Description: "Requires the users:disable permission. Disabled users can't log in, refresh their " +
+			"sessions or use any of their access tokens, including those of their logins.",
+		Tags: []string{"admin"},
+	}, idParam("id")),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodPost, "/api/v1/admin/users/{id}/enable", withParams(&openapi3.Operation{
+		OperationID: "adminEnableUser",
+		Summary:     "Enable a disabled user",
+		Description: "Requires the users:disable permission.",
+		Tags:        []string{"admin"},
+	}, idParam("id")),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodDelete, "/api/v1/admin/users/{id}", withParams(&openapi3.Operation{
+		OperationID: "adminDeleteUser",
+		Summary:     "Delete a user and all their data",
+		Description: "Requires the users:delete permission.",
+		Tags:        []string{"admin"},
+	}, idParam("id")),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodPut, "/api/v1/admin/users/{id}/role", withParams(jsonBody(&openapi3.Operation{
+		OperationID: "adminSetUserRole",
+		Summary:     "Change a user's role",
+		Description: "Requires the users:roles permission. The user's permissions change with their " +
+			"next request.",
+		Tags: []string{"admin"},
+	}, b.schema.ref(dto.SetUserRoleRequest{}, true)), idParam("id")),
+		http.StatusNoContent, noContent(),
+		http.StatusBadRequest, http.StatusNotFound)
+
+	b.add(http.MethodGet, "/api/v1/admin/stats", &openapi3.Operation{
+		OperationID: "adminGetStats",
+		Summary:     "Get counts of users and todos",
+		Description: "Requires the stats:read permission.",
+		Tags:        []string{"admin"},
+	},
+		http.StatusOK, ok(b.schema.ref(dto.SystemStats{}, false)),
+		http.StatusForbidden)
+
+	b.add(http.MethodGet, "/api/v1/admin/audit-log", withParams(&openapi3.Operation{
+		OperationID: "adminListAuditLog",
+		Summary:     "List the actions of admins, newest first",
+		Description: "Requires the audit:read permission.",
+		Tags:        []string{"admin"},
+	}, pageParams()...),
+		http.StatusOK, ok(b.schema.list(dto.AuditEntryItem{})),
+		http.StatusBadRequest, http.StatusForbidden)
+}
+
+// add registers op with its success response and error responses. Every operation
+// can fail with 500 and, over the rate limits, 429, and authenticated ones with 401,
+// or 403 for read-only tokens if they aren't GETs.
+func (b *builder) add(method, path string, op *openapi3.Operation, status int, success *openapi3.Response, errorCodes ...int) {
+	op.Responses = openapi3.NewResponses()
+	op.Responses.Delete("default")
+	op.Responses.Set(strconv.Itoa(status), &openapi3.ResponseRef{Value: success})
+
+	if op.Security == nil {
+		errorCodes = append(errorCodes, http.StatusUnauthorized)
+		if method != http.MethodGet {
+			errorCodes = append(errorCodes, http.StatusForbidden)
+		}
+	}
+	errorCodes = append(errorCodes, http.StatusTooManyRequests, http.StatusInternalServerError)
+	for _, code := range errorCodes {
+		key := strconv.Itoa(code)
+		op.Responses.Set(key, &openapi3.ResponseRef{
+			Ref:   "#/components/responses/" + key,
+			Value: b.doc.Components.Responses[key].Value,
+		})
+	}
+
+	item := b.doc.Paths.Value(path)
+	if item == nil {
+		item = &openapi3.PathItem{}
+		b.doc.Paths.Set(path, item)
+	}
+	item.SetOperation(method, op)
+}
+
+func ok(schema *openapi3.SchemaRef) *openapi3.Response {
+	return openapi3.NewResponse().WithDescription("OK").WithJSONSchemaRef(schema)
+}
+
+func created(schema *openapi3.SchemaRef) *openapi3.Response {
+	return openapi3.NewResponse().WithDescription("Created").WithJSONSchemaRef(schema)
+}
+
+func accepted() *openapi3.Response {
+	return openapi3.NewResponse().WithDescription("Accepted")
+}
+
+func noContent() *openapi3.Response {
+	return openapi3.NewResponse().WithDescription("No Content")
+}
+
+func public(op *openapi3.Operation) *openapi3.Operation {
+	op.Security = &openapi3.SecurityRequirements{}
+	return op
+}
+
+func jsonBody(op *openapi3.Operation, schema *openapi3.SchemaRef) *openapi3.Operation {
+	op.RequestBody = &openapi3.RequestBodyRef{
+		Value: openapi3.NewRequestBody().WithRequired(true).WithJSONSchemaRef(schema),
+	}
+	return op
+}
+
+func withParams(op *openapi3.Operation, params ...*openapi3.Parameter) *openapi3.Operation {
+	for _, p := range params {
+		op.AddParameter(p)
+	}
+	return op
+}
+
+func idParam(name string) *openapi3.Parameter {
+	return openapi3.NewPathParameter(name).
+		WithSchema(openapi3.NewInt64Schema().WithMin(1))
+}
+
+func providerParam() *openapi3.Parameter {
+	return openapi3.NewPathParameter("provider").WithSchema(openapi3.NewStringSchema())
+}
+
+func pageParams() []*openapi3.Parameter {
+	return []*openapi3.Parameter{
+		openapi3.NewQueryParameter("limit").WithSchema(openapi3.NewInt32Schema().WithMin(0)),
+		openapi3.NewQueryParameter("offset").WithSchema(openapi3.NewInt32Schema().WithMin(0)),
+	}
+}
@@ -0,0 +1,409 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/stream"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"go.uber.org/zap"
+)
+
+type client struct {
+	srv    *Server
+	id     uint64
+	userID models.UserID
+	email  string
+	// readOnly clients can subscribe but not change todos.
+	readOnly bool
+	// canWrite is false for personal access tokens without todos:write.
+	canWrite bool
+	conn     *websocket.Conn
+
+	// send is drained by writeLoop. Producers never block on it: a client that
+	// doesn't keep up is disconnected instead.
+	send      chan outboundMessage
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu sync.Mutex
+	// subs are the subscriptions of the lists the client joined.
+	subs map[ListID]*stream.Subscription
+}
+
+func newClient(srv *Server, id uint64, caller models.Caller, conn *websocket.Conn) *client {
+	return &client{
+		srv:      srv,
+		id:       id,
+		userID:   caller.UserID,
+		email:    caller.Email,
+		readOnly: caller.ReadOnly,
+		canWrite: caller.Allows(models.ScopeTodosWrite),
+		conn:     conn,
+		send:     make(chan outboundMessage, sendBuffer),
+		done:     make(chan struct{}),
+		subs:     make(map[ListID]*stream.Subscription),
+	}
+}
+
+func (c *client) enqueue(msg outboundMessage) {
+	select {
+	case <-c.done:
+	case c.send <- msg:
+	default:
+		c.close(websocket.CloseTryAgainLater, "client too slow")
+	}
+}
+
+func (c *client) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+		_ = c.conn.Close()
+
+		c.mu.Lock()
+		subs := c.subs
+		c.subs = nil
+		c.mu.Unlock()
+
+		for list, sub := range subs {
+			sub.Close()
+			c.srv.leave(c, list)
+		}
+		c.srv.remove(c)
+	})
+}
+
+func (c *client) writeLoop() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		}
+	}
+}
+
+// watchAuth closes the connection when its token expires, and authenticates the token
+// again every authCheckInterval, so that logging out, resetting the password, and
+// disabling or deleting the user also end it. Both run on timers: a connection that
+// only receives events sends nothing that could trigger a check.
+func (c *client) watchAuth(ctx context.Context, token string, expiresAt time.Time) {
+	var expired <-chan time.Time
+	if !expiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(expiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	ticker := time.NewTicker(authCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-expired:
+			c.close(websocket.ClosePolicyViolation, "token expired")
+			return
+		case <-ticker.C:
+			_, err := c.srv.tokens.Authenticate(ctx, token)
+			switch {
+			case err == nil:
+			case errors.Is(err, e.ErrInvalidAccessToken), errors.Is(err, e.ErrUserDisabled), errors.Is(err, e.ErrEmailNotVerified):
+				c.close(websocket.ClosePolicyViolation, "unauthorized")
+				return
+			default:
+				// The check is retried on the next tick rather than dropping clients
+				// while the database is unavailable.
+				c.srv.logger.Warn("websocket token check failed", zap.Uint64("connection_id", c.id), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (c *client) readLoop(ctx context.Context) {
+	defer c.close(websocket.CloseNormalClosure, "")
+
+	c.conn.SetReadLimit(maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		var msg inboundMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) && !errors.Is(err, websocket.ErrCloseSent) {
+				c.srv.logger.Debug("websocket read failed", zap.Uint64("connection_id", c.id), zap.Error(err))
+			}
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+
+		c.handle(ctx, msg)
+	}
+}
+
+func (c *client) handle(ctx context.Context, msg inboundMessage) {
+	var (
+		data any
+		err  error
+	)
+
+	switch msg.Type {
+	case msgSubscribe:
+		err = c.subscribe(ctx, msg.ListID)
+	case msgUnsubscribe:
+		c.unsubscribe(msg.ListID)
+	case msgListTodos:
+		data, err = c.listTodos(ctx, msg)
+	case msgCreateTodo, msgUpdateTodo, msgDeleteTodo:
+		data, err = c.write(ctx, msg)
+	default:
+		err = errUnknownMessage
+	}
+
+	if err != nil {
+		c.enqueue(outboundMessage{Type: msgError, RequestID: msg.RequestID, Error: clientError(err)})
+		return
+	}
+
+	c.enqueue(outboundMessage{Type: msgAck, RequestID: msg.RequestID, Data: data})
+}
+
+func (c *client) write(ctx context.Context, msg inboundMessage) (any, error) {
+	if c.readOnly {
+		return nil, e.ErrEmailNotVerified
+	}
+	if !c.canWrite {
+		return nil, e.ErrInsufficientScope
+	}
+
+	switch msg.Type {
+	case msgCreateTodo:
+		return c.createTodo(ctx, msg)
+	case msgUpdateTodo:
+		return nil, c.updateTodo(ctx, msg)
+	default:
+		return nil, c.deleteTodo(ctx, msg)
+	}
+}
+
+var (
+	errUnknownMessage = errors.New("unknown message type")
+	errForbidden      = errors.New("forbidden")
+)
+
+func clientError(err error) string {
+	switch {
+	case errors.Is(err, errUnknownMessage), errors.Is(err, errForbidden), errors.Is(err, e.ErrTodoNotFound):
+		return err.Error()
+	case errors.Is(err, e.ErrTodoTitleRequired), errors.Is(err, e.ErrTodoTitleTooLong), errors.Is(err, e.ErrEmailNotVerified),
+		errors.Is(err, e.ErrInsufficientScope):
+		return err.Error()
+	default:
+		return "internal error"
+	}
+}
+
+// checkAccess allows the caller's own list and the lists shared with them.
+func (c *client) checkAccess(ctx context.Context, list ListID) error {
+	ok, err := c.srv.lists.CanAccess(ctx, c.userID, list)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errForbidden
+	}
+	return nil
+}
+
+func (c *client) subscribe(ctx context.Context, list ListID) error {
+	if list == 0 {
+		list = c.userID
+	}
+	if err := c.checkAccess(ctx, list); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.subs == nil {
+		c.mu.Unlock()
+		return nil
+	}
+	if _, ok := c.subs[list]; ok {
+		c.mu.Unlock()
+		return nil
+	}
+	sub, _, _, ok := c.srv.hub.Subscribe(list, 0)
+	if !ok {
+		c.mu.Unlock()
+		return errors.New("server shutting down")
+	}
+	c.subs[list] = sub
+	go c.forward(ctx, list, sub)
+	c.mu.Unlock()
+
+	c.srv.join(c, list)
+	return nil
+}
+
+func (c *client) unsubscribe(list ListID) {
+	if list == 0 {
+		list = c.userID
+	}
+
+	c.mu.Lock()
+	sub, ok := c.subs[list]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.subs, list)
+	c.mu.Unlock()
+
+	sub.Close()
+	c.srv.leave(c, list)
+}
+
+// forward relays hub events of list to the client. The hub closes the subscription
+// when the client falls behind or the server shuts down; both end the connection so
+// that the client reconnects and reloads.
+//
+// Access to a shared list is checked again every accessCheckInterval, so that a member
+// the list is no longer shared with stops receiving its events.
+func (c *client) forward(ctx context.Context, list ListID, sub *stream.Subscription) {
+	checked := time.Now()
+	revoked := false
+	for event := range sub.Events {
+		if revoked {
+			continue
+		}
+		if list != c.userID && time.Since(checked) >= accessCheckInterval {
+			if err := c.checkAccess(ctx, list); errors.Is(err, errForbidden) {
+				revoked = true
+				c.unsubscribe(list)
+				c.enqueue(outboundMessage{Type: msgError, ListID: list, Error: errForbidden.Error()})
+				continue
+			}
+			checked = time.Now()
+		}
+		c.enqueue(eventMessage(event))
+	}
+
+	c.mu.Lock()
+	dropped := c.subs[list] == sub
+	c.mu.Unlock()
+
+	if dropped {
+		c.close(websocket.CloseTryAgainLater, "event stream interrupted")
+	}
+}
+
+// listTodo reports todos of lists the caller can't access as not found, so that
+// callers can't probe for their IDs.
+func (c *client) listTodo(ctx context.Context, id models.ToDoID) (models.ToDo, error) {
+	todo, err := c.srv.todoUC.GetTodoByID(ctx, id)
+	if err != nil {
+		return models.ToDo{}, err
+	}
+	if err := c.checkAccess(ctx, todo.UserID); err != nil {
+		if errors.Is(err, errForbidden) {
+			return models.ToDo{}, e.ErrTodoNotFound
+		}
+		return models.ToDo{}, err
+	}
+	return todo, nil
+}
+
+func (c *client) listTodos(ctx context.Context, msg inboundMessage) ([]dto.TodoItem, error) {
+	list := msg.ListID
+	if list == 0 {
+		list = c.userID
+	}
+	if err := c.checkAccess(ctx, list); err != nil {
+		return nil, err
+	}
+
+	todos, err := c.srv.todoUC.ListTodos(ctx, dto.GetListTodosRequest{UserID: list, Limit: msg.Limit, Offset: msg.Offset})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.TodoItem, len(todos))
+	for i, todo := range todos {
+		items[i] = dto.TodoItem{
+			ID:          todo.ID,
+			UserID:      todo.UserID,
+			Title:       todo.Title,
+			Description: todo.Description,
+			Completed:   todo.Completed,
+			CreatedAt:   todo.CreatedAt,
+			UpdatedAt:   todo.UpdatedAt,
+		}
+	}
+	return items, nil
+}
+
+// createTodo adds a todo to the caller's own list or to a list shared with them.
+func (c *client) createTodo(ctx context.Context, msg inboundMessage) (dto.CreateTodoResponse, error) {
+	list := msg.ListID
+	if list == 0 {
+		list = c.userID
+	}
+	if err := c.checkAccess(ctx, list); err != nil {
+		return dto.CreateTodoResponse{}, err
+	}
+
+	id, err := c.srv.todoUC.CreateTodo(ctx, dto.CreateTodoRequest{
+		UserID:      list,
+		Title:       msg.Title,
+		Description: msg.Description,
+	})
+	if err != nil {
+		return dto.CreateTodoResponse{}, err
+	}
+
+	return dto.CreateTodoResponse{ID: id}, nil
+}
+
+func (c *client) updateTodo(ctx context.Context, msg inboundMessage) error {
+	if _, err := c.listTodo(ctx, msg.ID); err != nil {
+		return err
+	}
+
+	return c.srv.todoUC.UpdateTodo(ctx, models.ToDo{
+		ID:          msg.ID,
+		Title:       msg.Title,
+		Description: msg.Description,
+		Completed:   msg.Completed,
+	})
+}
+
+func (c *client) deleteTodo(ctx context.Context, msg inboundMessage) error {
+	if _, err := c.listTodo(ctx, msg.ID); err != nil {
+		return err
+	}
+
+	return c.srv.todoUC.DeleteTodoByID(ctx, msg.ID)
+}
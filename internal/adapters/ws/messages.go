@@ -0,0 +1,64 @@
+package ws
+
+import (
+	"encoding/json"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+const (
+	msgSubscribe   = "subscribe"
+	msgUnsubscribe = "unsubscribe"
+	msgListTodos   = "list_todos"
+	msgCreateTodo  = "create_todo"
+	msgUpdateTodo  = "update_todo"
+	msgDeleteTodo  = "delete_todo"
+
+	msgAck      = "ack"
+	msgError    = "error"
+	msgEvent    = "event"
+	msgPresence = "presence"
+)
+
+// ListID identifies a todo list. Every user owns exactly one list, identified by the
+// owner's user ID, and can share it with other users.
+type ListID = models.UserID
+
+type inboundMessage struct {
+	Type        string        `json:"type"`
+	RequestID   string        `json:"request_id,omitempty"`
+	ListID      ListID        `json:"list_id,omitempty"`
+	ID          models.ToDoID `json:"id,omitempty"`
+	Title       string        `json:"title,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Completed   bool          `json:"completed,omitempty"`
+	Limit       int           `json:"limit,omitempty"`
+	Offset      int           `json:"offset,omitempty"`
+}
+
+type outboundMessage struct {
+	Type      string           `json:"type"`
+	RequestID string           `json:"request_id,omitempty"`
+	ListID    ListID           `json:"list_id,omitempty"`
+	Event     models.EventType `json:"event,omitempty"`
+	Sequence  int64            `json:"sequence,omitempty"`
+	Data      any              `json:"data,omitempty"`
+	Viewers   []Viewer         `json:"viewers,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+type Viewer struct {
+	ConnectionID uint64        `json:"connection_id"`
+	UserID       models.UserID `json:"user_id"`
+	Email        string        `json:"email"`
+}
+
+func eventMessage(event models.Event) outboundMessage {
+	return outboundMessage{
+		Type:     msgEvent,
+		ListID:   event.UserID,
+		Event:    event.Type,
+		Sequence: event.Sequence,
+		Data:     json.RawMessage(event.Payload),
+	}
+}
@@ -0,0 +1,209 @@
+package ws
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/stream"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"go.uber.org/zap"
+)
+
+const (
+	writeTimeout   = 10 * time.Second
+	pongTimeout    = 60 * time.Second
+	pingInterval   = pongTimeout * 9 / 10
+	maxMessageSize = 64 * 1024
+	sendBuffer     = 64
+	// accessCheckInterval is how often access to a shared list is checked again while
+	// a member is subscribed to it.
+	accessCheckInterval = time.Minute
+	// authCheckInterval is how often the token of a connection is checked again.
+	authCheckInterval = time.Minute
+
+	// Subprotocol is the WebSocket subprotocol of the API. Clients request it next to
+	// their access token, which they pass as a tokenProtocolPrefix subprotocol.
+	Subprotocol         = "todos.v1"
+	tokenProtocolPrefix = "bearer."
+)
+
+// Server is the WebSocket API for live todo lists. Clients subscribe to their own list
+// and the lists shared with them, receive their changes and presence updates, and can
+// send mutations that go through TodoUsecase like REST requests do.
+type Server struct {
+	todoUC   *usecase.TodoUsecase
+	lists    *usecase.ListUsecase
+	hub      *stream.Hub
+	tokens   *usecase.AccessTokenUsecase
+	logger   *zap.Logger
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	nextID  uint64
+	clients map[*client]struct{}
+	viewers map[ListID]map[*client]struct{}
+	closed  bool
+}
+
+func NewServer(todoUC *usecase.TodoUsecase, lists *usecase.ListUsecase, hub *stream.Hub, tokens *usecase.AccessTokenUsecase, logger *zap.Logger) *Server {
+	return &Server{
+		todoUC: todoUC,
+		lists:  lists,
+		hub:    hub,
+		tokens: tokens,
+		logger: logger,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			Subprotocols:    []string{Subprotocol},
+		},
+		clients: make(map[*client]struct{}),
+		viewers: make(map[ListID]map[*client]struct{}),
+	}
+}
+
+// bearerToken reads the token from the Authorization header or, since browsers can't
+// set headers on WebSocket requests, from a "bearer.<token>" subprotocol, e.g.
+// new WebSocket(url, ["todos.v1", "bearer." + token]). Tokens are never read from the
+// URL, which ends up in access logs.
+func bearerToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		parts := strings.Split(h, " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			return parts[1]
+		}
+		return ""
+	}
+	for _, protocol := range websocket.Subprotocols(r) {
+		if token, ok := strings.CutPrefix(protocol, tokenProtocolPrefix); ok {
+			return token
+		}
+	}
+	return ""
+}
+
+func (s *Server) Handle(c *gin.Context) {
+	token := bearerToken(c.Request)
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
+		return
+	}
+
+	caller, err := s.tokens.Authenticate(c.Request.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, e.ErrInvalidAccessToken):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		case errors.Is(err, e.ErrEmailNotVerified):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "email not verified"})
+		case errors.Is(err, e.ErrUserDisabled):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+		return
+	}
+	// Personal access tokens need todos:read to connect, and todos:write to change todos.
+	if !caller.Allows(models.ScopeTodosRead) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": e.ErrInsufficientScope.Error()})
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an error response.
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			time.Now().Add(writeTimeout))
+		_ = conn.Close()
+		return
+	}
+	s.nextID++
+	cl := newClient(s, s.nextID, caller, conn)
+	s.clients[cl] = struct{}{}
+	s.mu.Unlock()
+
+	go cl.writeLoop()
+	go cl.watchAuth(c.Request.Context(), token, caller.ExpiresAt)
+	cl.readLoop(c.Request.Context())
+}
+
+// Close disconnects every client. Hijacked WebSocket connections are not tracked by
+// http.Server.Shutdown, so this must be called during application shutdown.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	clients := make([]*client, 0, len(s.clients))
+	for cl := range s.clients {
+		clients = append(clients, cl)
+	}
+	s.mu.Unlock()
+
+	for _, cl := range clients {
+		cl.close(websocket.CloseGoingAway, "server shutting down")
+	}
+}
+
+func (s *Server) join(cl *client, list ListID) {
+	s.mu.Lock()
+	if s.viewers[list] == nil {
+		s.viewers[list] = make(map[*client]struct{})
+	}
+	s.viewers[list][cl] = struct{}{}
+	s.mu.Unlock()
+
+	s.broadcastPresence(list)
+}
+
+func (s *Server) leave(cl *client, list ListID) {
+	s.mu.Lock()
+	viewers, ok := s.viewers[list]
+	if ok {
+		delete(viewers, cl)
+		if len(viewers) == 0 {
+			delete(s.viewers, list)
+		}
+	}
+	s.mu.Unlock()
+
+	if ok {
+		s.broadcastPresence(list)
+	}
+}
+
+func (s *Server) remove(cl *client) {
+	s.mu.Lock()
+	delete(s.clients, cl)
+	s.mu.Unlock()
+}
+
+func (s *Server) broadcastPresence(list ListID) {
+	s.mu.Lock()
+	recipients := make([]*client, 0, len(s.viewers[list]))
+	viewers := make([]Viewer, 0, len(s.viewers[list]))
+	for cl := range s.viewers[list] {
+		recipients = append(recipients, cl)
+		viewers = append(viewers, Viewer{ConnectionID: cl.id, UserID: cl.userID, Email: cl.email})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(viewers, func(i, j int) bool { return viewers[i].ConnectionID < viewers[j].ConnectionID })
+	msg := outboundMessage{Type: msgPresence, ListID: list, Viewers: viewers}
+	for _, cl := range recipients {
+		cl.enqueue(msg)
+	}
+}
@@ -0,0 +1,134 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/stream"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"go.uber.org/zap"
+)
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[models.UserID]models.User
+}
+
+func (r *fakeUserRepo) GetUserByID(_ context.Context, id models.UserID) (models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, e.ErrUserNotFound
+	}
+	return user, nil
+}
+
+type fakeSessionRepo struct {
+	repository.RefreshTokenRepository
+	revoked map[string]bool
+}
+
+func (r *fakeSessionRepo) RefreshTokenFamilyRevoked(_ context.Context, familyID string) (bool, error) {
+	return r.revoked[familyID], nil
+}
+
+type wsFixture struct {
+	url      string
+	jwt      *auth.JWTService
+	user     models.User
+	sessions *fakeSessionRepo
+}
+
+func newWSFixture(t *testing.T, ttl time.Duration) wsFixture {
+	t.Helper()
+
+	verified := time.Now()
+	user := models.User{ID: 1, Email: "alice@example.com", EmailVerifiedAt: &verified, Role: models.RoleUser}
+	users := &fakeUserRepo{users: map[models.UserID]models.User{user.ID: user}}
+	sessions := &fakeSessionRepo{revoked: make(map[string]bool)}
+	jwt := auth.NewJWTService(auth.NewHMACKeySet("test-secret"), "test", "test", ttl)
+	tokens := usecase.NewAccessTokenUsecase(nil, users, sessions, nil, jwt, usecase.VerificationReadOnly)
+
+	hub := stream.NewHub(stream.DefaultReplaySize)
+	srv := NewServer(nil, nil, hub, tokens, zap.NewNop())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws", srv.Handle)
+	httpSrv := httptest.NewServer(router)
+	t.Cleanup(func() {
+		srv.Close()
+		hub.Close()
+		httpSrv.Close()
+	})
+
+	return wsFixture{
+		url:      "ws" + strings.TrimPrefix(httpSrv.URL, "http") + "/ws",
+		jwt:      jwt,
+		user:     user,
+		sessions: sessions,
+	}
+}
+
+func (f wsFixture) dial(t *testing.T, sessionID string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
+	token, err := f.jwt.GenerateToken(f.user, false, sessionID)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	conn, res, err := websocket.DefaultDialer.Dial(f.url, http.Header{"Authorization": {"Bearer " + token}})
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, res, err
+}
+
+func TestConnectionClosesWhenTokenExpires(t *testing.T) {
+	f := newWSFixture(t, 2*time.Second)
+
+	conn, _, err := f.dial(t, "family")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	// The client only listens, so nothing but the expiry timer can end the connection.
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation {
+			t.Fatalf("read err = %v, want close %d", err, websocket.ClosePolicyViolation)
+		}
+		return
+	}
+}
+
+func TestConnectRejectsLoggedOutSession(t *testing.T) {
+	f := newWSFixture(t, time.Hour)
+	f.sessions.revoked["family"] = true
+
+	_, res, err := f.dial(t, "family")
+	if err == nil {
+		t.Fatal("dial succeeded with the token of a logged out session")
+	}
+	if res == nil || res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("dial response = %v, want 401", res)
+	}
+
+	if _, _, err := f.dial(t, "other"); err != nil {
+		t.Fatalf("dial with another session: %v", err)
+	}
+}
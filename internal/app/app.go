@@ -7,32 +7,84 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	adminv1 "github.com/mrxacker/go-to-do-app/api/admin/v1"
+	todov1 "github.com/mrxacker/go-to-do-app/api/todo/v1"
+	userv1 "github.com/mrxacker/go-to-do-app/api/user/v1"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/clientip"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/graphql"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/grpc/gateway"
+	internal_grpc "github.com/mrxacker/go-to-do-app/internal/adapters/grpc/handlers"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/grpc/interceptors"
 	internal_http "github.com/mrxacker/go-to-do-app/internal/adapters/http/handlers"
 	"github.com/mrxacker/go-to-do-app/internal/adapters/http/middleware"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/http/openapi"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/ws"
 	"github.com/mrxacker/go-to-do-app/internal/config"
 	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/events"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/mail"
 	"github.com/mrxacker/go-to-do-app/internal/infrastructure/postgres"
+	infra_ratelimit "github.com/mrxacker/go-to-do-app/internal/infrastructure/ratelimit"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/stream"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/webhook"
 	"github.com/mrxacker/go-to-do-app/internal/logger"
+	"github.com/mrxacker/go-to-do-app/internal/ports/mailer"
+	"github.com/mrxacker/go-to-do-app/internal/ports/ratelimit"
 	"github.com/mrxacker/go-to-do-app/internal/usecase"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	channelz "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
-const shutdownTimeout = 5 * time.Second
+const (
+	shutdownTimeout         = 5 * time.Second
+	webhookDispatchInterval = 5 * time.Second
+	outboxRelayInterval     = time.Second
+	outboxPurgeInterval     = 10 * time.Minute
+	webhookSendTimeout      = 10 * time.Second
+	healthCheckInterval     = 5 * time.Second
+	healthCheckTimeout      = 2 * time.Second
+	rateLimitSweepInterval  = time.Minute
+)
+
+// healthServices are reported by the gRPC health service next to the overall status.
+var healthServices = []string{
+	todov1.TodoService_ServiceDesc.ServiceName,
+	userv1.UserService_ServiceDesc.ServiceName,
+}
 
 type App struct {
 	cfg    *config.Config
 	logger *zap.Logger
 
-	todoUC *usecase.TodoUsecase
-
-	httpServer *http.Server
-	grpcServer *grpc.Server
-	db         *sql.DB
+	todoUC              *usecase.TodoUsecase
+	webhookUC           *usecase.WebhookUsecase
+	passwordResetUC     *usecase.PasswordResetUsecase
+	emailVerificationUC *usecase.EmailVerificationUsecase
+	loginThrottle       *usecase.LoginThrottle
+	rateLimiter         *usecase.RateLimiter
+	outboxRelay         *usecase.OutboxRelay
+	hub                 *stream.Hub
+	eventListener       *events.PGListener
+	wsServer            *ws.Server
+	graphqlServer       *graphql.Server
+
+	httpServer  *http.Server
+	grpcServer  *grpc.Server
+	healthSrv   *health.Server
+	gatewayConn *grpc.ClientConn
+	db          *sql.DB
 
 	wg sync.WaitGroup
 }
@@ -51,42 +103,169 @@ func NewApp() (*App, error) {
 	}
 
 	// Initialize database connection
-	db, err := postgres.NewPostgresDB(fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName,
-	))
+	)
+	db, err := postgres.NewPostgresDB(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// Initialize JWT service
-	jwtService := auth.NewJWTService(cfg.JWTSecret, time.Hour*24)
+	keys := auth.NewHMACKeySet(cfg.JWTSecret)
+	if cfg.JWTSigningKeyFile != "" {
+		keys, err = auth.LoadKeySet(cfg.JWTSigningKeyFile, cfg.JWTVerificationKeyFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT keys: %w", err)
+		}
+	}
+	jwtService := auth.NewJWTService(keys, cfg.JWTIssuer, cfg.JWTAudience, cfg.AccessTokenTTL)
 
 	// Initialize repositories and use cases
+	webhookRepo := postgres.NewWebhookRepo(db)
+	webhookUC := usecase.NewWebhookUsecase(webhookRepo, webhook.NewClient(webhookSendTimeout), l.Logger)
 	todoRepo := postgres.NewTodoRepo(db)
 	todoUC := usecase.NewTodoUsecase(todoRepo)
 	userRepo := postgres.NewUserRepo(db)
-	userUC := usecase.NewUserUseCase(userRepo, jwtService)
-
-	// Initialize HTTP handlers
-	httpRouter := initHandlers(todoUC, userUC, jwtService)
+	listUC := usecase.NewListUsecase(postgres.NewListMemberRepo(db), userRepo)
+	refreshTokenRepo := postgres.NewRefreshTokenRepo(db)
+	linkSigner := auth.NewTokenSigner(cfg.LinkSigningSecret)
+	var mfaCipher *auth.Cipher
+	if cfg.MFAEncryptionKey != nil {
+		mfaCipher, err = auth.NewCipher(cfg.MFAEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create MFA cipher: %w", err)
+		}
+	}
+	webAuthn, err := initWebAuthn(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure WebAuthn: %w", err)
+	}
+	passkeyUC := usecase.NewPasskeyUsecase(postgres.NewPasskeyRepo(db), userRepo, webAuthn)
+	oidcUC := usecase.NewOIDCUsecase(initOIDCProviders(cfg), postgres.NewIdentityRepo(db), userRepo)
+	var m mailer.Mailer = mail.NewLogMailer(l.Logger)
+	if cfg.SMTPAddr != "" {
+		m, err = mail.NewSMTPMailer(cfg.SMTPAddr, cfg.MailFrom, cfg.SMTPUsername, cfg.SMTPPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create mailer: %w", err)
+		}
+	}
+	loginThrottle := usecase.NewLoginThrottle(postgres.NewLoginAttemptRepo(db), userRepo, linkSigner, m,
+		cfg.AccountUnlockURL, cfg.LoginMaxFailures, cfg.LoginIPMaxFailures, cfg.LoginLockout, l.Logger)
+	mfaUC := usecase.NewMFAUsecase(postgres.NewMFARepo(db), userRepo, mfaCipher, linkSigner, loginThrottle, cfg.MFAIssuer)
+	userUC := usecase.NewUserUseCase(userRepo, refreshTokenRepo, mfaUC, passkeyUC, oidcUC, loginThrottle, jwtService, cfg.RefreshTokenTTL,
+		usecase.VerificationPolicy(cfg.EmailVerificationPolicy))
+	oauthUC := usecase.NewOAuthUsecase(postgres.NewOAuthRepo(db), linkSigner, cfg.OAuthConsentURL,
+		cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	tokenUC := usecase.NewAccessTokenUsecase(postgres.NewAccessTokenRepo(db), userRepo, refreshTokenRepo, oauthUC, jwtService,
+		usecase.VerificationPolicy(cfg.EmailVerificationPolicy))
+	adminUC := usecase.NewAdminUsecase(userRepo, postgres.NewAdminRepo(db))
+	passwordResetUC := usecase.NewPasswordResetUsecase(userRepo, postgres.NewPasswordResetRepo(db), m,
+		cfg.PasswordResetURL, cfg.PasswordResetTTL, l.Logger)
+	emailVerificationUC := usecase.NewEmailVerificationUsecase(userRepo, linkSigner, m,
+		cfg.EmailVerificationURL, cfg.EmailVerificationTTL, cfg.EmailVerificationResendInterval, l.Logger)
+
+	// Initialize event publishing
+	// With EventsPGNotify, live streams are fed through LISTEN/NOTIFY rather than by the
+	// local relay, so that subscribers see changes relayed by any instance.
+	hub := stream.NewHub(stream.DefaultReplaySize)
+	outboxRepo := postgres.NewOutboxRepo(db)
+	publisher := events.NewInProcessPublisher()
+	publisher.Subscribe(webhookUC)
+	publisher.Subscribe(emailVerificationUC)
+	var eventListener *events.PGListener
+	if cfg.EventsPGNotify {
+		publisher.Subscribe(events.NewPGNotifyPublisher(db, cfg.EventsChannel))
+		eventListener = events.NewPGListener(dsn, cfg.EventsChannel, outboxRepo, hub, l.Logger)
+	} else {
+		publisher.Subscribe(hub)
+	}
+	outboxRelay := usecase.NewOutboxRelay(outboxRepo, publisher, cfg.OutboxRetention)
 
 	// Initialize servers
-	grpcSrv := grpc.NewServer()
+	healthSrv := health.NewServer()
+	clientIPs, err := clientip.NewResolver(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure trusted proxies: %w", err)
+	}
+	var rateLimitStore ratelimit.Store = infra_ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "postgres" {
+		rateLimitStore = infra_ratelimit.NewPostgresStore(db)
+	}
+	rateLimiter := usecase.NewRateLimiter(rateLimitStore, initRateLimits(cfg), l.Logger)
+	grpcSrv := initGRPCServer(cfg, todoUC, listUC, userUC, passwordResetUC, emailVerificationUC, loginThrottle, mfaUC, passkeyUC, oidcUC, tokenUC, adminUC, hub, clientIPs, rateLimiter, healthSrv, l.Logger)
+
+	// Initialize HTTP handlers. The REST API for todos and users is transcoded to gRPC
+	// and served through the gRPC server's own address.
+	gatewayAddr := "localhost:" + cfg.GRPCAddr
+	if cfg.ListenMode == config.ListenModeSingle {
+		gatewayAddr = "localhost:" + cfg.HTTPAddr
+	}
+	gatewayConn, err := grpc.NewClient(gatewayAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gateway connection: %w", err)
+	}
+	gatewayHandler, err := gateway.NewHandler(context.Background(), gatewayConn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gateway: %w", err)
+	}
+	wsServer := ws.NewServer(todoUC, listUC, hub, tokenUC, l.Logger)
+	graphqlServer, err := graphql.NewServer(todoUC, userUC, hub, tokenUC, l.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GraphQL server: %w", err)
+	}
+	spec, err := openapi.NewSpec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI spec: %w", err)
+	}
+	openapiHandler, err := internal_http.NewOpenAPIHandler(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OpenAPI handler: %w", err)
+	}
+	var validator gin.HandlerFunc
+	if cfg.OpenAPIValidation {
+		validator, err = middleware.OpenAPIValidator(spec, cfg.ENV == "dev", l.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OpenAPI validator: %w", err)
+		}
+	}
+	httpRouter := initHandlers(gatewayHandler, webhookUC, oauthUC, hub, wsServer, graphqlServer, openapiHandler, validator, jwtService, tokenUC, rateLimiter, clientIPs)
+
 	httpSrv := &http.Server{Addr: ":" + cfg.HTTPAddr, Handler: httpRouter}
+	if cfg.ListenMode == config.ListenModeSingle {
+		httpSrv.Handler = newMuxHandler(grpcSrv, httpRouter, cfg.GRPCWebAllowedOrigins)
+		httpSrv.Protocols = new(http.Protocols)
+		httpSrv.Protocols.SetHTTP1(true)
+		httpSrv.Protocols.SetHTTP2(true)
+		httpSrv.Protocols.SetUnencryptedHTTP2(true)
+	}
 
 	// Return the application instance
 	return &App{
-		cfg:        cfg,
-		todoUC:     todoUC,
-		httpServer: httpSrv,
-		grpcServer: grpcSrv,
-		logger:     l.Logger,
-		db:         db,
+		cfg:                 cfg,
+		todoUC:              todoUC,
+		webhookUC:           webhookUC,
+		passwordResetUC:     passwordResetUC,
+		emailVerificationUC: emailVerificationUC,
+		loginThrottle:       loginThrottle,
+		rateLimiter:         rateLimiter,
+		outboxRelay:         outboxRelay,
+		hub:                 hub,
+		eventListener:       eventListener,
+		wsServer:            wsServer,
+		graphqlServer:       graphqlServer,
+		httpServer:          httpSrv,
+		grpcServer:          grpcSrv,
+		healthSrv:           healthSrv,
+		gatewayConn:         gatewayConn,
+		logger:              l.Logger,
+		db:                  db,
 	}, nil
 }
 
 func (a *App) Start(ctx context.Context) error {
 	a.logger.Info("Starting application",
+		zap.String("listen_mode", a.cfg.ListenMode),
 		zap.String("http_addr", a.cfg.HTTPAddr),
 		zap.String("grpc_addr", a.cfg.GRPCAddr),
 	)
@@ -99,15 +278,58 @@ func (a *App) Start(ctx context.Context) error {
 	a.wg.Add(1)
 	go func() {
 		defer a.wg.Done()
-		errCh <- a.runHTTP(ctx)
+		a.runOutboxRelay(ctx)
+	}()
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.runOutboxPurge(ctx)
+	}()
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.runWebhookDispatcher(ctx)
 	}()
 
 	a.wg.Add(1)
 	go func() {
 		defer a.wg.Done()
-		errCh <- a.runGRPC(ctx)
+		a.runHealthCheck(ctx)
 	}()
 
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.runRateLimitSweeper(ctx)
+	}()
+
+	if a.eventListener != nil {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			if err := a.eventListener.Run(ctx); err != nil {
+				a.logger.Error("event listener stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		errCh <- a.runHTTP(ctx)
+	}()
+
+	// In single-port mode the gRPC server is served by the HTTP server.
+	if a.cfg.ListenMode == config.ListenModeSplit {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			errCh <- a.runGRPC(ctx)
+		}()
+	}
+
 	select {
 	case <-ctx.Done():
 		return a.shutdown()
@@ -128,12 +350,32 @@ func (a *App) shutdown() error {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
+	// Report NOT_SERVING first so that load balancers stop routing to this instance
+	// while the servers drain.
+	a.healthSrv.Shutdown()
+
+	// Streaming responses never go idle on their own and hijacked WebSocket connections
+	// aren't tracked by the HTTP server, so end them before asking the servers to drain.
+	a.hub.Close()
+	a.wsServer.Close()
+	a.graphqlServer.Close()
+
 	_ = a.shutdownHTTP()
-	a.shutdownGRPC()
+	if a.cfg.ListenMode == config.ListenModeSplit {
+		a.shutdownGRPC()
+	} else {
+		// gRPC requests were drained by the HTTP server, and GracefulStop isn't
+		// supported for connections served through ServeHTTP.
+		a.grpcServer.Stop()
+	}
+	_ = a.gatewayConn.Close()
 
 	done := make(chan struct{})
 	go func() {
 		a.wg.Wait()
+		a.passwordResetUC.Wait()
+		a.emailVerificationUC.Wait()
+		a.loginThrottle.Wait()
 		close(done)
 	}()
 
@@ -215,13 +457,251 @@ func (a *App) shutdownGRPC() {
 	}
 }
 
-func initHandlers(todoUC *usecase.TodoUsecase, userUC *usecase.UserUseCase, jwtService *auth.JWTService) *gin.Engine {
-	todoHandler := internal_http.NewTodoHandler(todoUC)
-	userHandler := internal_http.NewUserHandler(userUC)
+// runHealthCheck reports the gRPC services as serving while the database is reachable.
+// Once shutdown has started, the health server ignores further updates.
+func (a *App) runHealthCheck(ctx context.Context) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		a.checkHealth(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) checkHealth(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if err := a.db.PingContext(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		a.logger.Warn("database health check failed", zap.Error(err))
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	a.healthSrv.SetServingStatus("", status)
+	for _, service := range healthServices {
+		a.healthSrv.SetServingStatus(service, status)
+	}
+}
+
+// runOutboxRelay publishes outbox events until ctx is cancelled. Events that failed to
+// publish stay in the outbox and are retried on the next tick.
+func (a *App) runOutboxRelay(ctx context.Context) {
+	ticker := time.NewTicker(outboxRelayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for ctx.Err() == nil {
+			n, err := a.outboxRelay.RelayBatch(ctx)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					a.logger.Error("failed to relay outbox events", zap.Error(err))
+				}
+				break
+			}
+			if n == 0 {
+				break
+			}
+		}
+	}
+}
+
+// runOutboxPurge deletes old published outbox events until ctx is cancelled, so that the
+// outbox doesn't grow forever.
+func (a *App) runOutboxPurge(ctx context.Context) {
+	ticker := time.NewTicker(outboxPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := a.outboxRelay.Purge(ctx)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				a.logger.Error("failed to purge outbox events", zap.Error(err))
+			}
+			continue
+		}
+		if n > 0 {
+			a.logger.Debug("purged outbox events", zap.Int64("count", n))
+		}
+	}
+}
+
+// runRateLimitSweeper forgets unused rate limit keys until ctx is cancelled, so that
+// they don't pile up.
+func (a *App) runRateLimitSweeper(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := a.rateLimiter.Sweep(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			a.logger.Error("failed to sweep rate limits", zap.Error(err))
+		}
+	}
+}
+
+// runWebhookDispatcher drains due webhook deliveries until ctx is cancelled. A full batch
+// is followed immediately by the next one so that a backlog doesn't wait for the ticker.
+func (a *App) runWebhookDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(webhookDispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for ctx.Err() == nil {
+			n, err := a.webhookUC.DispatchDue(ctx)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					a.logger.Error("failed to dispatch webhook deliveries", zap.Error(err))
+				}
+				break
+			}
+			if n == 0 {
+				break
+			}
+		}
+	}
+}
+
+func initHandlers(gatewayHandler http.Handler, webhookUC *usecase.WebhookUsecase, oauthUC *usecase.OAuthUsecase, hub *stream.Hub, wsServer *ws.Server, graphqlServer *graphql.Server, openapiHandler *internal_http.OpenAPIHandler, validator gin.HandlerFunc, jwtService *auth.JWTService, tokenUC *usecase.AccessTokenUsecase, rateLimiter *usecase.RateLimiter, clientIPs *clientip.Resolver) *gin.Engine {
+	webhookHandler := internal_http.NewWebhookHandler(webhookUC)
+	oauthHandler := internal_http.NewOAuthHandler(oauthUC)
+	streamHandler := internal_http.NewStreamHandler(hub)
 	r := gin.Default()
+	if validator != nil {
+		r.Use(validator)
+	}
+	// Gateway routes are rate limited by the gRPC interceptors, the others here.
+	// Addresses are limited before authenticating, and callers after.
+	authLimit := middleware.RateLimitByIP(rateLimiter, clientIPs, usecase.RateLimitGroupAuth)
+	apiLimit := middleware.RateLimitByIP(rateLimiter, clientIPs, usecase.RateLimitGroupAPI)
+	callerLimit := middleware.RateLimitByCaller(rateLimiter, usecase.RateLimitGroupAPI)
+	openapiHandler.RegisterRoutes(r)
+	internal_http.NewJWKSHandler(jwtService).RegisterRoutes(r)
+	oauthHandler.RegisterRoutes(r.Group("/", authLimit))
+	// The WebSocket endpoints authenticate on their own, as browsers can't send an
+	// Authorization header with the upgrade request.
+	r.GET("/api/v1/ws", apiLimit, wsServer.Handle)
+	r.GET("/graphql", apiLimit, graphqlServer.HandleWebSocket)
+	r.POST("/graphql", apiLimit, middleware.JWTMiddleware(tokenUC), callerLimit, graphqlServer.Handle)
+	// The gateway serves the paths gin has no route for, and is authenticated by the
+	// gRPC interceptors. Gin answers those with 404 unless the handler sets a status,
+	// which the gateway leaves implicit on success.
+	r.NoRoute(func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		gatewayHandler.ServeHTTP(c.Writer, c.Request)
+	})
 	api := r.Group("/api/v1")
-	api.Use(middleware.JWTMiddleware(jwtService))
-	todoHandler.RegisterRoutes(api.Group("/todos"))
-	userHandler.RegisterRoutes(api.Group("/users"))
+	api.Use(apiLimit, middleware.JWTMiddleware(tokenUC), callerLimit)
+	streamHandler.RegisterRoutes(api.Group("/todos"))
+	webhookHandler.RegisterRoutes(api.Group("/webhooks"))
+	oauthHandler.RegisterAPIRoutes(api.Group("/oauth"))
 	return r
 }
+
+// initWebAuthn configures passkeys to be discoverable and to verify the user, so that
+// they replace both the password and the second factor.
+func initWebAuthn(cfg *config.Config) (*webauthn.WebAuthn, error) {
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthnRPID,
+		RPDisplayName: cfg.MFAIssuer,
+		RPOrigins:     cfg.WebAuthnOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: usecase.PasskeyCeremonyTimeout},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: usecase.PasskeyCeremonyTimeout},
+		},
+	})
+}
+
+// initOIDCProviders returns the configured OpenID Connect providers by name. Each
+// redirects back to its own callback page.
+func initOIDCProviders(cfg *config.Config) map[string]*auth.OIDCProvider {
+	providers := make(map[string]*auth.OIDCProvider, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		redirectURL := strings.TrimSuffix(cfg.OIDCRedirectURL, "/") + "/" + p.Name
+		providers[p.Name] = auth.NewOIDCProvider(p.Issuer, p.ClientID, p.ClientSecret, redirectURL)
+	}
+	return providers
+}
+
+// initRateLimits returns the configured rate limits.
+func initRateLimits(cfg *config.Config) []usecase.RateLimit {
+	limits := make([]usecase.RateLimit, 0, len(cfg.RateLimits))
+	for _, l := range cfg.RateLimits {
+		limits = append(limits, usecase.RateLimit{
+			Group:    l.Group,
+			Kind:     usecase.RateLimitKind(l.Kind),
+			Requests: l.Requests,
+			Period:   l.Period,
+		})
+	}
+	return limits
+}
+
+func initGRPCServer(cfg *config.Config, todoUC *usecase.TodoUsecase, listUC *usecase.ListUsecase, userUC *usecase.UserUseCase, passwordResetUC *usecase.PasswordResetUsecase, emailVerificationUC *usecase.EmailVerificationUsecase, loginThrottle *usecase.LoginThrottle, mfaUC *usecase.MFAUsecase, passkeyUC *usecase.PasskeyUsecase, oidcUC *usecase.OIDCUsecase, tokenUC *usecase.AccessTokenUsecase, adminUC *usecase.AdminUsecase, hub *stream.Hub, clientIPs *clientip.Resolver, rateLimiter *usecase.RateLimiter, healthSrv *health.Server, logger *zap.Logger) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			interceptors.LoggingUnaryInterceptor(logger),
+			interceptors.RecoveryUnaryInterceptor(logger),
+			interceptors.ClientIPUnaryInterceptor(clientIPs),
+			interceptors.RateLimitByIPUnaryInterceptor(rateLimiter),
+			interceptors.AuthUnaryInterceptor(tokenUC),
+			interceptors.RateLimitByCallerUnaryInterceptor(rateLimiter),
+			interceptors.RequirePermissionUnaryInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptors.LoggingStreamInterceptor(logger),
+			interceptors.RecoveryStreamInterceptor(logger),
+			interceptors.ClientIPStreamInterceptor(clientIPs),
+			interceptors.RateLimitByIPStreamInterceptor(rateLimiter),
+			interceptors.AuthStreamInterceptor(tokenUC),
+			interceptors.RateLimitByCallerStreamInterceptor(rateLimiter),
+			interceptors.RequirePermissionStreamInterceptor(),
+		),
+	)
+	todov1.RegisterTodoServiceServer(srv, internal_grpc.NewTodoServer(todoUC, listUC, hub))
+	userv1.RegisterUserServiceServer(srv, internal_grpc.NewUserServer(userUC, passwordResetUC, emailVerificationUC, mfaUC, passkeyUC, oidcUC, tokenUC, loginThrottle))
+	adminv1.RegisterAdminServiceServer(srv, internal_grpc.NewAdminServer(adminUC))
+	healthpb.RegisterHealthServer(srv, healthSrv)
+	if cfg.GRPCDebug {
+		reflection.Register(srv)
+		channelz.RegisterChannelzServiceToServer(srv)
+	}
+	return srv
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	adminv1 "github.com/mrxacker/go-to-do-app/api/admin/v1"
+	todov1 "github.com/mrxacker/go-to-do-app/api/todo/v1"
+	userv1 "github.com/mrxacker/go-to-do-app/api/user/v1"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/clientip"
+	internal_http "github.com/mrxacker/go-to-do-app/internal/adapters/http/handlers"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/http/middleware"
+	"github.com/mrxacker/go-to-do-app/internal/adapters/http/openapi"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/ratelimit"
+	"github.com/mrxacker/go-to-do-app/internal/usecase"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// newTestRouter returns the HTTP routes with validation and without rate limits, and a
+// gateway that answers every request with an empty object and records its path.
+func newTestRouter(t *testing.T) (*gin.Engine, *[]string) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	spec, err := openapi.NewSpec()
+	if err != nil {
+		t.Fatalf("build spec: %v", err)
+	}
+	openapiHandler, err := internal_http.NewOpenAPIHandler(spec)
+	if err != nil {
+		t.Fatalf("create OpenAPI handler: %v", err)
+	}
+	validator, err := middleware.OpenAPIValidator(spec, false, zap.NewNop())
+	if err != nil {
+		t.Fatalf("create validator: %v", err)
+	}
+
+	clientIPs, err := clientip.NewResolver(nil)
+	if err != nil {
+		t.Fatalf("create client IP resolver: %v", err)
+	}
+	limiter := usecase.NewRateLimiter(ratelimit.NewMemoryStore(), nil, zap.NewNop())
+
+	var served []string
+	gateway := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	})
+	return initHandlers(gateway, nil, nil, nil, nil, nil, openapiHandler, validator, nil, nil, limiter, clientIPs), &served
+}
+
+func TestGatewayMount(t *testing.T) {
+	r, served := newTestRouter(t)
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		gateway            bool
+	}{
+		{http.MethodGet, "/api/v1/todos", "", http.StatusOK, true},
+		{http.MethodGet, "/api/v1/todos/42", "", http.StatusOK, true},
+		{http.MethodPost, "/api/v1/todos", `{"title":"Buy milk"}`, http.StatusOK, true},
+		{http.MethodGet, "/api/v1/admin/audit-log", "", http.StatusOK, true},
+		// Requests the spec rejects don't reach the gateway.
+		{http.MethodPost, "/api/v1/todos", `{"title":42}`, http.StatusBadRequest, false},
+		// Gin's routes are served by gin, behind its own authentication.
+		{http.MethodGet, "/api/v1/todos/stream", "", http.StatusUnauthorized, false},
+		{http.MethodGet, "/api/v1/webhooks/", "", http.StatusUnauthorized, false},
+		{http.MethodGet, "/openapi.json", "", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			*served = nil
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if reached := len(*served) > 0; reached != tt.gateway {
+				t.Fatalf("gateway served the request: %v, want %v", reached, tt.gateway)
+			}
+		})
+	}
+}
+
+// undocumented are the routes the spec leaves out on purpose.
+var undocumented = map[string]bool{
+	// The spec itself.
+	"GET /openapi.json": true,
+	"GET /docs":         true,
+	// Standard OAuth 2.0 endpoints, described by their RFCs.
+	"GET /.well-known/jwks.json": true,
+	"GET /oauth/authorize":       true,
+	"POST /oauth/token":          true,
+	"POST /oauth/revoke":         true,
+	"POST /oauth/introspect":     true,
+	// GraphQL is described by its own schema.
+	"GET /graphql":  true,
+	"POST /graphql": true,
+}
+
+var (
+	ginParam   = regexp.MustCompile(`:([^/]+)`)
+	protoParam = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+)
+
+func TestSpecCoversRoutes(t *testing.T) {
+	spec, err := openapi.NewSpec()
+	if err != nil {
+		t.Fatalf("build spec: %v", err)
+	}
+	documented := func(method, path string) bool {
+		// Groups register their root with a trailing slash, which the spec leaves out.
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+		item := spec.Paths.Find(path)
+		return item != nil && item.GetOperation(method) != nil
+	}
+
+	r, _ := newTestRouter(t)
+	for _, route := range r.Routes() {
+		if undocumented[route.Method+" "+route.Path] {
+			continue
+		}
+		if path := ginParam.ReplaceAllString(route.Path, "{$1}"); !documented(route.Method, path) {
+			t.Errorf("%s %s isn't in the spec", route.Method, route.Path)
+		}
+	}
+
+	for _, service := range []protoreflect.ServiceDescriptor{
+		todov1.File_todo_v1_todo_proto.Services().ByName("TodoService"),
+		userv1.File_user_v1_user_proto.Services().ByName("UserService"),
+		adminv1.File_admin_v1_admin_proto.Services().ByName("AdminService"),
+	} {
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			m := methods.Get(i)
+			rule, _ := proto.GetExtension(m.Options(), annotations.E_Http).(*annotations.HttpRule)
+			if rule == nil {
+				// gRPC only, like the streams that gin serves over SSE and WebSocket.
+				continue
+			}
+			for _, binding := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+				method, path := httpPattern(binding)
+				path = protoParam.ReplaceAllString(path, "{$1}")
+				if !documented(method, path) {
+					t.Errorf("%s: %s %s isn't in the spec", m.FullName(), method, path)
+				}
+			}
+		}
+	}
+}
+
+func httpPattern(rule *annotations.HttpRule) (method, path string) {
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return http.MethodGet, p.Get
+	case *annotations.HttpRule_Post:
+		return http.MethodPost, p.Post
+	case *annotations.HttpRule_Put:
+		return http.MethodPut, p.Put
+	case *annotations.HttpRule_Patch:
+		return http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Delete:
+		return http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Custom:
+		return p.Custom.GetKind(), p.Custom.GetPath()
+	}
+	return "", ""
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+)
+
+// newMuxHandler serves native gRPC, gRPC-Web and the REST API from one HTTP server.
+// Native gRPC needs HTTP/2, which the server accepts without TLS (h2c) in this mode.
+func newMuxHandler(grpcSrv *grpc.Server, rest http.Handler, allowedOrigins []string) http.Handler {
+	web := grpcweb.WrapServer(grpcSrv,
+		grpcweb.WithOriginFunc(func(origin string) bool {
+			return slices.Contains(allowedOrigins, origin)
+		}),
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case web.IsGrpcWebRequest(r) || web.IsAcceptableGrpcCorsRequest(r):
+			web.ServeHTTP(w, r)
+		case r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc"):
+			grpcSrv.ServeHTTP(w, r)
+		default:
+			rest.ServeHTTP(w, r)
+		}
+	})
+}
@@ -1,17 +1,36 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	// ListenModeSplit serves REST on HTTPAddr and gRPC on GRPCAddr.
+	ListenModeSplit = "split"
+	// ListenModeSingle serves REST, gRPC and gRPC-Web on HTTPAddr.
+	ListenModeSingle = "single"
+)
+
+// minSecretBytes is the shortest secret that HMAC signing keys are accepted with.
+const minSecretBytes = 32
+
 type Config struct {
 	ENV      string
 	HTTPAddr string
 	GRPCAddr string
+	// ListenMode is ListenModeSplit or ListenModeSingle.
+	ListenMode string
+	// GRPCWebAllowedOrigins are the cross-origin sites allowed to call gRPC-Web.
+	GRPCWebAllowedOrigins []string
+	// GRPCDebug enables server reflection and channelz on the gRPC server.
+	GRPCDebug bool
 
 	DBHost     string
 	DBPort     int
@@ -19,7 +38,120 @@ type Config struct {
 	DBPassword string
 	DBName     string
 
+	// JWTSecret signs tokens with HS256 when JWTSigningKeyFile isn't set. It must then
+	// be at least minSecretBytes long.
 	JWTSecret string
+	// JWTSigningKeyFile is a PEM RSA or Ed25519 private key tokens are signed with.
+	// Without it tokens are signed with JWTSecret.
+	JWTSigningKeyFile string
+	// JWTVerificationKeyFiles are PEM public keys of retired signing keys, still
+	// accepted while the tokens they signed are alive.
+	JWTVerificationKeyFiles []string
+	JWTIssuer               string
+	JWTAudience             string
+	AccessTokenTTL          time.Duration
+	RefreshTokenTTL         time.Duration
+
+	// SMTPAddr is the host:port of the relay mail is sent through. Without it, mail is
+	// written to the log.
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+
+	// PasswordResetURL is the page reset links point to, with the token in its query.
+	PasswordResetURL string
+	PasswordResetTTL time.Duration
+
+	// EmailVerificationPolicy is "optional", "required" to refuse logging in unverified
+	// users, or "read_only" to only let them read.
+	EmailVerificationPolicy string
+	// EmailVerificationURL is the page verification links point to, with the token in
+	// its query.
+	EmailVerificationURL            string
+	EmailVerificationTTL            time.Duration
+	EmailVerificationResendInterval time.Duration
+	// LinkSigningSecret signs the tokens of emailed links and MFA challenges. It is
+	// required, at least minSecretBytes long, and must differ from JWTSecret, so that
+	// neither kind of token can be passed off as the other.
+	LinkSigningSecret string
+
+	// MFAEncryptionKey encrypts TOTP secrets at rest. It is 32 bytes, set base64 encoded;
+	// without it, two-factor authentication can't be enabled.
+	MFAEncryptionKey []byte
+	// MFAIssuer names the app in authenticator apps and passkey prompts.
+	MFAIssuer string
+
+	// WebAuthnRPID is the domain passkeys are registered for. Passkeys keep working for
+	// its subdomains, but not if it changes.
+	WebAuthnRPID string
+	// WebAuthnOrigins are the origins of the pages passkeys are used from.
+	WebAuthnOrigins []string
+
+	// OIDCProviders are the OpenID Connect providers users can log in with, set by name
+	// in OIDC_PROVIDERS, each with OIDC_<NAME>_ISSUER, _CLIENT_ID and _CLIENT_SECRET.
+	OIDCProviders []OIDCProviderConfig
+	// OIDCRedirectURL is the page providers redirect back to, followed by the provider
+	// name, e.g. http://localhost:3000/oidc/callback/google.
+	OIDCRedirectURL string
+
+	// OAuthConsentURL is the page third-party clients send users to for consent, with
+	// the request in its query.
+	OAuthConsentURL string
+
+	// LoginMaxFailures failed logins in a row lock an account until none has failed for
+	// LoginLockout, and mail the user an unlock link. Accounts are slowed down before
+	// that. LoginIPMaxFailures does the same for a client address, without a link.
+	LoginMaxFailures   int
+	LoginIPMaxFailures int
+	LoginLockout       time.Duration
+	// AccountUnlockURL is the page unlock links point to, with the token in its query.
+	AccountUnlockURL string
+	// TrustedProxies are the addresses or CIDR ranges of the proxies whose
+	// X-Forwarded-For entries are believed when finding client addresses.
+	TrustedProxies []string
+
+	// RateLimitStore is "memory" to keep rate limits per instance, or "postgres" to share
+	// them between replicas.
+	RateLimitStore string
+	// RateLimits are the requests allowed per route group ("auth" or "api") and per
+	// "user", "key" or "ip", set as RATE_LIMIT_<GROUP>_<KIND>=<requests>/<period>, e.g.
+	// RATE_LIMIT_API_USER=600/1m. A limit of 0 turns it off.
+	RateLimits []RateLimitConfig
+
+	EventsPGNotify bool
+	EventsChannel  string
+	// OutboxRetention is how long published events stay in the outbox, where instances
+	// reload the events they missed while their listener was disconnected.
+	OutboxRetention time.Duration
+
+	// OpenAPIValidation checks REST requests against the OpenAPI spec, and responses
+	// too when ENV is "dev".
+	OpenAPIValidation bool
+}
+
+type RateLimitConfig struct {
+	Group    string
+	Kind     string
+	Requests int
+	Period   time.Duration
+}
+
+// rateLimits are the limits that can be set, with their defaults.
+var rateLimits = []struct{ group, kind, def string }{
+	{"auth", "user", "0"},
+	{"auth", "key", "0"},
+	{"auth", "ip", "30/1m"},
+	{"api", "user", "600/1m"},
+	{"api", "key", "300/1m"},
+	{"api", "ip", "1200/1m"},
+}
+
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
 }
 
 func LoadConfig() (*Config, error) {
@@ -31,17 +163,152 @@ func LoadConfig() (*Config, error) {
 		ENV:        getEnv("ENV", "dev"),
 		HTTPAddr:   getEnv("HTTPPort", "8080"),
 		GRPCAddr:   getEnv("GRPCPort", "9091"),
+		GRPCDebug:  getEnvBool("GRPC_DEBUG", false),
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnvInt("DB_PORT", 5432),
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", "password"),
 		DBName:     getEnv("DB_NAME", "todoapp"),
 		JWTSecret:  getEnv("JWT_SECRET", ""),
+
+		JWTSigningKeyFile:       getEnv("JWT_SIGNING_KEY_FILE", ""),
+		JWTVerificationKeyFiles: getEnvList("JWT_VERIFICATION_KEY_FILES"),
+		JWTIssuer:               getEnv("JWT_ISSUER", "go-to-do-app"),
+		JWTAudience:             getEnv("JWT_AUDIENCE", "go-to-do-app"),
+
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		SMTPAddr:     getEnv("SMTP_ADDR", ""),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		MailFrom:     getEnv("MAIL_FROM", "Todo App <no-reply@localhost>"),
+
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		PasswordResetTTL: getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+
+		EmailVerificationPolicy:         getEnv("EMAIL_VERIFICATION_POLICY", "optional"),
+		EmailVerificationURL:            getEnv("EMAIL_VERIFICATION_URL", "http://localhost:3000/verify-email"),
+		EmailVerificationTTL:            getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		EmailVerificationResendInterval: getEnvDuration("EMAIL_VERIFICATION_RESEND_INTERVAL", 5*time.Minute),
+		LinkSigningSecret:               getEnv("LINK_SIGNING_SECRET", ""),
+
+		MFAIssuer: getEnv("MFA_ISSUER", "Todo App"),
+
+		WebAuthnRPID:    getEnv("WEBAUTHN_RP_ID", "localhost"),
+		WebAuthnOrigins: getEnvList("WEBAUTHN_ORIGINS"),
+
+		OIDCRedirectURL: getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/oidc/callback"),
+
+		OAuthConsentURL: getEnv("OAUTH_CONSENT_URL", "http://localhost:3000/oauth/consent"),
+
+		LoginMaxFailures:   getEnvInt("LOGIN_MAX_FAILURES", 10),
+		LoginIPMaxFailures: getEnvInt("LOGIN_IP_MAX_FAILURES", 100),
+		LoginLockout:       getEnvDuration("LOGIN_LOCKOUT", 15*time.Minute),
+		AccountUnlockURL:   getEnv("ACCOUNT_UNLOCK_URL", "http://localhost:3000/unlock-account"),
+		TrustedProxies:     getEnvList("TRUSTED_PROXIES"),
+
+		RateLimitStore: getEnv("RATE_LIMIT_STORE", "memory"),
+
+		EventsPGNotify:  getEnvBool("EVENTS_PG_NOTIFY", false),
+		EventsChannel:   getEnv("EVENTS_CHANNEL", "todo_app_events"),
+		OutboxRetention: getEnvDuration("OUTBOX_RETENTION", 24*time.Hour),
+
+		ListenMode:            getEnv("LISTEN_MODE", ListenModeSplit),
+		GRPCWebAllowedOrigins: getEnvList("GRPC_WEB_ALLOWED_ORIGINS"),
+
+		OpenAPIValidation: getEnvBool("OPENAPI_VALIDATION", false),
+	}
+
+	if cfg.ListenMode != ListenModeSplit && cfg.ListenMode != ListenModeSingle {
+		return nil, fmt.Errorf("invalid LISTEN_MODE %q", cfg.ListenMode)
+	}
+
+	switch cfg.EmailVerificationPolicy {
+	case "optional", "required", "read_only":
+	default:
+		return nil, fmt.Errorf("invalid EMAIL_VERIFICATION_POLICY %q", cfg.EmailVerificationPolicy)
+	}
+
+	if cfg.LoginMaxFailures < 1 || cfg.LoginIPMaxFailures < 1 {
+		return nil, fmt.Errorf("LOGIN_MAX_FAILURES and LOGIN_IP_MAX_FAILURES must be positive")
+	}
+
+	if cfg.RateLimitStore != "memory" && cfg.RateLimitStore != "postgres" {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_STORE %q", cfg.RateLimitStore)
+	}
+	for _, l := range rateLimits {
+		key := "RATE_LIMIT_" + strings.ToUpper(l.group) + "_" + strings.ToUpper(l.kind)
+		limit, err := parseRateLimit(getEnv(key, l.def))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		if limit.Requests > 0 {
+			limit.Group, limit.Kind = l.group, l.kind
+			cfg.RateLimits = append(cfg.RateLimits, limit)
+		}
+	}
+
+	if cfg.JWTSigningKeyFile == "" && len(cfg.JWTSecret) < minSecretBytes {
+		return nil, fmt.Errorf("JWT_SECRET must be at least %d bytes when JWT_SIGNING_KEY_FILE is not set", minSecretBytes)
+	}
+	if len(cfg.LinkSigningSecret) < minSecretBytes {
+		return nil, fmt.Errorf("LINK_SIGNING_SECRET must be at least %d bytes", minSecretBytes)
+	}
+	if cfg.LinkSigningSecret == cfg.JWTSecret {
+		return nil, fmt.Errorf("LINK_SIGNING_SECRET must differ from JWT_SECRET")
+	}
+
+	if key := getEnv("MFA_ENCRYPTION_KEY", ""); key != "" {
+		b, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(b) != 32 {
+			return nil, fmt.Errorf("MFA_ENCRYPTION_KEY must be 32 base64 encoded bytes")
+		}
+		cfg.MFAEncryptionKey = b
+	}
+
+	if len(cfg.WebAuthnOrigins) == 0 {
+		cfg.WebAuthnOrigins = []string{"http://localhost:3000"}
+	}
+
+	for _, name := range getEnvList("OIDC_PROVIDERS") {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProviderConfig{
+			Name:         strings.ToLower(name),
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+		cfg.OIDCProviders = append(cfg.OIDCProviders, provider)
 	}
 
 	return cfg, nil
 }
 
+// parseRateLimit parses "<requests>/<period>", or "0" for no limit.
+func parseRateLimit(v string) (RateLimitConfig, error) {
+	if v == "0" {
+		return RateLimitConfig{}, nil
+	}
+
+	requests, period, ok := strings.Cut(v, "/")
+	if !ok {
+		return RateLimitConfig{}, fmt.Errorf("want <requests>/<period>, e.g. 600/1m")
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 0 {
+		return RateLimitConfig{}, fmt.Errorf("invalid number of requests %q", requests)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d < time.Second {
+		return RateLimitConfig{}, fmt.Errorf("invalid period %q", period)
+	}
+	return RateLimitConfig{Requests: n, Period: d}, nil
+}
+
 func getEnv(key, def string) string {
 	if v, ok := os.LookupEnv(key); ok {
 		return v
@@ -62,3 +329,41 @@ func getEnvInt(key string, def int) int {
 
 	return i
 }
+
+func getEnvBool(key string, def bool) bool {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+
+	return b
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+
+	return d
+}
+
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigSecrets(t *testing.T) {
+	long := strings.Repeat("a", minSecretBytes)
+	other := strings.Repeat("b", minSecretBytes)
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "no JWT secret or key file",
+			env:     map[string]string{"LINK_SIGNING_SECRET": other},
+			wantErr: "JWT_SECRET",
+		},
+		{
+			name:    "short JWT secret",
+			env:     map[string]string{"JWT_SECRET": "short", "LINK_SIGNING_SECRET": other},
+			wantErr: "JWT_SECRET",
+		},
+		{
+			name: "JWT secret",
+			env:  map[string]string{"JWT_SECRET": long, "LINK_SIGNING_SECRET": other},
+		},
+		{
+			name: "key file without JWT secret",
+			env:  map[string]string{"JWT_SIGNING_KEY_FILE": "jwt.pem", "LINK_SIGNING_SECRET": other},
+		},
+		{
+			// The link secret no longer defaults to the JWT secret.
+			name:    "no link secret",
+			env:     map[string]string{"JWT_SECRET": long},
+			wantErr: "LINK_SIGNING_SECRET",
+		},
+		{
+			name:    "short link secret",
+			env:     map[string]string{"JWT_SECRET": long, "LINK_SIGNING_SECRET": "short"},
+			wantErr: "LINK_SIGNING_SECRET",
+		},
+		{
+			name:    "link secret equal to JWT secret",
+			env:     map[string]string{"JWT_SECRET": long, "LINK_SIGNING_SECRET": long},
+			wantErr: "LINK_SIGNING_SECRET",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if err := os.WriteFile(".env", nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{"JWT_SECRET", "JWT_SIGNING_KEY_FILE", "LINK_SIGNING_SECRET"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			_, err := LoadConfig()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("LoadConfig: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("LoadConfig err = %v, want one about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type AdminUserItem struct {
+	ID            models.UserID `json:"id"`
+	Username      string        `json:"username"`
+	Email         string        `json:"email"`
+	Role          models.Role   `json:"role"`
+	EmailVerified bool          `json:"email_verified"`
+	// DisabledAt is null for users who aren't disabled.
+	DisabledAt *time.Time `json:"disabled_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type SetUserRoleRequest struct {
+	Role models.Role `json:"role" binding:"required"`
+}
+
+type SystemStats struct {
+	Users         int64                 `json:"users"`
+	VerifiedUsers int64                 `json:"verified_users"`
+	DisabledUsers int64                 `json:"disabled_users"`
+	UsersByRole   map[models.Role]int64 `json:"users_by_role"`
+	Todos         int64                 `json:"todos"`
+}
+
+type AuditEntryItem struct {
+	ID      models.AuditEntryID `json:"id"`
+	ActorID models.UserID       `json:"actor_id"`
+	Action  models.AuditAction  `json:"action"`
+	// TargetUserID is null for actions that aren't taken on a user.
+	TargetUserID *models.UserID `json:"target_user_id"`
+	Details      map[string]any `json:"details"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
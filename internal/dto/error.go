@@ -0,0 +1,6 @@
+package dto
+
+// ErrorResponse is the body of every error response of the REST API.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,115 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=64"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,max=10"`
+	// Public clients, such as single-page and native apps, get no secret and rely on
+	// PKCE alone.
+	Public bool `json:"public"`
+}
+
+type OAuthClientItem struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Public       bool      `json:"public"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CreateOAuthClientResponse is the only response that carries the client secret.
+type CreateOAuthClientResponse struct {
+	OAuthClientItem
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type OAuthClientURI struct {
+	ClientID string `uri:"client_id" binding:"required"`
+}
+
+// OAuthAuthorizeRequest is the query of the authorization endpoint. PKCE with S256 is
+// required of every client.
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+type OAuthConsentQuery struct {
+	Request string `form:"request" binding:"required"`
+}
+
+// OAuthConsentRequest describes a client's request for the consent page.
+type OAuthConsentRequest struct {
+	ClientName  string         `json:"client_name"`
+	RedirectURI string         `json:"redirect_uri"`
+	Scopes      []models.Scope `json:"scopes"`
+}
+
+type OAuthConsentDecision struct {
+	// Request is the request parameter the consent page was opened with.
+	Request string `json:"request" binding:"required"`
+	Approve bool   `json:"approve"`
+}
+
+// OAuthRedirect is where the consent page sends the user back to the client.
+type OAuthRedirect struct {
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// OAuthTokenRequest is the form of the token endpoint. Clients authenticate with HTTP
+// Basic or with client_id and client_secret in the form.
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// OAuthTokenResponse is the successful response of the token endpoint (RFC 6749 5.1).
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// OAuthTokenForm is the form of the revocation and introspection endpoints.
+type OAuthTokenForm struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// OAuthIntrospection is the response of the introspection endpoint (RFC 7662). Only
+// Active is set for inactive tokens.
+type OAuthIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// OAuthError is the error response of the OAuth endpoints (RFC 6749 5.2).
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
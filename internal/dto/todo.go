@@ -1,11 +1,29 @@
 package dto
 
-import "github.com/mrxacker/go-to-do-app/internal/models"
+import (
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
 
 type CreateTodoRequest struct {
 	UserID      models.UserID `json:"user_id" binding:"required"`
 	Title       string        `json:"title" binding:"required"`
 	Description string        `json:"description" binding:"required"`
+	Completed   bool          `json:"completed"`
+}
+
+// CreateTodoBody and UpdateTodoBody are the REST request bodies. The owner is always
+// the authenticated user.
+type CreateTodoBody struct {
+	Title       string `json:"title" binding:"required,max=200"`
+	Description string `json:"description"`
+}
+
+type UpdateTodoBody struct {
+	Title       string `json:"title" binding:"required,max=200"`
+	Description string `json:"description"`
+	Completed   bool   `json:"completed"`
 }
 
 type UpdateTodoURI struct {
@@ -32,6 +50,35 @@ type ListTodosResponse struct {
 
 type TodoItem struct {
 	ID          models.ToDoID `json:"id"`
+	UserID      models.UserID `json:"user_id"`
 	Title       string        `json:"title"`
 	Description string        `json:"description"`
+	Completed   bool          `json:"completed"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+type ShareListRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ListMemberItem struct {
+	UserID    models.UserID `json:"user_id"`
+	Email     string        `json:"email"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// SharedListItem is a list another user shared with the caller. ListID is the owner's
+// user ID, which WebSocket clients subscribe to.
+type SharedListItem struct {
+	ListID     models.UserID `json:"list_id"`
+	OwnerEmail string        `json:"owner_email"`
+	SharedAt   time.Time     `json:"shared_at"`
+}
+
+// ImportTodoResult reports the outcome for one todo of an import. Err is set for
+// todos that were rejected.
+type ImportTodoResult struct {
+	ID  models.ToDoID
+	Err error
 }
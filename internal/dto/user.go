@@ -1,16 +1,150 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
 type RegisterUserRequest struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+type RegisterUserResponse struct {
+	ID models.UserID `json:"id"`
+}
+
 type LoginUserRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
 }
 
 type AuthResponse struct {
-	Token string `json:"token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	// ExpiresIn is the lifetime of the access token in seconds.
+	ExpiresIn int64 `json:"expires_in"`
+	// MFAToken is set instead of the tokens when the user has to enter a second factor.
+	MFAToken string `json:"mfa_token,omitempty"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// Code is a TOTP code or a recovery code.
+	Code string `json:"code" binding:"required"`
+}
+
+type TOTPEnrollment struct {
+	// Secret is the base32 secret, for authenticators that can't scan the QR code.
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCodePNG  []byte `json:"qr_png"`
+}
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type ConfirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// PasskeyCeremony starts registering or logging in with a passkey.
+type PasskeyCeremony struct {
+	// SessionID identifies the challenge; it is sent back with the browser's response.
+	SessionID string `json:"session_id"`
+	// Options are passed to navigator.credentials.create or get, with binary values
+	// base64url encoded.
+	Options map[string]any `json:"options"`
+}
+
+type FinishPasskeyRegistrationRequest struct {
+	SessionID string `json:"session_id" binding:"required"`
+	Name      string `json:"name" binding:"required,max=64"`
+	// Credential is the PublicKeyCredential returned by the browser, in its JSON form.
+	Credential map[string]any `json:"credential" binding:"required"`
+}
+
+type FinishPasskeyLoginRequest struct {
+	SessionID string `json:"session_id" binding:"required"`
+	// Credential is the PublicKeyCredential returned by the browser, in its JSON form.
+	Credential map[string]any `json:"credential" binding:"required"`
+}
+
+type OIDCLoginResponse struct {
+	// AuthorizationURL is the provider's login page, which redirects back with a state
+	// and a code.
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type FinishOIDCLoginRequest struct {
+	State string `json:"state" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+type PasskeyItem struct {
+	ID   models.PasskeyID `json:"id"`
+	Name string           `json:"name"`
+	// BackedUp reports whether the passkey is synced, e.g. to a cloud keychain.
+	BackedUp  bool      `json:"backed_up"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateAccessTokenRequest struct {
+	Name   string         `json:"name" binding:"required,max=64"`
+	Scopes []models.Scope `json:"scopes" binding:"required,min=1"`
+	// ExpiresAt is omitted for a token that doesn't expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type AccessTokenItem struct {
+	ID         models.AccessTokenID `json:"id"`
+	Name       string               `json:"name"`
+	Scopes     []models.Scope       `json:"scopes"`
+	ExpiresAt  *time.Time           `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time           `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+type CreateAccessTokenResponse struct {
+	// Token is sent as a bearer token. It can't be retrieved again.
+	Token       string          `json:"token"`
+	AccessToken AccessTokenItem `json:"access_token"`
 }
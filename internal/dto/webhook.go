@@ -0,0 +1,64 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type CreateWebhookRequest struct {
+	URL    string             `json:"url" binding:"required,url"`
+	Events []models.EventType `json:"events" binding:"required,min=1"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    string             `json:"url" binding:"required,url"`
+	Events []models.EventType `json:"events" binding:"required,min=1"`
+	Active bool               `json:"active"`
+}
+
+type WebhookURI struct {
+	ID models.WebhookID `uri:"id" binding:"required"`
+}
+
+type WebhookDeliveryURI struct {
+	ID         models.WebhookID         `uri:"id" binding:"required"`
+	DeliveryID models.WebhookDeliveryID `uri:"delivery_id" binding:"required"`
+}
+
+type ListWebhookDeliveriesRequest struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+type WebhookItem struct {
+	ID                  models.WebhookID   `json:"id"`
+	URL                 string             `json:"url"`
+	Events              []models.EventType `json:"events"`
+	Active              bool               `json:"active"`
+	ConsecutiveFailures int                `json:"consecutive_failures"`
+	DisabledAt          *time.Time         `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time          `json:"created_at"`
+}
+
+// CreateWebhookResponse is the only response that carries the signing secret.
+type CreateWebhookResponse struct {
+	WebhookItem
+	Secret string `json:"secret"`
+}
+
+type WebhookDeliveryItem struct {
+	ID              models.WebhookDeliveryID  `json:"id"`
+	EventID         string                    `json:"event_id"`
+	EventType       models.EventType          `json:"event_type"`
+	Status          models.DeliveryStatus     `json:"status"`
+	Attempts        int                       `json:"attempts"`
+	NextAttemptAt   *time.Time                `json:"next_attempt_at,omitempty"`
+	LastAttemptAt   *time.Time                `json:"last_attempt_at,omitempty"`
+	RequestExcerpt  string                    `json:"request_excerpt"`
+	ResponseStatus  int                       `json:"response_status,omitempty"`
+	ResponseExcerpt string                    `json:"response_excerpt,omitempty"`
+	Error           string                    `json:"error,omitempty"`
+	RedeliveredFrom *models.WebhookDeliveryID `json:"redelivered_from,omitempty"`
+	CreatedAt       time.Time                 `json:"created_at"`
+}
@@ -3,8 +3,53 @@ package errors
 import "errors"
 
 var (
-	ErrTodoNotFound      = errors.New("todo not found")
-	ErrUserNotFound      = errors.New("user not found")
-	ErrUserAlreadyExists = errors.New("user already exists")
-	ErrInvalidIdentifier = errors.New("invalid identifier")
+	ErrTodoNotFound            = errors.New("todo not found")
+	ErrTodoTitleRequired       = errors.New("title is required")
+	ErrTodoTitleTooLong        = errors.New("title is too long")
+	ErrUserNotFound            = errors.New("user not found")
+	ErrUserAlreadyExists       = errors.New("user already exists")
+	ErrUserDisabled            = errors.New("user is disabled")
+	ErrInvalidRole             = errors.New("invalid role")
+	ErrPermissionDenied        = errors.New("permission denied")
+	ErrAdminSelfAction         = errors.New("admins can't disable, delete or change the role of themselves")
+	ErrInvalidIdentifier       = errors.New("invalid identifier")
+	ErrLoginLocked             = errors.New("too many failed logins, try again later")
+	ErrInvalidUnlockToken      = errors.New("invalid or expired unlock token")
+	ErrRateLimited             = errors.New("rate limit exceeded")
+	ErrInvalidRefreshToken     = errors.New("invalid refresh token")
+	ErrRefreshTokenReused      = errors.New("refresh token reused")
+	ErrInvalidResetToken       = errors.New("invalid or expired reset token")
+	ErrInvalidVerifyToken      = errors.New("invalid or expired verification token")
+	ErrEmailNotVerified        = errors.New("email not verified")
+	ErrInvalidMFAToken         = errors.New("invalid or expired mfa token")
+	ErrInvalidMFACode          = errors.New("invalid code")
+	ErrMFALocked               = errors.New("too many failed attempts, try again later")
+	ErrMFAUnavailable          = errors.New("two-factor authentication is not configured")
+	ErrTOTPNotEnabled          = errors.New("two-factor authentication is not enabled")
+	ErrTOTPAlreadyEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrPasskeyNotFound         = errors.New("passkey not found")
+	ErrPasskeyAlreadyExists    = errors.New("passkey already registered")
+	ErrInvalidPasskey          = errors.New("passkey verification failed")
+	ErrInvalidWebAuthnSession  = errors.New("invalid or expired webauthn session")
+	ErrUnknownOIDCProvider     = errors.New("unknown login provider")
+	ErrInvalidOIDCState        = errors.New("invalid or expired login state")
+	ErrOIDCLoginFailed         = errors.New("login with provider failed")
+	ErrOIDCEmailNotVerified    = errors.New("provider did not verify the email")
+	ErrOIDCNoAccount           = errors.New("no user with this email, register first")
+	ErrAccessTokenNotFound     = errors.New("access token not found")
+	ErrInvalidAccessToken      = errors.New("invalid access token")
+	ErrInvalidScope            = errors.New("invalid scope")
+	ErrInsufficientScope       = errors.New("token lacks the required scope")
+	ErrOAuthClientNotFound     = errors.New("oauth client not found")
+	ErrInvalidRedirectURI      = errors.New("invalid redirect uri")
+	ErrInvalidClient           = errors.New("invalid client credentials")
+	ErrInvalidGrant            = errors.New("invalid or expired grant")
+	ErrInvalidOAuthRequest     = errors.New("invalid or expired authorization request")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrListMemberNotFound      = errors.New("list member not found")
+	ErrShareWithOwner          = errors.New("lists can't be shared with their owner")
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL       = errors.New("invalid webhook url")
+	ErrInvalidWebhookEvent     = errors.New("invalid webhook event")
 )
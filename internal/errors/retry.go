@@ -0,0 +1,18 @@
+package errors
+
+import "time"
+
+// RetryError refuses a request until RetryAfter has passed. Err is the reason, so that
+// errors.Is still matches it.
+type RetryError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (r *RetryError) Error() string {
+	return r.Err.Error()
+}
+
+func (r *RetryError) Unwrap() error {
+	return r.Err
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+var errCiphertextTooShort = errors.New("ciphertext too short")
+
+// Cipher encrypts secrets stored at rest, such as TOTP secrets, with AES-256-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher takes a 32 byte key.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts plaintext. additionalData isn't encrypted, but has to be passed to Open
+// again; binding ciphertexts to their owner this way keeps them from being swapped
+// between rows.
+func (c *Cipher) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (c *Cipher) Open(ciphertext, additionalData []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, errCiphertextTooShort
+	}
+	return c.aead.Open(nil, ciphertext[:n], ciphertext[n:], additionalData)
+}
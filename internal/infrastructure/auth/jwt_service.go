@@ -10,46 +10,82 @@ import (
 type JWTClaims struct {
 	UserID models.UserID `json:"user_id"`
 	Email  string        `json:"email"`
+	// ReadOnly tokens only allow requests that don't change anything. They are issued
+	// to users who haven't verified their email when the policy requires it.
+	ReadOnly bool        `json:"read_only,omitempty"`
+	Role     models.Role `json:"role,omitempty"`
+	// Permissions are those of Role when the token was issued.
+	Permissions []models.Permission `json:"permissions,omitempty"`
+	// SessionID is the refresh token family of the login, so that the token stops being
+	// accepted once the login is logged out or revoked.
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type JWTService struct {
-	secret []byte
-	ttl    time.Duration
+	keys     *KeySet
+	issuer   string
+	audience string
+	ttl      time.Duration
+	parser   *jwt.Parser
 }
 
-func NewJWTService(secret string, ttl time.Duration) *JWTService {
+func NewJWTService(keys *KeySet, issuer, audience string, ttl time.Duration) *JWTService {
 	return &JWTService{
-		secret: []byte(secret),
-		ttl:    ttl,
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		ttl:      ttl,
+		parser:   jwt.NewParser(jwt.WithValidMethods(keys.methods())),
 	}
 }
 
-func (s *JWTService) GenerateToken(user models.User) (string, error) {
+// TTL is the lifetime of the access tokens.
+func (s *JWTService) TTL() time.Duration {
+	return s.ttl
+}
+
+// JWKS returns the public keys that tokens of this service are verified with.
+func (s *JWTService) JWKS() JWKS {
+	return s.keys.JWKS()
+}
+
+func (s *JWTService) GenerateToken(user models.User, readOnly bool, sessionID string) (string, error) {
+	now := time.Now()
 	claims := JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:      user.ID,
+		Email:       user.Email,
+		ReadOnly:    readOnly,
+		Role:        user.Role,
+		Permissions: user.Permissions,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.ttl)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    s.issuer,
+			Audience:  jwt.ClaimStrings{s.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secret)
+	return s.keys.sign(claims)
 }
 
 func (s *JWTService) ParseToken(tokenStr string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return s.secret, nil
-	})
+	token, err := s.parser.ParseWithClaims(tokenStr, &JWTClaims{}, s.keys.keyFunc)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	if !claims.VerifyIssuer(s.issuer, true) {
+		return nil, jwt.ErrTokenInvalidIssuer
+	}
+	if !claims.VerifyAudience(s.audience, true) {
+		return nil, jwt.ErrTokenInvalidAudience
 	}
 
-	return nil, jwt.ErrTokenInvalidClaims
+	return claims, nil
 }
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const minRSAKeyBits = 2048
+
+var (
+	errUnknownKey     = errors.New("unknown signing key")
+	errUnexpectedAlg  = errors.New("unexpected signing method")
+	errNoSigningKey   = errors.New("signing key can only verify")
+	errUnsupportedKey = errors.New("unsupported key type")
+)
+
+type jwtKey struct {
+	id     string
+	method jwt.SigningMethod
+	// private is nil for keys that only verify tokens.
+	private crypto.Signer
+	public  crypto.PublicKey
+	// secret is set instead of the key pair for HMAC keys, which are never published.
+	secret []byte
+}
+
+// KeySet holds the key that signs tokens and every key that tokens are verified with.
+// To rotate keys, first add the new key for verification on all instances, then make it
+// the signing key, and remove the old one once the tokens it signed have expired.
+type KeySet struct {
+	signing *jwtKey
+	keys    map[string]*jwtKey
+}
+
+// LoadKeySet reads PEM encoded RSA or Ed25519 keys. signingFile must hold a private
+// key; verificationFiles may hold private or public keys. Key IDs are the RFC 7638
+// thumbprints of the public keys, so every instance derives the same IDs.
+func LoadKeySet(signingFile string, verificationFiles []string) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*jwtKey)}
+
+	signing, err := loadKey(signingFile)
+	if err != nil {
+		return nil, err
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("%s: %w", signingFile, errNoSigningKey)
+	}
+	ks.signing = signing
+	ks.keys[signing.id] = signing
+
+	for _, file := range verificationFiles {
+		key, err := loadKey(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := ks.keys[key.id]; !ok {
+			ks.keys[key.id] = key
+		}
+	}
+
+	return ks, nil
+}
+
+// NewHMACKeySet returns a key set that signs with HS256. It is meant for development:
+// other services can't verify its tokens, as the secret isn't published.
+func NewHMACKeySet(secret string) *KeySet {
+	key := &jwtKey{id: "hs256", method: jwt.SigningMethodHS256, secret: []byte(secret)}
+	return &KeySet{signing: key, keys: map[string]*jwtKey{key.id: key}}
+}
+
+func loadKey(file string) (*jwtKey, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", file)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	key := &jwtKey{}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private = signer
+		key.public = signer.Public()
+	} else {
+		key.public = parsed
+	}
+
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%s: RSA keys must have at least %d bits", file, minRSAKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("%s: %w", file, errUnsupportedKey)
+	}
+
+	key.id, err = thumbprint(jwkOf(key))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return key, nil
+}
+
+// sign signs claims with the signing key and sets its ID as the kid header.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.id
+
+	if ks.signing.secret != nil {
+		return token.SignedString(ks.signing.secret)
+	}
+	return token.SignedString(ks.signing.private)
+}
+
+// keyFunc selects the verification key by kid and only accepts the algorithm of that
+// key, so that a token can't choose how it is verified.
+func (ks *KeySet) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, errUnknownKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errUnexpectedAlg
+	}
+
+	if key.secret != nil {
+		return key.secret, nil
+	}
+	return key.public, nil
+}
+
+func (ks *KeySet) methods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, key := range ks.keys {
+		if alg := key.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys. HMAC keys are left out.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		if key.secret != nil {
+			continue
+		}
+
+		jwk := jwkOf(key)
+		jwk.Kid = key.id
+		jwk.Use = "sig"
+		jwk.Alg = key.method.Alg()
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func jwkOf(key *jwtKey) JWK {
+	enc := base64.RawURLEncoding
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   enc.EncodeToString(pub.N.Bytes()),
+			E:   enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: enc.EncodeToString(pub)}
+	default:
+		return JWK{}
+	}
+}
+
+// thumbprint computes the RFC 7638 thumbprint from the required members of jwk, which
+// encoding/json writes in the lexicographic order the RFC asks for.
+func thumbprint(jwk JWK) (string, error) {
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", errUnsupportedKey
+	}
+
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
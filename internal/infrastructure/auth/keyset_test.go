@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+// writeKey writes the PKCS #8 private key, or the PKIX public key of key if public
+// is set, to a PEM file.
+func writeKey(t *testing.T, key crypto.Signer, public bool) string {
+	t.Helper()
+
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	file := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(file, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func newRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func loadTestKeySet(t *testing.T, signing string, verification ...string) *KeySet {
+	t.Helper()
+
+	ks, err := LoadKeySet(signing, verification)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	return ks
+}
+
+func testClaims() JWTClaims {
+	now := time.Now()
+	return JWTClaims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "test",
+			Audience:  jwt.ClaimStrings{"test"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+}
+
+// forge signs claims with method and key, and sets kid as the key ID.
+func forge(t *testing.T, method jwt.SigningMethod, key any, kid string) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, testClaims())
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed
+}
+
+func TestKeySetSignsWithKeyID(t *testing.T) {
+	for name, key := range map[string]crypto.Signer{
+		"RS256": newRSAKey(t, 2048),
+		"EdDSA": newEd25519Key(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ks := loadTestKeySet(t, writeKey(t, key, false))
+			svc := NewJWTService(ks, "test", "test", time.Hour)
+
+			token, err := svc.GenerateToken(models.User{ID: 1}, false, "")
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &JWTClaims{})
+			if err != nil {
+				t.Fatalf("parse header: %v", err)
+			}
+			if parsed.Method.Alg() != name || parsed.Header["kid"] != ks.signing.id {
+				t.Fatalf("header = %v, want alg %s and kid %s", parsed.Header, name, ks.signing.id)
+			}
+			if _, err := svc.ParseToken(token); err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			// The key ID is the thumbprint of the public key, so an instance that only
+			// has the public key derives the same one.
+			verifier := loadTestKeySet(t, writeKey(t, newEd25519Key(t), false), writeKey(t, key, true))
+			if _, ok := verifier.keys[ks.signing.id]; !ok {
+				t.Fatalf("public key has a different ID than %s", ks.signing.id)
+			}
+		})
+	}
+}
+
+func TestKeySetPinsAlgorithmAndKeyID(t *testing.T) {
+	rsaKey := newRSAKey(t, 2048)
+	edKey := newEd25519Key(t)
+	ks := loadTestKeySet(t, writeKey(t, rsaKey, false), writeKey(t, edKey, true))
+	svc := NewJWTService(ks, "test", "test", time.Hour)
+
+	rsaID := ks.signing.id
+	var edID string
+	for id := range ks.keys {
+		if id != rsaID {
+			edID = id
+		}
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		accept bool
+		// want is the error of rejected tokens, if it is one of the key set's.
+		want error
+	}{
+		{
+			name:   "signed with the RSA key",
+			token:  forge(t, jwt.SigningMethodRS256, rsaKey, rsaID),
+			accept: true,
+		},
+		{
+			name:   "signed with the Ed25519 verification key",
+			token:  forge(t, jwt.SigningMethodEdDSA, edKey, edID),
+			accept: true,
+		},
+		{
+			name:  "no kid",
+			token: forge(t, jwt.SigningMethodRS256, rsaKey, ""),
+			want:  errUnknownKey,
+		},
+		{
+			name:  "unknown kid",
+			token: forge(t, jwt.SigningMethodRS256, newRSAKey(t, 2048), "unknown"),
+			want:  errUnknownKey,
+		},
+		{
+			// The kid of the RSA key doesn't let another algorithm verify with it.
+			name:  "RSA kid with PS256",
+			token: forge(t, jwt.SigningMethodPS256, rsaKey, rsaID),
+		},
+		{
+			name:  "RSA kid signed with the Ed25519 key",
+			token: forge(t, jwt.SigningMethodEdDSA, edKey, rsaID),
+			want:  errUnexpectedAlg,
+		},
+		{
+			// The public key is known to everyone, so accepting it as an HMAC secret
+			// would let anyone sign tokens.
+			name:  "HS256 with the public key as secret",
+			token: forge(t, jwt.SigningMethodHS256, publicDER, rsaID),
+		},
+		{
+			name:  "alg none",
+			token: forge(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, rsaID),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.ParseToken(tt.token)
+			switch {
+			case tt.accept:
+				if err != nil {
+					t.Fatalf("ParseToken: %v", err)
+				}
+			case err == nil:
+				t.Fatal("ParseToken accepted the token")
+			case tt.want != nil && !errors.Is(err, tt.want):
+				t.Fatalf("ParseToken err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadKeySetRejectsWeakOrPublicSigningKeys(t *testing.T) {
+	if _, err := LoadKeySet(writeKey(t, newEd25519Key(t), true), nil); !errors.Is(err, errNoSigningKey) {
+		t.Fatalf("public signing key: err = %v, want errNoSigningKey", err)
+	}
+	if _, err := LoadKeySet(writeKey(t, newRSAKey(t, 1024), false), nil); err == nil {
+		t.Fatal("1024-bit RSA key accepted")
+	}
+}
+
+func TestJWKSLeavesOutHMACKeys(t *testing.T) {
+	if keys := NewHMACKeySet("test-secret").JWKS().Keys; len(keys) != 0 {
+		t.Fatalf("JWKS = %v, want no keys", keys)
+	}
+
+	ks := loadTestKeySet(t, writeKey(t, newEd25519Key(t), false))
+	keys := ks.JWKS().Keys
+	if len(keys) != 1 || keys[0].Kid != ks.signing.id || keys[0].Alg != "EdDSA" || keys[0].Use != "sig" {
+		t.Fatalf("JWKS = %+v, want the signing key", keys)
+	}
+}
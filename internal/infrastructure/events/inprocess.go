@@ -0,0 +1,42 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/events"
+)
+
+// InProcessPublisher fans events out synchronously to the publishers subscribed to it.
+// Publish fails if any subscriber fails, so the outbox relay retries the event and every
+// subscriber must tolerate seeing it more than once.
+type InProcessPublisher struct {
+	mu          sync.RWMutex
+	subscribers []events.EventPublisher
+}
+
+func NewInProcessPublisher() *InProcessPublisher {
+	return &InProcessPublisher{}
+}
+
+func (p *InProcessPublisher) Subscribe(sub events.EventPublisher) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.subscribers = append(p.subscribers, sub)
+}
+
+func (p *InProcessPublisher) Publish(ctx context.Context, event models.Event) error {
+	p.mu.RLock()
+	subs := p.subscribers
+	p.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subs {
+		errs = append(errs, sub.Publish(ctx, event))
+	}
+
+	return errors.Join(errs...)
+}
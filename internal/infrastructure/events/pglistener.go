@@ -0,0 +1,167 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/events"
+	"go.uber.org/zap"
+)
+
+const (
+	listenerMinReconnect = 100 * time.Millisecond
+	listenerMaxReconnect = 30 * time.Second
+	listenerPingInterval = 90 * time.Second
+	listenerResyncBatch  = 500
+)
+
+// EventLoader reads published events back from the outbox.
+type EventLoader interface {
+	GetEventBySequence(ctx context.Context, seq int64) (models.Event, error)
+	ListPublishedEventsAfter(ctx context.Context, seq int64, limit int) ([]models.Event, error)
+}
+
+// PGListener receives the events published by PGNotifyPublisher on any instance and
+// hands them to a local sink. It uses a dedicated connection that lib/pq re-establishes
+// with backoff; after a reconnect the events published in the meantime are reloaded
+// from the outbox, since notifications sent while disconnected are lost.
+type PGListener struct {
+	dsn     string
+	channel string
+	loader  EventLoader
+	sink    events.EventPublisher
+	logger  *zap.Logger
+
+	lastSeq int64
+}
+
+func NewPGListener(dsn, channel string, loader EventLoader, sink events.EventPublisher, logger *zap.Logger) *PGListener {
+	return &PGListener{dsn: dsn, channel: channel, loader: loader, sink: sink, logger: logger}
+}
+
+// Run listens until ctx is cancelled. Failing to connect or to start listening is
+// retried rather than returned, so that the listener outlives database restarts.
+func (l *PGListener) Run(ctx context.Context) error {
+	log := l.logger.With(zap.String("channel", l.channel))
+
+	listener := pq.NewListener(l.dsn, listenerMinReconnect, listenerMaxReconnect,
+		func(ev pq.ListenerEventType, err error) {
+			switch ev {
+			case pq.ListenerEventDisconnected:
+				log.Warn("event listener disconnected", zap.Error(err))
+			case pq.ListenerEventReconnected:
+				log.Info("event listener reconnected")
+			case pq.ListenerEventConnectionAttemptFailed:
+				log.Warn("event listener connection attempt failed", zap.Error(err))
+			}
+		})
+	defer listener.Close()
+
+	if !l.listen(ctx, log, listener) {
+		return nil
+	}
+
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			// lib/pq sends nil after re-establishing the connection.
+			if n == nil {
+				l.resync(ctx, log)
+				continue
+			}
+			l.handle(ctx, log, n.Extra)
+		case <-ping.C:
+			go func() {
+				_ = listener.Ping()
+			}()
+		}
+	}
+}
+
+// listen starts listening on the channel, retrying with backoff like lib/pq does for
+// the connection. It reports false if ctx is cancelled first. Listen blocks while the
+// connection is down, so the listener is closed on cancellation to release it.
+func (l *PGListener) listen(ctx context.Context, log *zap.Logger, listener *pq.Listener) bool {
+	stop := context.AfterFunc(ctx, func() { _ = listener.Close() })
+	defer stop()
+
+	delay := listenerMinReconnect
+	for {
+		err := listener.Listen(l.channel)
+		if err == nil || errors.Is(err, pq.ErrChannelAlreadyOpen) {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		log.Warn("failed to listen for events", zap.Duration("retry_in", delay), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, listenerMaxReconnect)
+	}
+}
+
+func (l *PGListener) handle(ctx context.Context, log *zap.Logger, payload string) {
+	var n Notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		log.Error("invalid event notification", zap.Error(err))
+		return
+	}
+
+	event := n.Event()
+	if n.IDOnly() {
+		var err error
+		event, err = l.loader.GetEventBySequence(ctx, n.Sequence)
+		if err != nil {
+			log.Error("failed to load notified event", zap.Int64("sequence", n.Sequence), zap.Error(err))
+			return
+		}
+	}
+
+	l.deliver(ctx, log, event)
+}
+
+func (l *PGListener) resync(ctx context.Context, log *zap.Logger) {
+	if l.lastSeq == 0 {
+		return
+	}
+
+	for ctx.Err() == nil {
+		batch, err := l.loader.ListPublishedEventsAfter(ctx, l.lastSeq, listenerResyncBatch)
+		if err != nil {
+			log.Error("failed to reload events after reconnect", zap.Error(err))
+			return
+		}
+
+		for _, event := range batch {
+			l.deliver(ctx, log, event)
+		}
+
+		if len(batch) < listenerResyncBatch {
+			return
+		}
+	}
+}
+
+func (l *PGListener) deliver(ctx context.Context, log *zap.Logger, event models.Event) {
+	if event.Sequence > l.lastSeq {
+		l.lastSeq = event.Sequence
+	}
+
+	if err := l.sink.Publish(ctx, event); err != nil {
+		log.Error("failed to deliver event", zap.Int64("sequence", event.Sequence), zap.Error(err))
+	}
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+// maxNotifyPayload stays below the 8000 byte limit Postgres puts on NOTIFY payloads.
+const maxNotifyPayload = 7900
+
+// Notification is the JSON payload sent on the NOTIFY channel. When the full event
+// doesn't fit, only the sequence is sent and listeners load the event from the outbox.
+type Notification struct {
+	Sequence      int64            `json:"sequence"`
+	ID            string           `json:"id,omitempty"`
+	Type          models.EventType `json:"type,omitempty"`
+	UserID        models.UserID    `json:"user_id,omitempty"`
+	AggregateType string           `json:"aggregate_type,omitempty"`
+	AggregateID   int64            `json:"aggregate_id,omitempty"`
+	Payload       json.RawMessage  `json:"payload,omitempty"`
+	OccurredAt    time.Time        `json:"occurred_at,omitzero"`
+}
+
+func (n Notification) IDOnly() bool {
+	return n.ID == ""
+}
+
+func NewNotification(event models.Event) Notification {
+	return Notification{
+		Sequence:      event.Sequence,
+		ID:            event.ID,
+		Type:          event.Type,
+		UserID:        event.UserID,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Payload:       event.Payload,
+		OccurredAt:    event.OccurredAt,
+	}
+}
+
+func (n Notification) Event() models.Event {
+	return models.Event{
+		ID:            n.ID,
+		Sequence:      n.Sequence,
+		Type:          n.Type,
+		UserID:        n.UserID,
+		AggregateType: n.AggregateType,
+		AggregateID:   n.AggregateID,
+		Payload:       n.Payload,
+		OccurredAt:    n.OccurredAt,
+	}
+}
+
+// PGNotifyPublisher publishes events with pg_notify so that every process LISTENing on
+// the channel receives them, whichever instance relayed them.
+type PGNotifyPublisher struct {
+	db      *sql.DB
+	channel string
+}
+
+func NewPGNotifyPublisher(db *sql.DB, channel string) *PGNotifyPublisher {
+	return &PGNotifyPublisher{db: db, channel: channel}
+}
+
+func (p *PGNotifyPublisher) Publish(ctx context.Context, event models.Event) error {
+	payload, err := json.Marshal(NewNotification(event))
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxNotifyPayload {
+		payload, err = json.Marshal(Notification{Sequence: event.Sequence})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = p.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", p.channel, string(payload))
+	return err
+}
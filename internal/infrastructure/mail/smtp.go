@@ -0,0 +1,78 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/ports/mailer"
+)
+
+var errInvalidHeader = errors.New("invalid mail header")
+
+// SMTPMailer sends mail through a relay, upgrading to TLS when the relay supports it.
+type SMTPMailer struct {
+	addr string
+	from mail.Address
+	auth smtp.Auth
+}
+
+// NewSMTPMailer returns a mailer for the relay at addr (host:port). username may be
+// empty for relays that don't require authentication.
+func NewSMTPMailer(addr, from, username, password string) (*SMTPMailer, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	m := &SMTPMailer{addr: addr, from: *sender}
+	if username != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg mailer.Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return errInvalidHeader
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	// net/smtp has no context support, so the send is abandoned rather than aborted
+	// when ctx is done.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.addr, m.auth, m.from.Address, []string{to.Address}, b.Bytes())
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+const accessTokenColumns = "id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at"
+
+type AccessTokenRepo struct {
+	db *sql.DB
+}
+
+func NewAccessTokenRepo(db *sql.DB) *AccessTokenRepo {
+	return &AccessTokenRepo{db: db}
+}
+
+func scanAccessToken(row rowScanner) (models.AccessToken, error) {
+	var t models.AccessToken
+	var scopes []string
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.TokenHash, pq.Array(&scopes), &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt)
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+
+	t.Scopes = toScopes(scopes)
+	return t, nil
+}
+
+func toScopes(values []string) []models.Scope {
+	scopes := make([]models.Scope, len(values))
+	for i, v := range values {
+		scopes[i] = models.Scope(v)
+	}
+	return scopes
+}
+
+func scopeArray(scopes []models.Scope) any {
+	values := make([]string, len(scopes))
+	for i, s := range scopes {
+		values[i] = string(s)
+	}
+	return pq.Array(values)
+}
+
+func (r *AccessTokenRepo) CreateAccessToken(ctx context.Context, t models.AccessToken) (models.AccessToken, error) {
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO access_tokens (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		t.UserID, t.Name, t.TokenHash, scopeArray(t.Scopes), t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+
+	return t, nil
+}
+
+func (r *AccessTokenRepo) ListAccessTokens(ctx context.Context, userID models.UserID) ([]models.AccessToken, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+accessTokenColumns+" FROM access_tokens WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]models.AccessToken, 0)
+	for rows.Next() {
+		t, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (r *AccessTokenRepo) DeleteAccessToken(ctx context.Context, userID models.UserID, id models.AccessTokenID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM access_tokens WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res, e.ErrAccessTokenNotFound)
+}
+
+func (r *AccessTokenRepo) GetAccessTokenByHash(ctx context.Context, tokenHash string) (models.AccessToken, error) {
+	t, err := scanAccessToken(r.db.QueryRowContext(ctx,
+		"SELECT "+accessTokenColumns+" FROM access_tokens WHERE token_hash = $1", tokenHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AccessToken{}, e.ErrInvalidAccessToken
+		}
+		return models.AccessToken{}, err
+	}
+
+	return t, nil
+}
+
+func (r *AccessTokenRepo) TouchAccessToken(ctx context.Context, id models.AccessTokenID) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE access_tokens SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, id)
+	return err
+}
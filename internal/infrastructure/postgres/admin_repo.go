@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type AdminRepo struct {
+	db *sql.DB
+}
+
+func NewAdminRepo(db *sql.DB) *AdminRepo {
+	return &AdminRepo{db: db}
+}
+
+func (r *AdminRepo) Stats(ctx context.Context) (models.SystemStats, error) {
+	var stats models.SystemStats
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(email_verified_at), COUNT(disabled_at), (SELECT COUNT(*) FROM to_do)
+		FROM users`).Scan(&stats.Users, &stats.VerifiedUsers, &stats.DisabledUsers, &stats.Todos)
+	if err != nil {
+		return models.SystemStats{}, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT role, COUNT(*) FROM users GROUP BY role")
+	if err != nil {
+		return models.SystemStats{}, err
+	}
+	defer rows.Close()
+
+	stats.UsersByRole = make(map[models.Role]int64)
+	for rows.Next() {
+		var role models.Role
+		var n int64
+		if err := rows.Scan(&role, &n); err != nil {
+			return models.SystemStats{}, err
+		}
+		stats.UsersByRole[role] = n
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.SystemStats{}, err
+	}
+
+	return stats, nil
+}
+
+func (r *AdminRepo) RecordAuditEntry(ctx context.Context, entry models.AuditEntry) error {
+	details := entry.Details
+	if details == nil {
+		details = []byte("{}")
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO audit_log (actor_id, action, target_user_id, details) VALUES ($1, $2, $3, $4)",
+		entry.ActorID, entry.Action, entry.TargetUserID, []byte(details))
+	return err
+}
+
+func (r *AdminRepo) ListAuditEntries(ctx context.Context, limit, offset int) ([]models.AuditEntry, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, actor_id, action, target_user_id, details, created_at FROM audit_log
+		ORDER BY id DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var entry models.AuditEntry
+		var details []byte
+		err := rows.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.TargetUserID, &details, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.Details = details
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
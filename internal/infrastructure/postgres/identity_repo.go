@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type IdentityRepo struct {
+	db *sql.DB
+}
+
+func NewIdentityRepo(db *sql.DB) *IdentityRepo {
+	return &IdentityRepo{db: db}
+}
+
+func (r *IdentityRepo) GetIdentity(ctx context.Context, provider, subject string) (models.UserIdentity, error) {
+	var i models.UserIdentity
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, user_id, provider, subject, email, created_at FROM user_identities
+		WHERE provider = $1 AND subject = $2`, provider, subject).
+		Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserIdentity{}, e.ErrUserNotFound
+		}
+		return models.UserIdentity{}, err
+	}
+
+	return i, nil
+}
+
+func (r *IdentityRepo) LinkIdentity(ctx context.Context, i models.UserIdentity) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (provider, subject) DO NOTHING`, i.UserID, i.Provider, i.Subject, i.Email)
+	return err
+}
+
+func (r *IdentityRepo) CreateOIDCState(ctx context.Context, s models.OIDCLoginState) error {
+	// Logins that never came back are swept here rather than by a background job.
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM oidc_login_states WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO oidc_login_states (state_hash, provider, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`, s.StateHash, s.Provider, s.CodeVerifier, s.Nonce, s.ExpiresAt)
+	return err
+}
+
+func (r *IdentityRepo) TakeOIDCState(ctx context.Context, stateHash, provider string) (models.OIDCLoginState, error) {
+	var s models.OIDCLoginState
+	err := r.db.QueryRowContext(ctx,
+		`DELETE FROM oidc_login_states WHERE state_hash = $1 AND provider = $2 AND expires_at > NOW()
+		RETURNING state_hash, provider, code_verifier, nonce, expires_at`, stateHash, provider).
+		Scan(&s.StateHash, &s.Provider, &s.CodeVerifier, &s.Nonce, &s.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OIDCLoginState{}, e.ErrInvalidOIDCState
+		}
+		return models.OIDCLoginState{}, err
+	}
+
+	return s, nil
+}
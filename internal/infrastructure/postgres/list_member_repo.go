@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type ListMemberRepo struct {
+	db *sql.DB
+}
+
+func NewListMemberRepo(db *sql.DB) *ListMemberRepo {
+	return &ListMemberRepo{db: db}
+}
+
+func (r *ListMemberRepo) AddListMember(ctx context.Context, ownerID, userID models.UserID) (models.ListMember, error) {
+	m := models.ListMember{OwnerID: ownerID, UserID: userID}
+	err := r.db.QueryRowContext(ctx,
+		// The no-op update makes RETURNING report existing memberships too.
+		`INSERT INTO list_members (owner_id, user_id) VALUES ($1, $2)
+		ON CONFLICT (owner_id, user_id) DO UPDATE SET owner_id = EXCLUDED.owner_id
+		RETURNING created_at`,
+		ownerID, userID).Scan(&m.CreatedAt)
+	if err != nil {
+		return models.ListMember{}, err
+	}
+
+	return m, nil
+}
+
+func (r *ListMemberRepo) RemoveListMember(ctx context.Context, ownerID, userID models.UserID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM list_members WHERE owner_id = $1 AND user_id = $2", ownerID, userID)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res, e.ErrListMemberNotFound)
+}
+
+func (r *ListMemberRepo) ListListMembers(ctx context.Context, ownerID models.UserID) ([]models.ListMember, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT m.owner_id, m.user_id, u.email, m.created_at
+		FROM list_members m JOIN users u ON u.id = m.user_id
+		WHERE m.owner_id = $1 ORDER BY m.created_at, m.user_id`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]models.ListMember, 0)
+	for rows.Next() {
+		var m models.ListMember
+		if err := rows.Scan(&m.OwnerID, &m.UserID, &m.Email, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (r *ListMemberRepo) ListSharedLists(ctx context.Context, userID models.UserID) ([]models.SharedList, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT m.owner_id, u.email, m.created_at
+		FROM list_members m JOIN users u ON u.id = m.owner_id
+		WHERE m.user_id = $1 ORDER BY m.created_at, m.owner_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := make([]models.SharedList, 0)
+	for rows.Next() {
+		var l models.SharedList
+		if err := rows.Scan(&l.OwnerID, &l.OwnerEmail, &l.SharedAt); err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+func (r *ListMemberRepo) IsListMember(ctx context.Context, ownerID, userID models.UserID) (bool, error) {
+	var ok bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM list_members WHERE owner_id = $1 AND user_id = $2)", ownerID, userID).Scan(&ok)
+	return ok, err
+}
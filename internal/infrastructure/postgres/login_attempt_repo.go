@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type LoginAttemptRepo struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepo(db *sql.DB) *LoginAttemptRepo {
+	return &LoginAttemptRepo{db: db}
+}
+
+func (r *LoginAttemptRepo) GetLoginAttempts(ctx context.Context, keys []string, window time.Duration) ([]models.LoginAttempts, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT key, failures, last_failed_at FROM login_attempts
+		WHERE key = ANY($1) AND last_failed_at > NOW() - make_interval(secs => $2)`,
+		pq.Array(keys), window.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []models.LoginAttempts
+	for rows.Next() {
+		var a models.LoginAttempts
+		if err := rows.Scan(&a.Key, &a.Failures, &a.LastFailedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// ReserveLoginAttempt counts the failure and checks the limit in one statement, so that
+// concurrent attempts can't get past it. It also removes the rows of other keys that
+// have gone quiet, which would count from zero anyway.
+func (r *LoginAttemptRepo) ReserveLoginAttempt(ctx context.Context, key string, limit int, window time.Duration) (models.LoginAttempts, bool, error) {
+	a := models.LoginAttempts{Key: key}
+	err := r.db.QueryRowContext(ctx,
+		`WITH swept AS (
+			DELETE FROM login_attempts
+			WHERE last_failed_at <= NOW() - make_interval(secs => $2) AND key <> $1
+		)
+		INSERT INTO login_attempts (key, failures, last_failed_at) VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failed_at <= NOW() - make_interval(secs => $2) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failed_at = NOW()
+		WHERE login_attempts.failures < $3
+			OR login_attempts.last_failed_at <= NOW() - make_interval(secs => $2)
+		RETURNING failures, last_failed_at`,
+		key, window.Seconds(), limit).Scan(&a.Failures, &a.LastFailedAt)
+	if err == nil {
+		return a, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.LoginAttempts{}, false, err
+	}
+
+	// The key is locked. If it was unlocked since, the caller waits a lockout anyway.
+	err = r.db.QueryRowContext(ctx,
+		"SELECT failures, last_failed_at FROM login_attempts WHERE key = $1", key).
+		Scan(&a.Failures, &a.LastFailedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.LoginAttempts{Key: key, Failures: limit, LastFailedAt: time.Now()}, false, nil
+	}
+	if err != nil {
+		return models.LoginAttempts{}, false, err
+	}
+	return a, false, nil
+}
+
+func (r *LoginAttemptRepo) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE login_attempts SET failures = failures - 1 WHERE key = $1 AND failures > 0", key)
+	return err
+}
+
+func (r *LoginAttemptRepo) ResetLoginAttempts(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type MFARepo struct {
+	db *sql.DB
+}
+
+func NewMFARepo(db *sql.DB) *MFARepo {
+	return &MFARepo{db: db}
+}
+
+func (r *MFARepo) GetTOTP(ctx context.Context, userID models.UserID) (models.TOTPCredential, error) {
+	var cred models.TOTPCredential
+	err := r.db.QueryRowContext(ctx,
+		`SELECT user_id, secret, confirmed_at, last_step, failed_attempts, last_failed_at, created_at
+		FROM totp_credentials WHERE user_id = $1`, userID).
+		Scan(&cred.UserID, &cred.Secret, &cred.ConfirmedAt, &cred.LastStep,
+			&cred.FailedAttempts, &cred.LastFailedAt, &cred.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTPCredential{}, e.ErrTOTPNotEnabled
+		}
+		return models.TOTPCredential{}, err
+	}
+
+	return cred, nil
+}
+
+func (r *MFARepo) SaveTOTP(ctx context.Context, cred models.TOTPCredential) error {
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO totp_credentials (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = NOW(),
+			last_step = 0, failed_attempts = 0, last_failed_at = NULL
+		WHERE totp_credentials.confirmed_at IS NULL`, cred.UserID, cred.Secret)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res, e.ErrTOTPAlreadyEnabled)
+}
+
+func (r *MFARepo) ConfirmTOTP(ctx context.Context, userID models.UserID, step int64, codeHashes []string) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE totp_credentials SET confirmed_at = NOW(), last_step = $2
+			WHERE user_id = $1 AND confirmed_at IS NULL`, userID, step)
+		if err != nil {
+			return err
+		}
+		if err := expectRow(res, e.ErrTOTPNotEnabled); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+			return err
+		}
+
+		for _, hash := range codeHashes {
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *MFARepo) DeleteTOTP(ctx context.Context, userID models.UserID) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM totp_credentials WHERE user_id = $1", userID)
+		if err != nil {
+			return err
+		}
+		if err := expectRow(res, e.ErrTOTPNotEnabled); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+		return err
+	})
+}
+
+func (r *MFARepo) UseTOTPStep(ctx context.Context, userID models.UserID, step int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE totp_credentials SET last_step = $2, failed_attempts = 0, last_failed_at = NULL
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_step < $2`, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	return affected(res)
+}
+
+func (r *MFARepo) UseRecoveryCode(ctx context.Context, userID models.UserID, codeHash string) (bool, error) {
+	var used bool
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE recovery_codes SET used_at = NOW()
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash)
+		if err != nil {
+			return err
+		}
+		if used, err = affected(res); err != nil || !used {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE totp_credentials SET failed_attempts = 0, last_failed_at = NULL WHERE user_id = $1", userID)
+		return err
+	})
+	return used, err
+}
+
+func (r *MFARepo) ReserveMFAAttempt(ctx context.Context, userID models.UserID, maxFailures int, lockout time.Duration) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE totp_credentials SET failed_attempts = failed_attempts + 1, last_failed_at = NOW()
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL
+			AND (failed_attempts < $2 OR last_failed_at <= NOW() - make_interval(secs => $3))`,
+		userID, maxFailures, lockout.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	return affected(res)
+}
+
+// UseMFAChallenge also removes the IDs of tokens that have expired.
+func (r *MFARepo) UseMFAChallenge(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`WITH swept AS (
+			DELETE FROM used_mfa_challenges WHERE expires_at <= NOW()
+		)
+		INSERT INTO used_mfa_challenges (id, expires_at) VALUES ($1, $2)
+		ON CONFLICT (id) DO NOTHING`, id, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	return affected(res)
+}
+
+func affected(res sql.Result) (bool, error) {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// expectRow returns notFound if the statement didn't affect any row.
+func expectRow(res sql.Result, notFound error) error {
+	ok, err := affected(res)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return notFound
+	}
+	return nil
+}
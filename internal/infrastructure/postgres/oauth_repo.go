@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+const (
+	oauthClientColumns = "id, user_id, name, redirect_uris, COALESCE(secret_hash, ''), created_at"
+	oauthTokenColumns  = `id, client_id, user_id, scopes, access_token_hash, refresh_token_hash, access_expires_at,
+	refresh_expires_at, revoked_at, created_at`
+)
+
+type OAuthRepo struct {
+	db *sql.DB
+}
+
+func NewOAuthRepo(db *sql.DB) *OAuthRepo {
+	return &OAuthRepo{db: db}
+}
+
+func scanOAuthClient(row rowScanner) (models.OAuthClient, error) {
+	var c models.OAuthClient
+	err := row.Scan(&c.ID, &c.UserID, &c.Name, pq.Array(&c.RedirectURIs), &c.SecretHash, &c.CreatedAt)
+	return c, err
+}
+
+func scanOAuthToken(row rowScanner) (models.OAuthToken, error) {
+	var t models.OAuthToken
+	var scopes []string
+	err := row.Scan(&t.ID, &t.ClientID, &t.UserID, pq.Array(&scopes), &t.AccessTokenHash, &t.RefreshTokenHash,
+		&t.AccessExpiresAt, &t.RefreshExpiresAt, &t.RevokedAt, &t.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OAuthToken{}, e.ErrInvalidGrant
+		}
+		return models.OAuthToken{}, err
+	}
+
+	t.Scopes = toScopes(scopes)
+	return t, nil
+}
+
+func (r *OAuthRepo) CreateClient(ctx context.Context, c models.OAuthClient) (models.OAuthClient, error) {
+	var secretHash *string
+	if c.SecretHash != "" {
+		secretHash = &c.SecretHash
+	}
+
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO oauth_clients (id, user_id, name, redirect_uris, secret_hash) VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`,
+		c.ID, c.UserID, c.Name, pq.Array(c.RedirectURIs), secretHash).Scan(&c.CreatedAt)
+	if err != nil {
+		return models.OAuthClient{}, err
+	}
+
+	return c, nil
+}
+
+func (r *OAuthRepo) GetClient(ctx context.Context, id string) (models.OAuthClient, error) {
+	c, err := scanOAuthClient(r.db.QueryRowContext(ctx,
+		"SELECT "+oauthClientColumns+" FROM oauth_clients WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OAuthClient{}, e.ErrOAuthClientNotFound
+		}
+		return models.OAuthClient{}, err
+	}
+
+	return c, nil
+}
+
+func (r *OAuthRepo) ListClients(ctx context.Context, userID models.UserID) ([]models.OAuthClient, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+oauthClientColumns+" FROM oauth_clients WHERE user_id = $1 ORDER BY created_at, id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := make([]models.OAuthClient, 0)
+	for rows.Next() {
+		c, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+func (r *OAuthRepo) DeleteClient(ctx context.Context, userID models.UserID, id string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM oauth_clients WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res, e.ErrOAuthClientNotFound)
+}
+
+func (r *OAuthRepo) CreateAuthorizationCode(ctx context.Context, c models.OAuthAuthorizationCode) error {
+	// Codes that were never exchanged are swept here rather than by a background job.
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM oauth_authorization_codes WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, code_challenge, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		c.CodeHash, c.ClientID, c.UserID, c.RedirectURI, c.CodeChallenge, scopeArray(c.Scopes), c.ExpiresAt)
+	return err
+}
+
+func (r *OAuthRepo) TakeAuthorizationCode(ctx context.Context, codeHash string) (models.OAuthAuthorizationCode, error) {
+	var c models.OAuthAuthorizationCode
+	var scopes []string
+	err := r.db.QueryRowContext(ctx,
+		`DELETE FROM oauth_authorization_codes WHERE code_hash = $1 AND expires_at > NOW()
+		RETURNING code_hash, client_id, user_id, redirect_uri, code_challenge, scopes, expires_at`, codeHash).
+		Scan(&c.CodeHash, &c.ClientID, &c.UserID, &c.RedirectURI, &c.CodeChallenge, pq.Array(&scopes), &c.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OAuthAuthorizationCode{}, e.ErrInvalidGrant
+		}
+		return models.OAuthAuthorizationCode{}, err
+	}
+
+	c.Scopes = toScopes(scopes)
+	return c, nil
+}
+
+func (r *OAuthRepo) CreateToken(ctx context.Context, t models.OAuthToken) error {
+	// Tokens that can no longer be refreshed are swept here rather than by a background
+	// job.
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM oauth_tokens WHERE refresh_expires_at < NOW()"); err != nil {
+		return err
+	}
+
+	return insertOAuthToken(ctx, r.db, t)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertOAuthToken(ctx context.Context, db execer, t models.OAuthToken) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO oauth_tokens (client_id, user_id, scopes, access_token_hash, refresh_token_hash,
+			access_expires_at, refresh_expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		t.ClientID, t.UserID, scopeArray(t.Scopes), t.AccessTokenHash, t.RefreshTokenHash,
+		t.AccessExpiresAt, t.RefreshExpiresAt)
+	return err
+}
+
+func (r *OAuthRepo) GetTokenByAccessHash(ctx context.Context, hash string) (models.OAuthToken, error) {
+	return scanOAuthToken(r.db.QueryRowContext(ctx,
+		"SELECT "+oauthTokenColumns+" FROM oauth_tokens WHERE access_token_hash = $1", hash))
+}
+
+func (r *OAuthRepo) GetTokenByRefreshHash(ctx context.Context, hash string) (models.OAuthToken, error) {
+	return scanOAuthToken(r.db.QueryRowContext(ctx,
+		"SELECT "+oauthTokenColumns+" FROM oauth_tokens WHERE refresh_token_hash = $1", hash))
+}
+
+func (r *OAuthRepo) RotateToken(ctx context.Context, id models.OAuthTokenID, next models.OAuthToken) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			"UPDATE oauth_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+		if err != nil {
+			return err
+		}
+		if err := expectRow(res, e.ErrInvalidGrant); err != nil {
+			return err
+		}
+
+		return insertOAuthToken(ctx, tx, next)
+	})
+}
+
+// revokeUserOAuthGrants revokes the tokens of every OAuth client of the user, and
+// deletes the codes they haven't exchanged yet.
+func revokeUserOAuthGrants(ctx context.Context, tx *sql.Tx, userID models.UserID) error {
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM oauth_authorization_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx,
+		"UPDATE oauth_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
+
+func (r *OAuthRepo) RevokeToken(ctx context.Context, id models.OAuthTokenID) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE oauth_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	return err
+}
@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+// outboxRelayLockKey is the advisory lock held by the relay. Only one
+// relay processes the outbox at a time, which keeps events in outbox order across
+// replicas.
+const outboxRelayLockKey = 727001
+
+const outboxColumns = `id, event_id, event_type, aggregate_type, aggregate_id, user_id, payload, occurred_at`
+
+type OutboxRepo struct {
+	db *sql.DB
+}
+
+func NewOutboxRepo(db *sql.DB) *OutboxRepo {
+	return &OutboxRepo{db: db}
+}
+
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertOutboxEvent records event in the same transaction as the change it describes,
+// so the event exists if and only if the change is committed.
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, event models.Event) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO outbox (event_id, event_type, aggregate_type, aggregate_id, user_id, payload, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		event.ID, event.Type, event.AggregateType, event.AggregateID, event.UserID, []byte(event.Payload), event.OccurredAt)
+	return err
+}
+
+func scanOutboxEvent(row rowScanner) (models.Event, error) {
+	var event models.Event
+	err := row.Scan(&event.Sequence, &event.ID, &event.Type, &event.AggregateType, &event.AggregateID,
+		&event.UserID, &event.Payload, &event.OccurredAt)
+	return event, err
+}
+
+// ProcessBatch hands up to limit unpublished events to fn in outbox order and marks the
+// ones fn accepted as published. Once fn fails for an aggregate, its later events are
+// left for the next batch so that they are never published out of order. If another
+// relay holds the outbox lock, ProcessBatch returns immediately.
+//
+// The lock is a session lock on a dedicated connection rather than a transaction lock:
+// the batch is read and the published events marked in separate statements, so no
+// transaction or row lock is held while fn talks to the publisher.
+func (r *OutboxRepo) ProcessBatch(ctx context.Context, limit int, fn func(models.Event) error) (int, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", outboxRelayLockKey).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+	defer func() {
+		_, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", outboxRelayLockKey)
+		if err != nil {
+			// Closing the session is the only other way to release the lock, so the
+			// connection must not go back to the pool.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	batch, err := queryOutboxEvents(ctx, conn,
+		"SELECT "+outboxColumns+" FROM outbox WHERE published_at IS NULL ORDER BY id LIMIT $1", limit)
+	if err != nil {
+		return 0, err
+	}
+
+	type aggregateKey struct {
+		typ string
+		id  int64
+	}
+	blocked := make(map[aggregateKey]bool)
+	var published []int64
+	var errs []error
+	for _, event := range batch {
+		key := aggregateKey{event.AggregateType, event.AggregateID}
+		if blocked[key] {
+			continue
+		}
+		if err := fn(event); err != nil {
+			blocked[key] = true
+			errs = append(errs, err)
+			continue
+		}
+		published = append(published, event.Sequence)
+	}
+
+	if len(published) > 0 {
+		// A relay that fails here publishes these events again, which at least once
+		// delivery allows.
+		if _, err := conn.ExecContext(ctx,
+			"UPDATE outbox SET published_at = NOW() WHERE id = ANY($1)", pq.Array(published)); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(published), errors.Join(errs...)
+}
+
+// DeletePublishedBefore deletes the events published more than age ago and returns how
+// many were deleted.
+func (r *OutboxRepo) DeletePublishedBefore(ctx context.Context, age time.Duration) (int64, error) {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM outbox WHERE published_at < NOW() - make_interval(secs => $1)", age.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (r *OutboxRepo) GetEventBySequence(ctx context.Context, seq int64) (models.Event, error) {
+	return scanOutboxEvent(r.db.QueryRowContext(ctx, "SELECT "+outboxColumns+" FROM outbox WHERE id = $1", seq))
+}
+
+func (r *OutboxRepo) ListPublishedEventsAfter(ctx context.Context, seq int64, limit int) ([]models.Event, error) {
+	return queryOutboxEvents(ctx, r.db,
+		"SELECT "+outboxColumns+" FROM outbox WHERE id > $1 AND published_at IS NOT NULL ORDER BY id LIMIT $2",
+		seq, limit)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func queryOutboxEvents(ctx context.Context, q queryer, query string, args ...any) ([]models.Event, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]models.Event, 0)
+	for rows.Next() {
+		event, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+func testOutboxEvent(id string, aggregateID int64) models.Event {
+	return models.Event{
+		ID:            id,
+		Type:          models.EventTodoUpdated,
+		UserID:        1,
+		AggregateType: models.AggregateTodo,
+		AggregateID:   aggregateID,
+		Payload:       []byte(`{}`),
+		OccurredAt:    time.Now(),
+	}
+}
+
+func insertTestEvents(t *testing.T, db *sql.DB, events ...models.Event) {
+	t.Helper()
+
+	err := withTx(context.Background(), db, func(tx *sql.Tx) error {
+		for _, event := range events {
+			if err := insertOutboxEvent(context.Background(), tx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessBatchLockIsExclusive(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	insertTestEvents(t, db, testOutboxEvent("e1", 1), testOutboxEvent("e2", 2))
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	type result struct {
+		n   int
+		err error
+	}
+	first := make(chan result, 1)
+	go func() {
+		n, err := NewOutboxRepo(db).ProcessBatch(ctx, 10, func(models.Event) error {
+			once.Do(func() { close(entered) })
+			<-release
+			return nil
+		})
+		first <- result{n, err}
+	}()
+
+	select {
+	case <-entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first worker didn't start its batch")
+	}
+
+	// The second worker runs while the first one is inside its batch, like a relay on
+	// another replica.
+	n, err := NewOutboxRepo(db).ProcessBatch(ctx, 10, func(event models.Event) error {
+		t.Errorf("second worker got %s while the first held the lock", event.ID)
+		return nil
+	})
+	if err != nil || n != 0 {
+		t.Fatalf("second worker = %d, %v, want 0, nil", n, err)
+	}
+
+	close(release)
+	res := <-first
+	if res.err != nil || res.n != 2 {
+		t.Fatalf("first worker = %d, %v, want 2, nil", res.n, res.err)
+	}
+
+	// The lock was released, so the next batch is processed.
+	insertTestEvents(t, db, testOutboxEvent("e3", 3))
+	var got []string
+	n, err = NewOutboxRepo(db).ProcessBatch(ctx, 10, func(event models.Event) error {
+		got = append(got, event.ID)
+		return nil
+	})
+	if err != nil || n != 1 || fmt.Sprint(got) != "[e3]" {
+		t.Fatalf("next batch = %d, %v, %v, want 1, nil, [e3]", n, err, got)
+	}
+}
+
+func TestProcessBatchHoldsBackBlockedAggregate(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewOutboxRepo(db)
+	ctx := context.Background()
+	insertTestEvents(t, db,
+		testOutboxEvent("a1", 1),
+		testOutboxEvent("b1", 2),
+		testOutboxEvent("a2", 1),
+		testOutboxEvent("b2", 2),
+	)
+
+	errPublish := errors.New("publish failed")
+	var got []string
+	n, err := repo.ProcessBatch(ctx, 10, func(event models.Event) error {
+		got = append(got, event.ID)
+		if event.ID == "a1" {
+			return errPublish
+		}
+		return nil
+	})
+	if !errors.Is(err, errPublish) {
+		t.Fatalf("err = %v, want errPublish", err)
+	}
+	if n != 2 {
+		t.Fatalf("published %d, want 2", n)
+	}
+	// a2 must not be handed over before a1 is published.
+	if fmt.Sprint(got) != "[a1 b1 b2]" {
+		t.Fatalf("handed over %v, want [a1 b1 b2]", got)
+	}
+
+	got = nil
+	n, err = repo.ProcessBatch(ctx, 10, func(event models.Event) error {
+		got = append(got, event.ID)
+		return nil
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("retry = %d, %v, want 2, nil", n, err)
+	}
+	if fmt.Sprint(got) != "[a1 a2]" {
+		t.Fatalf("retry handed over %v, want [a1 a2]", got)
+	}
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+const passkeyColumns = `id, user_id, name, credential_id, public_key, attestation_type, transports, aaguid,
+	flags, sign_count, clone_warning, created_at, last_used_at`
+
+type PasskeyRepo struct {
+	db *sql.DB
+}
+
+func NewPasskeyRepo(db *sql.DB) *PasskeyRepo {
+	return &PasskeyRepo{db: db}
+}
+
+func scanPasskey(row rowScanner) (models.Passkey, error) {
+	var p models.Passkey
+	err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.CredentialID, &p.PublicKey, &p.AttestationType,
+		pq.Array(&p.Transports), &p.AAGUID, &p.Flags, &p.SignCount, &p.CloneWarning, &p.CreatedAt, &p.LastUsedAt)
+	return p, err
+}
+
+func (r *PasskeyRepo) CreatePasskey(ctx context.Context, p models.Passkey) (models.Passkey, error) {
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO passkeys (user_id, name, credential_id, public_key, attestation_type, transports, aaguid, flags, sign_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`,
+		p.UserID, p.Name, p.CredentialID, p.PublicKey, p.AttestationType, pq.Array(p.Transports), p.AAGUID,
+		p.Flags, p.SignCount).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return models.Passkey{}, e.ErrPasskeyAlreadyExists
+		}
+		return models.Passkey{}, err
+	}
+
+	return p, nil
+}
+
+func (r *PasskeyRepo) ListPasskeys(ctx context.Context, userID models.UserID) ([]models.Passkey, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+passkeyColumns+" FROM passkeys WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := make([]models.Passkey, 0)
+	for rows.Next() {
+		p, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return passkeys, nil
+}
+
+func (r *PasskeyRepo) DeletePasskey(ctx context.Context, userID models.UserID, id models.PasskeyID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM passkeys WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res, e.ErrPasskeyNotFound)
+}
+
+func (r *PasskeyRepo) UsePasskey(ctx context.Context, id models.PasskeyID, signCount uint32) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE passkeys SET sign_count = $2, last_used_at = NOW()
+		WHERE id = $1 AND NOT clone_warning AND (sign_count < $2 OR $2 = 0)`, id, signCount)
+	if err != nil {
+		return false, err
+	}
+
+	return affected(res)
+}
+
+func (r *PasskeyRepo) FlagClonedPasskey(ctx context.Context, id models.PasskeyID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE passkeys SET clone_warning = TRUE WHERE id = $1", id)
+	return err
+}
+
+func (r *PasskeyRepo) CreateWebAuthnSession(ctx context.Context, s models.WebAuthnSession) error {
+	// Sessions that were never answered are swept here rather than by a background job.
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM webauthn_sessions WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO webauthn_sessions (session_hash, ceremony, user_id, data, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`, s.SessionHash, s.Ceremony, s.UserID, s.Data, s.ExpiresAt)
+	return err
+}
+
+func (r *PasskeyRepo) TakeWebAuthnSession(ctx context.Context, sessionHash string, ceremony models.WebAuthnCeremony) (models.WebAuthnSession, error) {
+	var s models.WebAuthnSession
+	err := r.db.QueryRowContext(ctx,
+		`DELETE FROM webauthn_sessions WHERE session_hash = $1 AND ceremony = $2 AND expires_at > NOW()
+		RETURNING session_hash, ceremony, user_id, data, expires_at`, sessionHash, ceremony).
+		Scan(&s.SessionHash, &s.Ceremony, &s.UserID, &s.Data, &s.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WebAuthnSession{}, e.ErrInvalidWebAuthnSession
+		}
+		return models.WebAuthnSession{}, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type PasswordResetRepo struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepo(db *sql.DB) *PasswordResetRepo {
+	return &PasswordResetRepo{db: db}
+}
+
+func (r *PasswordResetRepo) CreatePasswordResetToken(ctx context.Context, token models.PasswordResetToken) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		token.UserID, token.TokenHash, token.ExpiresAt)
+	return err
+}
+
+func (r *PasswordResetRepo) GetPasswordResetToken(ctx context.Context, tokenHash string) (models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, user_id, token_hash, expires_at, created_at FROM password_reset_tokens
+		WHERE token_hash = $1 AND expires_at > NOW()`, tokenHash).
+		Scan(&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PasswordResetToken{}, e.ErrInvalidResetToken
+		}
+		return models.PasswordResetToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *PasswordResetRepo) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (models.UserID, error) {
+	var userID models.UserID
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		// Deleting the token is what makes it single-use: of two concurrent resets with
+		// the same token, only one gets the row back.
+		err := tx.QueryRowContext(ctx,
+			`DELETE FROM password_reset_tokens WHERE token_hash = $1 AND expires_at > NOW()
+			RETURNING user_id`, tokenHash).Scan(&userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return e.ErrInvalidResetToken
+			}
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE users SET password_hash = $1, password_changed_at = NOW() WHERE id = $2",
+			passwordHash, userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM password_reset_tokens WHERE user_id = $1", userID); err != nil {
+			return err
+		}
+
+		if err := revokeUserRefreshTokens(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		return revokeUserOAuthGrants(ctx, tx, userID)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/ratelimit"
+)
+
+// The buckets of the tests hold three requests, and refill one per testIncrement.
+const (
+	testIncrement = 200 * time.Millisecond
+	testLimit     = 3 * testIncrement
+	// testSlack covers the round trips of the steps.
+	testSlack = 50 * time.Millisecond
+)
+
+func TestPostgresRateLimitStore(t *testing.T) {
+	type step struct {
+		wait time.Duration
+		// retry waits for the retry time of the refused request before.
+		retry bool
+		sweep bool
+		want  bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"burst", []step{
+			{want: true}, {want: true}, {want: true}, {want: false}, {want: false},
+		}},
+		{"refill", []step{
+			{want: true}, {want: true}, {want: true}, {want: false},
+			{wait: testIncrement + testSlack, want: true}, {want: false},
+		}},
+		{"retry after refused", []step{
+			{want: true}, {want: true}, {want: true}, {want: false},
+			{retry: true, want: true}, {want: false},
+		}},
+		{"expired and swept", []step{
+			{want: true}, {want: true}, {want: true},
+			{wait: testLimit + testSlack, sweep: true, want: true}, {want: true}, {want: true}, {want: false},
+		}},
+	}
+
+	db := openTestDB(t)
+	s := ratelimit.NewPostgresStore(db)
+	ctx := context.Background()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var retryAfter time.Duration
+			for i, step := range tt.steps {
+				time.Sleep(step.wait)
+				if step.retry {
+					time.Sleep(retryAfter)
+				}
+				if step.sweep {
+					if err := s.DeleteExpired(ctx); err != nil {
+						t.Fatalf("step %d: delete expired: %v", i, err)
+					}
+					var n int
+					if err := db.QueryRow("SELECT COUNT(*) FROM rate_limits WHERE key = $1", tt.name).Scan(&n); err != nil {
+						t.Fatal(err)
+					}
+					if n != 0 {
+						t.Fatalf("step %d: key left after the bucket refilled", i)
+					}
+				}
+
+				tat, now, ok, err := s.Advance(ctx, tt.name, testIncrement, testLimit)
+				if err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				if ok != step.want {
+					t.Fatalf("step %d: allowed %v, want %v", i, ok, step.want)
+				}
+				if tat.Sub(now) > testLimit {
+					t.Fatalf("step %d: TAT is %v ahead, past the limit", i, tat.Sub(now))
+				}
+				if !ok {
+					retryAfter = tat.Add(testIncrement).Sub(now.Add(testLimit))
+					if retryAfter <= 0 || retryAfter > testIncrement {
+						t.Fatalf("step %d: retry after %v, want within %v", i, retryAfter, testIncrement)
+					}
+				}
+			}
+		})
+	}
+}
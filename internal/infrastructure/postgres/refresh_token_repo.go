@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type RefreshTokenRepo struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepo(db *sql.DB) *RefreshTokenRepo {
+	return &RefreshTokenRepo{db: db}
+}
+
+const insertRefreshToken = `INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+	VALUES ($1, $2, $3, $4)`
+
+func (r *RefreshTokenRepo) CreateRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	_, err := r.db.ExecContext(ctx, insertRefreshToken,
+		token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	return err
+}
+
+func (r *RefreshTokenRepo) GetRefreshTokenByHash(ctx context.Context, hash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, user_id, family_id, token_hash, expires_at, created_at, rotated_at, revoked_at
+		FROM refresh_tokens WHERE token_hash = $1`, hash).
+		Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash,
+			&token.ExpiresAt, &token.CreatedAt, &token.RotatedAt, &token.RevokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, e.ErrInvalidRefreshToken
+		}
+		return models.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *RefreshTokenRepo) RotateRefreshToken(ctx context.Context, id models.RefreshTokenID, next models.RefreshToken) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE refresh_tokens SET rotated_at = NOW()
+			WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL`, id)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return e.ErrRefreshTokenReused
+		}
+
+		_, err = tx.ExecContext(ctx, insertRefreshToken,
+			next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt)
+		return err
+	})
+}
+
+func (r *RefreshTokenRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL", familyID)
+	return err
+}
+
+func (r *RefreshTokenRepo) RefreshTokenFamilyRevoked(ctx context.Context, familyID string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE family_id = $1 AND revoked_at IS NOT NULL)", familyID).
+		Scan(&revoked)
+	return revoked, err
+}
+
+// revokeUserRefreshTokens ends every session of the user.
+func revokeUserRefreshTokens(ctx context.Context, tx *sql.Tx, userID models.UserID) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
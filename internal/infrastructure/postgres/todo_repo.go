@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/lib/pq"
 	e "github.com/mrxacker/go-to-do-app/internal/errors"
 	"github.com/mrxacker/go-to-do-app/internal/models"
 )
@@ -18,18 +19,64 @@ func NewTodoRepo(db *sql.DB) *TodoRepo {
 }
 
 func (r *TodoRepo) CreateTodo(ctx context.Context, todo models.ToDo) (models.ToDoID, error) {
-	var id models.ToDoID
-	err := r.db.QueryRowContext(ctx,
-		"INSERT INTO to_do (user_id, title, description) VALUES ($1, $2, $3) RETURNING id",
-		todo.UserID, todo.Title, todo.Description).Scan(&id)
-	return id, err
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			"INSERT INTO to_do (user_id, title, description) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at",
+			todo.UserID, todo.Title, todo.Description).Scan(&todo.ID, &todo.CreatedAt, &todo.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return insertTodoEvent(ctx, tx, models.EventTodoCreated, todo)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return todo.ID, nil
+}
+
+// CreateTodos inserts todos in a single transaction, so either all of them are created
+// or none.
+func (r *TodoRepo) CreateTodos(ctx context.Context, todos []models.ToDo) ([]models.ToDoID, error) {
+	ids := make([]models.ToDoID, len(todos))
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		for i, todo := range todos {
+			err := tx.QueryRowContext(ctx,
+				"INSERT INTO to_do (user_id, title, description, completed) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at",
+				todo.UserID, todo.Title, todo.Description, todo.Completed).Scan(&todo.ID, &todo.CreatedAt, &todo.UpdatedAt)
+			if err != nil {
+				return err
+			}
+
+			if err := insertTodoEvent(ctx, tx, models.EventTodoCreated, todo); err != nil {
+				return err
+			}
+			ids[i] = todo.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func insertTodoEvent(ctx context.Context, tx *sql.Tx, t models.EventType, todo models.ToDo) error {
+	event, err := models.NewTodoEvent(t, todo)
+	if err != nil {
+		return err
+	}
+
+	return insertOutboxEvent(ctx, tx, event)
 }
 
 func (r *TodoRepo) GetTodoByID(ctx context.Context, id models.ToDoID) (models.ToDo, error) {
 	var todo models.ToDo
 	err := r.db.QueryRowContext(ctx,
-		"SELECT id, title, description, created_at, updated_at FROM to_do WHERE id = $1",
-		id).Scan(&todo.ID, &todo.Title, &todo.Description, &todo.CreatedAt, &todo.UpdatedAt)
+		"SELECT id, user_id, title, description, completed, created_at, updated_at FROM to_do WHERE id = $1",
+		id).Scan(&todo.ID, &todo.UserID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.ToDo{}, e.ErrTodoNotFound
@@ -39,6 +86,32 @@ func (r *TodoRepo) GetTodoByID(ctx context.Context, id models.ToDoID) (models.To
 	return todo, nil
 }
 
+// GetTodosByIDs returns the todos that exist among ids, in no particular order.
+func (r *TodoRepo) GetTodosByIDs(ctx context.Context, ids []models.ToDoID) ([]models.ToDo, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, user_id, title, description, completed, created_at, updated_at FROM to_do WHERE id = ANY($1)",
+		pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := make([]models.ToDo, 0, len(ids))
+	for rows.Next() {
+		var todo models.ToDo
+		if err := rows.Scan(&todo.ID, &todo.UserID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
 func (r *TodoRepo) ListTodos(ctx context.Context, userID models.UserID, limit, offset int) ([]models.ToDo, error) {
 	if limit <= 0 {
 		limit = 20
@@ -47,7 +120,7 @@ func (r *TodoRepo) ListTodos(ctx context.Context, userID models.UserID, limit, o
 		offset = 0
 	}
 
-	rows, err := r.db.QueryContext(ctx, "SELECT id, title, description FROM to_do WHERE user_id = $1 ORDER BY id LIMIT $2 OFFSET $3", userID, limit, offset)
+	rows, err := r.db.QueryContext(ctx, "SELECT id, user_id, title, description, completed, created_at, updated_at FROM to_do WHERE user_id = $1 ORDER BY id LIMIT $2 OFFSET $3", userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +129,7 @@ func (r *TodoRepo) ListTodos(ctx context.Context, userID models.UserID, limit, o
 	todos := make([]models.ToDo, 0)
 	for rows.Next() {
 		var todo models.ToDo
-		if err := rows.Scan(&todo.ID, &todo.Title, &todo.Description); err != nil {
+		if err := rows.Scan(&todo.ID, &todo.UserID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
 			return nil, err
 		}
 		todos = append(todos, todo)
@@ -70,39 +143,48 @@ func (r *TodoRepo) ListTodos(ctx context.Context, userID models.UserID, limit, o
 }
 
 func (r *TodoRepo) DeleteTodoByID(ctx context.Context, id models.ToDoID) error {
-	result, err := r.db.ExecContext(ctx, "DELETE FROM to_do WHERE id = $1", id)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return e.ErrTodoNotFound
-	}
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var todo models.ToDo
+		err := tx.QueryRowContext(ctx,
+			"DELETE FROM to_do WHERE id = $1 RETURNING id, user_id, title, description, completed, created_at, updated_at",
+			id).Scan(&todo.ID, &todo.UserID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return e.ErrTodoNotFound
+			}
+			return err
+		}
 
-	return nil
+		return insertTodoEvent(ctx, tx, models.EventTodoDeleted, todo)
+	})
 }
 
+// UpdateTodo emits todo.completed instead of todo.updated when the update marks a
+// pending todo as completed.
 func (r *TodoRepo) UpdateTodo(ctx context.Context, todo models.ToDo) error {
-	result, err := r.db.ExecContext(ctx,
-		"UPDATE to_do SET title = $1, description = $2, updated_at = NOW() WHERE id = $3",
-		todo.Title, todo.Description, todo.ID)
-	if err != nil {
-		return err
-	}
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var wasCompleted bool
+		err := tx.QueryRowContext(ctx, "SELECT completed FROM to_do WHERE id = $1 FOR UPDATE", todo.ID).Scan(&wasCompleted)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return e.ErrTodoNotFound
+			}
+			return err
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
+		err = tx.QueryRowContext(ctx,
+			`UPDATE to_do SET title = $1, description = $2, completed = $3, updated_at = NOW() WHERE id = $4
+			RETURNING user_id, created_at, updated_at`,
+			todo.Title, todo.Description, todo.Completed, todo.ID).Scan(&todo.UserID, &todo.CreatedAt, &todo.UpdatedAt)
+		if err != nil {
+			return err
+		}
 
-	if rowsAffected == 0 {
-		return e.ErrTodoNotFound
-	}
+		eventType := models.EventTodoUpdated
+		if todo.Completed && !wasCompleted {
+			eventType = models.EventTodoCompleted
+		}
 
-	return nil
+		return insertTodoEvent(ctx, tx, eventType, todo)
+	})
 }
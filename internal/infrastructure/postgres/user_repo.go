@@ -4,11 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	e "github.com/mrxacker/go-to-do-app/internal/errors"
 	"github.com/mrxacker/go-to-do-app/internal/models"
 )
 
+// uniqueViolation is the Postgres error code for a duplicate key.
+const uniqueViolation = "23505"
+
 type UserRepo struct {
 	db *sql.DB
 }
@@ -18,20 +24,47 @@ func NewUserRepo(db *sql.DB) *UserRepo {
 }
 
 func (r *UserRepo) CreateUser(ctx context.Context, user models.User) (models.UserID, error) {
-	var id models.UserID
-	err := r.db.QueryRowContext(ctx,
-		"INSERT INTO users (username, email, password_hash) VALUES ($1, $2, $3) RETURNING id",
-		user.Username, user.Email, user.PasswordHash).Scan(&id)
-	return id, err
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			"INSERT INTO users (username, email, password_hash) VALUES ($1, $2, $3) RETURNING id",
+			user.Username, user.Email, user.PasswordHash).Scan(&user.ID)
+		if err != nil {
+			return err
+		}
+
+		return insertUserEvent(ctx, tx, models.EventUserCreated, user)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return user.ID, nil
 }
 
-func (r *UserRepo) getUser(ctx context.Context, query string, arg any) (models.User, error) {
+// baseUserSelect selects the columns scanUser reads, with the permissions of the
+// user's role.
+const baseUserSelect = `SELECT id, username, email, password_hash, email_verified_at, role,
+	ARRAY(SELECT permission FROM role_permissions WHERE role_permissions.role = users.role ORDER BY permission),
+	disabled_at, password_changed_at, created_at FROM users`
 
+func scanUser(row rowScanner) (models.User, error) {
 	var user models.User
-	const baseUserSelect = `SELECT id, username, email, password_hash FROM users`
-	err := r.db.QueryRowContext(ctx, baseUserSelect+" "+query, arg).
-		Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash)
+	var permissions []string
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.EmailVerifiedAt, &user.Role,
+		pq.Array(&permissions), &user.DisabledAt, &user.PasswordChangedAt, &user.CreatedAt)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user.Permissions = make([]models.Permission, len(permissions))
+	for i, p := range permissions {
+		user.Permissions[i] = models.Permission(p)
+	}
+	return user, nil
+}
 
+func (r *UserRepo) getUser(ctx context.Context, query string, arg any) (models.User, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx, baseUserSelect+" "+query, arg))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, e.ErrUserNotFound
@@ -46,6 +79,55 @@ func (r *UserRepo) GetUserByID(ctx context.Context, id models.UserID) (models.Us
 	return r.getUser(ctx, "WHERE id = $1", id)
 }
 
+// GetUsersByIDs returns the users that exist among ids, in no particular order.
+func (r *UserRepo) GetUsersByIDs(ctx context.Context, ids []models.UserID) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, baseUserSelect+" WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	return collectUsers(rows, len(ids))
+}
+
+func (r *UserRepo) ListUsers(ctx context.Context, query string, limit, offset int) ([]models.User, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	// The query is matched literally, so its LIKE wildcards are escaped.
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+	rows, err := r.db.QueryContext(ctx,
+		baseUserSelect+" WHERE username ILIKE $1 OR email ILIKE $1 ORDER BY id LIMIT $2 OFFSET $3",
+		pattern, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectUsers(rows, limit)
+}
+
+func collectUsers(rows *sql.Rows, capacity int) ([]models.User, error) {
+	defer rows.Close()
+
+	users := make([]models.User, 0, capacity)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
 func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
 	return r.getUser(ctx, "WHERE email = $1", email)
 }
@@ -53,3 +135,176 @@ func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (models.Use
 func (r *UserRepo) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
 	return r.getUser(ctx, "WHERE username = $1", username)
 }
+
+func (r *UserRepo) UpdateEmail(ctx context.Context, id models.UserID, email string) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		user := models.User{ID: id, Email: email}
+		err := tx.QueryRowContext(ctx,
+			`UPDATE users SET email = $2, email_verified_at = NULL, email_verification_sent_at = NULL,
+			updated_at = NOW() WHERE id = $1 RETURNING username`, id, email).Scan(&user.Username)
+		if err != nil {
+			var pqErr *pq.Error
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return e.ErrUserNotFound
+			case errors.As(err, &pqErr) && pqErr.Code == uniqueViolation:
+				return e.ErrUserAlreadyExists
+			}
+			return err
+		}
+
+		return insertUserEvent(ctx, tx, models.EventUserEmailChanged, user)
+	})
+}
+
+func (r *UserRepo) MarkEmailVerified(ctx context.Context, id models.UserID, email string) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = $1 AND email = $2`, id, email)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return e.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) ClaimVerificationEmail(ctx context.Context, id models.UserID, interval time.Duration) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE users SET email_verification_sent_at = NOW()
+		WHERE id = $1 AND email_verified_at IS NULL
+		AND (email_verification_sent_at IS NULL
+			OR email_verification_sent_at <= NOW() - $2 * INTERVAL '1 second')`,
+		id, interval.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetDisabled emits user.disabled or user.enabled if it changes whether the user is
+// disabled.
+func (r *UserRepo) SetDisabled(ctx context.Context, id models.UserID, disabled bool) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		user := models.User{ID: id}
+		var wasDisabled bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT username, email, role, disabled_at IS NOT NULL FROM users WHERE id = $1 FOR UPDATE",
+			id).Scan(&user.Username, &user.Email, &user.Role, &wasDisabled)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return e.ErrUserNotFound
+			}
+			return err
+		}
+		if wasDisabled == disabled {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE users SET disabled_at = CASE WHEN $2 THEN NOW() END, updated_at = NOW() WHERE id = $1",
+			id, disabled)
+		if err != nil {
+			return err
+		}
+
+		t := models.EventUserEnabled
+		if disabled {
+			t = models.EventUserDisabled
+		}
+		return insertUserEvent(ctx, tx, t, user)
+	})
+}
+
+// SetRole emits user.role_changed if it changes the user's role.
+func (r *UserRepo) SetRole(ctx context.Context, id models.UserID, role models.Role) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		user := models.User{ID: id}
+		err := tx.QueryRowContext(ctx,
+			"SELECT username, email, role FROM users WHERE id = $1 FOR UPDATE",
+			id).Scan(&user.Username, &user.Email, &user.Role)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return e.ErrUserNotFound
+			}
+			return err
+		}
+		if user.Role == role {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET role = $2, updated_at = NOW() WHERE id = $1", id, role); err != nil {
+			return err
+		}
+
+		user.Role = role
+		return insertUserEvent(ctx, tx, models.EventUserRoleChanged, user)
+	})
+}
+
+// DeleteUser deletes the user with their todos, which don't reference users, emitting
+// todo.deleted for each of them and then user.deleted. The rest of their data is
+// deleted by cascades.
+func (r *UserRepo) DeleteUser(ctx context.Context, id models.UserID) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			"DELETE FROM to_do WHERE user_id = $1 RETURNING id, user_id, title, description, completed, created_at, updated_at",
+			id)
+		if err != nil {
+			return err
+		}
+		var todos []models.ToDo
+		for rows.Next() {
+			var todo models.ToDo
+			if err := rows.Scan(&todo.ID, &todo.UserID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			todos = append(todos, todo)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// The transaction can't be used while the rows are open.
+		for _, todo := range todos {
+			if err := insertTodoEvent(ctx, tx, models.EventTodoDeleted, todo); err != nil {
+				return err
+			}
+		}
+
+		user := models.User{ID: id}
+		err = tx.QueryRowContext(ctx,
+			"DELETE FROM users WHERE id = $1 RETURNING username, email, role",
+			id).Scan(&user.Username, &user.Email, &user.Role)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return e.ErrUserNotFound
+			}
+			return err
+		}
+
+		return insertUserEvent(ctx, tx, models.EventUserDeleted, user)
+	})
+}
+
+func insertUserEvent(ctx context.Context, tx *sql.Tx, t models.EventType, user models.User) error {
+	event, err := models.NewUserEvent(t, user)
+	if err != nil {
+		return err
+	}
+
+	return insertOutboxEvent(ctx, tx, event)
+}
@@ -0,0 +1,276 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+const (
+	webhookColumns  = `id, user_id, url, secret, events, active, consecutive_failures, disabled_at, created_at, updated_at`
+	deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at,
+		COALESCE(response_status, 0), COALESCE(response_body, ''), COALESCE(error, ''), redelivered_from, created_at`
+)
+
+type WebhookRepo struct {
+	db *sql.DB
+}
+
+func NewWebhookRepo(db *sql.DB) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var hook models.Webhook
+	var events []string
+	err := row.Scan(&hook.ID, &hook.UserID, &hook.URL, &hook.Secret, pq.Array(&events), &hook.Active,
+		&hook.ConsecutiveFailures, &hook.DisabledAt, &hook.CreatedAt, &hook.UpdatedAt)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	hook.Events = make([]models.EventType, len(events))
+	for i, ev := range events {
+		hook.Events[i] = models.EventType(ev)
+	}
+
+	return hook, nil
+}
+
+func scanDelivery(row rowScanner) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastAttemptAt, &d.ResponseStatus, &d.ResponseBody, &d.Error, &d.RedeliveredFrom, &d.CreatedAt)
+	return d, err
+}
+
+func eventTypesArray(events []models.EventType) any {
+	s := make([]string, len(events))
+	for i, ev := range events {
+		s[i] = string(ev)
+	}
+	return pq.Array(s)
+}
+
+func (r *WebhookRepo) CreateWebhook(ctx context.Context, hook models.Webhook) (models.WebhookID, error) {
+	var id models.WebhookID
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO webhooks (user_id, url, secret, events) VALUES ($1, $2, $3, $4) RETURNING id",
+		hook.UserID, hook.URL, hook.Secret, eventTypesArray(hook.Events)).Scan(&id)
+	return id, err
+}
+
+func (r *WebhookRepo) GetWebhookByID(ctx context.Context, id models.WebhookID) (models.Webhook, error) {
+	hook, err := scanWebhook(r.db.QueryRowContext(ctx,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Webhook{}, e.ErrWebhookNotFound
+		}
+		return models.Webhook{}, err
+	}
+	return hook, nil
+}
+
+func (r *WebhookRepo) listWebhooks(ctx context.Context, query string, args ...any) ([]models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+func (r *WebhookRepo) ListWebhooks(ctx context.Context, userID models.UserID) ([]models.Webhook, error) {
+	return r.listWebhooks(ctx, "WHERE user_id = $1 ORDER BY id", userID)
+}
+
+func (r *WebhookRepo) ListActiveWebhooks(ctx context.Context, userID models.UserID, eventType models.EventType) ([]models.Webhook, error) {
+	return r.listWebhooks(ctx, "WHERE user_id = $1 AND active AND $2 = ANY(events) ORDER BY id", userID, string(eventType))
+}
+
+func (r *WebhookRepo) UpdateWebhook(ctx context.Context, hook models.Webhook) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE webhooks SET url = $1, events = $2, active = $3,
+			consecutive_failures = CASE WHEN $3 THEN 0 ELSE consecutive_failures END,
+			disabled_at = CASE WHEN $3 THEN NULL ELSE COALESCE(disabled_at, NOW()) END,
+			updated_at = NOW()
+		WHERE id = $4`,
+		hook.URL, eventTypesArray(hook.Events), hook.Active, hook.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return e.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) DeleteWebhookByID(ctx context.Context, id models.WebhookID) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return e.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) RecordWebhookResult(ctx context.Context, id models.WebhookID, success bool, disableAfter int) (bool, error) {
+	if success {
+		_, err := r.db.ExecContext(ctx,
+			"UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures > 0", id)
+		return false, err
+	}
+
+	var disabled bool
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE webhooks w SET consecutive_failures = w.consecutive_failures + 1,
+			active = w.active AND w.consecutive_failures + 1 < $2,
+			disabled_at = CASE WHEN w.active AND w.consecutive_failures + 1 >= $2 THEN NOW() ELSE w.disabled_at END,
+			updated_at = NOW()
+		FROM webhooks old
+		WHERE w.id = $1 AND old.id = w.id
+		RETURNING old.active AND NOT w.active`,
+		id, disableAfter).Scan(&disabled)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, e.ErrWebhookNotFound
+		}
+		return false, err
+	}
+
+	return disabled, nil
+}
+
+// CreateDelivery returns a zero ID without error when the event was already queued
+// for the webhook, which happens when the outbox relay delivers an event twice.
+func (r *WebhookRepo) CreateDelivery(ctx context.Context, d models.WebhookDelivery) (models.WebhookDeliveryID, error) {
+	var id models.WebhookDeliveryID
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, redelivered_from)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (webhook_id, event_id) WHERE redelivered_from IS NULL DO NOTHING
+		RETURNING id`,
+		d.WebhookID, d.EventID, d.EventType, []byte(d.Payload), d.RedeliveredFrom).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+func (r *WebhookRepo) GetDeliveryByID(ctx context.Context, id models.WebhookDeliveryID) (models.WebhookDelivery, error) {
+	d, err := scanDelivery(r.db.QueryRowContext(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WebhookDelivery{}, e.ErrWebhookDeliveryNotFound
+		}
+		return models.WebhookDelivery{}, err
+	}
+	return d, nil
+}
+
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, webhookID models.WebhookID, limit, offset int) ([]models.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3",
+		webhookID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectDeliveries(rows)
+}
+
+func (r *WebhookRepo) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`UPDATE webhook_deliveries SET next_attempt_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.active
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return collectDeliveries(rows)
+}
+
+func (r *WebhookRepo) UpdateDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, last_attempt_at = $4,
+			response_status = NULLIF($5, 0), response_body = NULLIF($6, ''), error = NULLIF($7, '')
+		WHERE id = $8`,
+		d.Status, d.Attempts, d.NextAttemptAt, d.LastAttemptAt, d.ResponseStatus, d.ResponseBody, d.Error, d.ID)
+	return err
+}
+
+func collectDeliveries(rows *sql.Rows) ([]models.WebhookDelivery, error) {
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the rate limits of this instance only. Each replica allows the full
+// rate, so use PostgresStore when running more than one.
+type MemoryStore struct {
+	mu   sync.Mutex
+	tats map[string]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tats: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Advance(_ context.Context, key string, increment, limit time.Duration) (time.Time, time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	tat := s.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+
+	next := tat.Add(increment)
+	if next.After(now.Add(limit)) {
+		return tat, now, false, nil
+	}
+	s.tats[key] = next
+	return next, now, true, nil
+}
+
+func (s *MemoryStore) DeleteExpired(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, tat := range s.tats {
+		if tat.Before(now) {
+			delete(s.tats, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// The buckets of the tests hold three requests, and refill one per testIncrement.
+const (
+	testIncrement = 100 * time.Millisecond
+	testLimit     = 3 * testIncrement
+	// testSlack covers the time the steps take.
+	testSlack = 30 * time.Millisecond
+)
+
+// storeStep waits, sweeps the store if asked to, and takes a request.
+type storeStep struct {
+	wait time.Duration
+	// retry waits for the retry time of the refused request before.
+	retry bool
+	sweep bool
+	want  bool
+}
+
+var storeTests = []struct {
+	name  string
+	steps []storeStep
+}{
+	{"burst", []storeStep{
+		{want: true}, {want: true}, {want: true}, {want: false}, {want: false},
+	}},
+	{"refill", []storeStep{
+		{want: true}, {want: true}, {want: true}, {want: false},
+		{wait: testIncrement + testSlack, want: true}, {want: false},
+	}},
+	{"retry after refused", []storeStep{
+		{want: true}, {want: true}, {want: true}, {want: false},
+		{retry: true, want: true}, {want: false},
+	}},
+	{"expired and swept", []storeStep{
+		{want: true}, {want: true}, {want: true},
+		{wait: testLimit + testSlack, sweep: true, want: true}, {want: true}, {want: true}, {want: false},
+	}},
+}
+
+func TestMemoryStore(t *testing.T) {
+	for _, tt := range storeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := NewMemoryStore()
+			ctx := context.Background()
+
+			var retryAfter time.Duration
+			for i, step := range tt.steps {
+				time.Sleep(step.wait)
+				if step.retry {
+					time.Sleep(retryAfter)
+				}
+				if step.sweep {
+					if err := s.DeleteExpired(ctx); err != nil {
+						t.Fatalf("step %d: delete expired: %v", i, err)
+					}
+					if len(s.tats) != 0 {
+						t.Fatalf("step %d: %d keys left after the bucket refilled", i, len(s.tats))
+					}
+				}
+
+				tat, now, ok, err := s.Advance(ctx, "key", testIncrement, testLimit)
+				if err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				if ok != step.want {
+					t.Fatalf("step %d: allowed %v, want %v", i, ok, step.want)
+				}
+				if tat.Sub(now) > testLimit {
+					t.Fatalf("step %d: TAT is %v ahead, past the limit", i, tat.Sub(now))
+				}
+				if !ok {
+					retryAfter = tat.Add(testIncrement).Sub(now.Add(testLimit))
+					if retryAfter <= 0 || retryAfter > testIncrement {
+						t.Fatalf("step %d: retry after %v, want within %v", i, retryAfter, testIncrement)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeysAreSeparate(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	for range 3 {
+		if _, _, ok, err := s.Advance(ctx, "a", testIncrement, testLimit); !ok || err != nil {
+			t.Fatalf("got %v, %v", ok, err)
+		}
+	}
+	if _, _, ok, _ := s.Advance(ctx, "a", testIncrement, testLimit); ok {
+		t.Fatal("burst of a wasn't limited")
+	}
+	if _, _, ok, err := s.Advance(ctx, "b", testIncrement, testLimit); !ok || err != nil {
+		t.Fatalf("b was limited by a: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PostgresStore keeps rate limits in the database, so that they hold across replicas.
+// Times are taken from the database clock.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Advance(ctx context.Context, key string, increment, limit time.Duration) (time.Time, time.Time, bool, error) {
+	for {
+		var tat, now time.Time
+		err := s.db.QueryRowContext(ctx,
+			`INSERT INTO rate_limits AS r (key, tat) VALUES ($1, NOW() + make_interval(secs => $2))
+			ON CONFLICT (key) DO UPDATE SET tat = GREATEST(r.tat, NOW()) + make_interval(secs => $2)
+			WHERE GREATEST(r.tat, NOW()) + make_interval(secs => $2) <= NOW() + make_interval(secs => $3)
+			RETURNING tat, NOW()`,
+			key, increment.Seconds(), limit.Seconds()).Scan(&tat, &now)
+		if err == nil {
+			return tat, now, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, time.Time{}, false, err
+		}
+
+		// The bucket is empty and was left as it is.
+		err = s.db.QueryRowContext(ctx,
+			"SELECT GREATEST(tat, NOW()), NOW() FROM rate_limits WHERE key = $1", key).Scan(&tat, &now)
+		if err == nil {
+			return tat, now, false, nil
+		}
+		// DeleteExpired removed the row in between, so the bucket has drained since.
+		if !errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, time.Time{}, false, err
+		}
+	}
+}
+
+func (s *PostgresStore) DeleteExpired(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM rate_limits WHERE tat < NOW()")
+	return err
+}
@@ -0,0 +1,199 @@
+package stream
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+const (
+	DefaultReplaySize = 1024
+	subscriberBuffer  = 64
+)
+
+type Subscription struct {
+	// Events is closed when the subscriber falls behind, unsubscribes or the hub closes.
+	Events <-chan models.Event
+
+	hub    *Hub
+	userID models.UserID
+	ch     chan models.Event
+}
+
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// Hub fans todo events out to the live subscribers of their owner and keeps the most
+// recent events so that reconnecting clients can resume from a sequence number.
+//
+// Publishing never blocks on a subscriber: one whose buffer is full is dropped and is
+// expected to reconnect and resume from the last event it received.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[models.UserID]map[*Subscription]struct{}
+	// replay is ordered by sequence and buffered maps the sequences in it to the order
+	// the events arrived in, so that events delivered more than once are recognised.
+	replay     []models.Event
+	buffered   map[int64]uint64
+	replaySize int
+	arrivals   uint64
+	// lostArrival is the latest arrival among the events evicted from the buffer.
+	// Subscribers that received an event before it can't resume without a gap.
+	lostArrival uint64
+	// evictedSeq is the high-water mark of the events evicted from the buffer. Events
+	// at or below it can no longer be told apart from redeliveries, so they are
+	// dropped.
+	evictedSeq int64
+	closed     bool
+}
+
+func NewHub(replaySize int) *Hub {
+	return &Hub{
+		subscribers: make(map[models.UserID]map[*Subscription]struct{}),
+		buffered:    make(map[int64]uint64),
+		replaySize:  replaySize,
+	}
+}
+
+// Subscribe registers a subscriber for userID. If lastSeq is non-zero, it also returns
+// the buffered events of that user the client missed since it received the event
+// lastSeq; complete is false when some of those may already have been evicted and the
+// client should reload its state instead. ok is false once the hub is closed.
+//
+// Outbox sequences are assigned before commit and the relay holds back the events of
+// failing aggregates, so events don't arrive in sequence order. The missed events are
+// therefore the ones that arrived after lastSeq, in the order they arrived, rather than
+// the ones with a higher sequence.
+func (h *Hub) Subscribe(userID models.UserID, lastSeq int64) (sub *Subscription, replay []models.Event, complete bool, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, false, false
+	}
+
+	complete = true
+	if lastSeq > 0 {
+		// An event that isn't buffered was evicted or arrived at another instance
+		// first, so it isn't known which events arrived after it.
+		lastArrival, found := h.buffered[lastSeq]
+		complete = found && lastArrival > h.lostArrival
+		for _, event := range h.replay {
+			if event.UserID != userID {
+				continue
+			}
+			if found && h.buffered[event.Sequence] > lastArrival || !found && event.Sequence > lastSeq {
+				replay = append(replay, event)
+			}
+		}
+		if found {
+			slices.SortFunc(replay, func(a, b models.Event) int {
+				return cmp.Compare(h.buffered[a.Sequence], h.buffered[b.Sequence])
+			})
+		}
+	}
+
+	ch := make(chan models.Event, subscriberBuffer)
+	sub = &Subscription{Events: ch, hub: h, userID: userID, ch: ch}
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+
+	return sub, replay, complete, true
+}
+
+func (h *Hub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(sub)
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	subs, ok := h.subscribers[sub.userID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.userID)
+	}
+	close(sub.ch)
+}
+
+// Publish implements events.EventPublisher. Events the hub has already buffered or
+// evicted are ignored, since the outbox relay delivers at least once and events may
+// also arrive from other instances.
+func (h *Hub) Publish(_ context.Context, event models.Event) error {
+	if event.AggregateType != models.AggregateTodo {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	if event.Sequence <= h.evictedSeq {
+		return nil
+	}
+	if _, ok := h.buffered[event.Sequence]; ok {
+		return nil
+	}
+
+	h.buffer(event)
+
+	for sub := range h.subscribers[event.UserID] {
+		select {
+		case sub.ch <- event:
+		default:
+			h.remove(sub)
+		}
+	}
+
+	return nil
+}
+
+func (h *Hub) buffer(event models.Event) {
+	h.arrivals++
+	i := sort.Search(len(h.replay), func(i int) bool { return h.replay[i].Sequence > event.Sequence })
+	h.replay = slices.Insert(h.replay, i, event)
+	h.buffered[event.Sequence] = h.arrivals
+
+	if len(h.replay) > h.replaySize {
+		evicted := h.replay[0]
+		h.evictedSeq = evicted.Sequence
+		h.lostArrival = max(h.lostArrival, h.buffered[evicted.Sequence])
+		delete(h.buffered, evicted.Sequence)
+		h.replay = slices.Delete(h.replay, 0, 1)
+	}
+}
+
+// Close ends every subscription and rejects new ones. Long-lived streams return once
+// their subscription channel is closed, which lets server shutdown complete.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+const (
+	alice models.UserID = 1
+	bob   models.UserID = 2
+)
+
+func publish(t *testing.T, h *Hub, seq int64, userID models.UserID) {
+	t.Helper()
+
+	event := models.Event{Sequence: seq, UserID: userID, AggregateType: models.AggregateTodo}
+	if err := h.Publish(context.Background(), event); err != nil {
+		t.Fatalf("publish %d: %v", seq, err)
+	}
+}
+
+func sequences(events []models.Event) string {
+	seqs := make([]int64, len(events))
+	for i, event := range events {
+		seqs[i] = event.Sequence
+	}
+	return fmt.Sprint(seqs)
+}
+
+// received drains the events the subscription has been sent so far.
+func received(sub *Subscription) []models.Event {
+	var events []models.Event
+	for {
+		select {
+		case event := <-sub.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestSubscribeResumesInArrivalOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		// before arrive while the client is connected, after once it disconnected.
+		before, after []int64
+		wantReplay    string
+	}{
+		{name: "in order", before: []int64{1, 2}, after: []int64{3, 4}, wantReplay: "[3 4]"},
+		{name: "held back event arrives while disconnected", before: []int64{1, 3}, after: []int64{2, 4}, wantReplay: "[2 4]"},
+		{name: "held back event arrived last", before: []int64{1, 3, 2}, after: []int64{5, 4}, wantReplay: "[5 4]"},
+		{name: "nothing missed", before: []int64{3, 1, 2}, wantReplay: "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(DefaultReplaySize)
+			sub, _, _, _ := h.Subscribe(alice, 0)
+			for _, seq := range tt.before {
+				publish(t, h, seq, alice)
+				publish(t, h, seq+100, bob)
+			}
+			got := received(sub)
+			sub.Close()
+			for _, seq := range tt.after {
+				publish(t, h, seq, alice)
+			}
+
+			// The client resumes with the sequence of the last event it received.
+			last := got[len(got)-1].Sequence
+			resumed, replay, complete, ok := h.Subscribe(alice, last)
+			if !ok || !complete {
+				t.Fatalf("Subscribe(%d) ok = %v, complete = %v, want both", last, ok, complete)
+			}
+			defer resumed.Close()
+			if s := sequences(replay); s != tt.wantReplay {
+				t.Fatalf("replay after %d = %s, want %s", last, s, tt.wantReplay)
+			}
+		})
+	}
+}
+
+func TestSubscribeReportsEvictedArrivals(t *testing.T) {
+	h := NewHub(3)
+	publish(t, h, 5, alice)
+	publish(t, h, 6, alice)
+	publish(t, h, 7, alice)
+
+	// 4 was held back and arrives after 7. The buffer is full and 4 has the lowest
+	// sequence, so it is evicted at once: a client that last received 7 missed it.
+	publish(t, h, 4, alice)
+	sub, replay, complete, _ := h.Subscribe(alice, 7)
+	defer sub.Close()
+	if complete {
+		t.Fatalf("complete after eviction, replay %s", sequences(replay))
+	}
+
+	// Redeliveries of the evicted event are dropped rather than buffered again.
+	publish(t, h, 4, alice)
+	if s := sequences(h.replay); s != "[5 6 7]" {
+		t.Fatalf("buffered %s, want [5 6 7]", s)
+	}
+}
+
+func TestSubscribeWithUnknownSequence(t *testing.T) {
+	h := NewHub(DefaultReplaySize)
+	publish(t, h, 10, alice)
+	publish(t, h, 12, alice)
+
+	// 11 arrived at another instance first, so the events missed since can't be told.
+	sub, replay, complete, _ := h.Subscribe(alice, 11)
+	defer sub.Close()
+	if complete {
+		t.Fatal("complete for a sequence the hub hasn't seen")
+	}
+	if s := sequences(replay); s != "[12]" {
+		t.Fatalf("replay = %s, want [12]", s)
+	}
+}
+
+func TestPublishDropsRedeliveries(t *testing.T) {
+	h := NewHub(DefaultReplaySize)
+	sub, _, _, _ := h.Subscribe(alice, 0)
+	defer sub.Close()
+
+	for _, seq := range []int64{1, 3, 1, 2, 3} {
+		publish(t, h, seq, alice)
+	}
+	if s := sequences(received(sub)); s != "[1 3 2]" {
+		t.Fatalf("received %s, want [1 3 2]", s)
+	}
+}
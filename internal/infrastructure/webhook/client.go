@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+const (
+	userAgent       = "go-to-do-app-webhooks/1.0"
+	maxExcerptBytes = 2048
+)
+
+type Result struct {
+	StatusCode   int
+	ResponseBody string
+	Err          error
+}
+
+func (r Result) Success() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+type Client struct {
+	http *http.Client
+}
+
+func NewClient(timeout time.Duration) *Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialControl}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would be dialed instead of the receiver, so the receiver's address
+	// wouldn't be checked.
+	transport.Proxy = nil
+
+	return &Client{
+		http: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// Redirects are not followed so a receiver can't bounce signed payloads elsewhere.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (c *Client) Send(ctx context.Context, hook models.Webhook, d models.WebhookDelivery) Result {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Webhook-ID", strconv.FormatInt(int64(hook.ID), 10))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(int64(d.ID), 10))
+	req.Header.Set("X-Webhook-Event", string(d.EventType))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(hook.Secret, timestamp, d.Payload))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxExcerptBytes))
+	if err != nil {
+		return Result{StatusCode: resp.StatusCode, Err: err}
+	}
+
+	return Result{StatusCode: resp.StatusCode, ResponseBody: string(body)}
+}
+
+// Excerpt truncates a request or response body for the delivery log.
+func Excerpt(b []byte) string {
+	if len(b) <= maxExcerptBytes {
+		return string(b)
+	}
+	return string(b[:maxExcerptBytes]) + "..."
+}
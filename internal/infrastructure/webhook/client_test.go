@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"fd00:ec2::254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := Allowed(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for _, host := range []string{"localhost", "127.0.0.1", "169.254.169.254", "::1"} {
+		if err := CheckHost(context.Background(), host); !errors.Is(err, ErrDisallowedAddress) {
+			t.Errorf("CheckHost(%q) = %v, want ErrDisallowedAddress", host, err)
+		}
+	}
+	if err := CheckHost(context.Background(), "93.184.216.34"); err != nil {
+		t.Errorf("CheckHost(public) = %v, want nil", err)
+	}
+}
+
+// The receiver passed registration but is on loopback when the client connects, as
+// after a DNS rebinding.
+func TestSendRefusesDisallowedAddress(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	res := NewClient(time.Second).Send(context.Background(),
+		models.Webhook{ID: 1, URL: srv.URL, Secret: "whsec_test"},
+		models.WebhookDelivery{ID: 1, EventType: models.EventTodoCreated, Payload: []byte(`{}`)})
+
+	if !errors.Is(res.Err, ErrDisallowedAddress) {
+		t.Fatalf("Send err = %v, want ErrDisallowedAddress", res.Err)
+	}
+	if called {
+		t.Fatal("receiver on loopback was called")
+	}
+}
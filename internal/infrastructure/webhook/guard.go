@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+var ErrDisallowedAddress = errors.New("webhook address is not allowed")
+
+// blockedPrefixes are the networks receivers can't be in, so that webhooks can't be
+// used to reach the app's own host, its private network or a cloud metadata service
+// (169.254.169.254, fd00:ec2::254, 100.100.100.200).
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/3"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// Allowed reports whether webhooks may be delivered to addr.
+func Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost resolves host and returns ErrDisallowedAddress if any of its addresses
+// isn't allowed.
+func CheckHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		if !Allowed(addr) {
+			return ErrDisallowedAddress
+		}
+	}
+	return nil
+}
+
+// dialControl refuses connections to disallowed addresses. It runs after DNS
+// resolution, so a host that resolved to a public address at registration can't be
+// pointed at an internal one later.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !Allowed(addrPort.Addr()) {
+		return ErrDisallowedAddress
+	}
+	return nil
+}
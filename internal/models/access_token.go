@@ -0,0 +1,78 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type AccessTokenID int64
+
+// Scope is a permission a personal access token can be granted.
+type Scope string
+
+const (
+	ScopeTodosRead     Scope = "todos:read"
+	ScopeTodosWrite    Scope = "todos:write"
+	ScopeWebhooksRead  Scope = "webhooks:read"
+	ScopeWebhooksWrite Scope = "webhooks:write"
+)
+
+// Scopes are the scopes tokens can be granted.
+var Scopes = []Scope{ScopeTodosRead, ScopeTodosWrite, ScopeWebhooksRead, ScopeWebhooksWrite}
+
+func (s Scope) Valid() bool {
+	return slices.Contains(Scopes, s)
+}
+
+// AccessToken is a personal access token, which scripts use to call the API on behalf
+// of a user without their password. Only the hash of the token is stored.
+type AccessToken struct {
+	ID        AccessTokenID `db:"id"`
+	UserID    UserID        `db:"user_id"`
+	Name      string        `db:"name"`
+	TokenHash string        `db:"token_hash"`
+	Scopes    []Scope       `db:"scopes"`
+	// ExpiresAt is nil for tokens that don't expire.
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+func (t AccessToken) Expired() bool {
+	return t.ExpiresAt != nil && !time.Now().Before(*t.ExpiresAt)
+}
+
+// Caller is who a request is authenticated as.
+type Caller struct {
+	UserID UserID
+	Email  string
+	// ReadOnly callers can only make requests that don't change anything.
+	ReadOnly bool
+	// Scopes limit what a personal access token can do. They are nil for access tokens
+	// of a login, which can do anything the user can.
+	Scopes []Scope
+	// Key identifies the personal access token or OAuth client the caller used, for
+	// rate limits. It is empty for access tokens of a login.
+	Key  string
+	Role Role
+	// Permissions are those of Role.
+	Permissions []Permission
+	// ExpiresAt is when the token expires. It is zero for tokens that don't.
+	ExpiresAt time.Time
+}
+
+// Can reports whether the caller's role grants permission.
+func (c Caller) Can(permission Permission) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// Allows reports whether the caller has scope.
+func (c Caller) Allows(scope Scope) bool {
+	return c.Scopes == nil || slices.Contains(c.Scopes, scope)
+}
+
+// Session reports whether the caller logged in, rather than using a personal access
+// token.
+func (c Caller) Session() bool {
+	return c.Scopes == nil
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventTodoCreated   EventType = "todo.created"
+	EventTodoUpdated   EventType = "todo.updated"
+	EventTodoCompleted EventType = "todo.completed"
+	EventTodoDeleted   EventType = "todo.deleted"
+	EventUserCreated   EventType = "user.created"
+	// EventUserEmailChanged carries the new address, which is unverified.
+	EventUserEmailChanged EventType = "user.email_changed"
+	EventUserDisabled     EventType = "user.disabled"
+	EventUserEnabled      EventType = "user.enabled"
+	// EventUserRoleChanged carries the new role.
+	EventUserRoleChanged EventType = "user.role_changed"
+	// EventUserDeleted follows the todo.deleted events of the user's todos.
+	EventUserDeleted EventType = "user.deleted"
+)
+
+const (
+	AggregateTodo = "todo"
+	AggregateUser = "user"
+)
+
+var TodoEventTypes = []EventType{
+	EventTodoCreated,
+	EventTodoUpdated,
+	EventTodoCompleted,
+	EventTodoDeleted,
+}
+
+type Event struct {
+	ID string
+	// Sequence is the outbox position of the event. It increases monotonically, so
+	// consumers can use it to resume and to order events of the same aggregate.
+	Sequence      int64
+	Type          EventType
+	UserID        UserID
+	AggregateType string
+	AggregateID   int64
+	Payload       json.RawMessage
+	OccurredAt    time.Time
+}
+
+// TodoEventData is the JSON representation of a todo carried in event payloads.
+type TodoEventData struct {
+	ID          ToDoID    `json:"id"`
+	UserID      UserID    `json:"user_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Completed   bool      `json:"completed"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func NewTodoEvent(t EventType, todo ToDo) (Event, error) {
+	payload, err := json.Marshal(TodoEventData{
+		ID:          todo.ID,
+		UserID:      todo.UserID,
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   todo.Completed,
+		CreatedAt:   todo.CreatedAt,
+		UpdatedAt:   todo.UpdatedAt,
+	})
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:            newEventID(),
+		Type:          t,
+		UserID:        todo.UserID,
+		AggregateType: AggregateTodo,
+		AggregateID:   int64(todo.ID),
+		Payload:       payload,
+		OccurredAt:    time.Now().UTC(),
+	}, nil
+}
+
+type UserEventData struct {
+	ID       UserID `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     Role   `json:"role,omitempty"`
+}
+
+func NewUserEvent(t EventType, user User) (Event, error) {
+	payload, err := json.Marshal(UserEventData{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Role:     user.Role,
+	})
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:            newEventID(),
+		Type:          t,
+		UserID:        user.ID,
+		AggregateType: AggregateUser,
+		AggregateID:   int64(user.ID),
+		Payload:       payload,
+		OccurredAt:    time.Now().UTC(),
+	}, nil
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to their account at an external OpenID Connect provider.
+type UserIdentity struct {
+	ID       int64  `db:"id"`
+	UserID   UserID `db:"user_id"`
+	Provider string `db:"provider"`
+	Subject  string `db:"subject"`
+	// Email is the one the identity was linked by.
+	Email     string    `db:"email"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// OIDCLoginState is a login at an external provider in progress. It is stored by the
+// hash of the state parameter, and used once.
+type OIDCLoginState struct {
+	StateHash    string    `db:"state_hash"`
+	Provider     string    `db:"provider"`
+	CodeVerifier string    `db:"code_verifier"`
+	Nonce        string    `db:"nonce"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ListMember is a user a todo list is shared with. Every user owns one list,
+// identified by the owner's user ID.
+type ListMember struct {
+	OwnerID UserID `db:"owner_id"`
+	UserID  UserID `db:"user_id"`
+	// Email is the member's email.
+	Email     string    `db:"email"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// SharedList is a list another user shared with the caller.
+type SharedList struct {
+	OwnerID    UserID    `db:"owner_id"`
+	OwnerEmail string    `db:"owner_email"`
+	SharedAt   time.Time `db:"created_at"`
+}
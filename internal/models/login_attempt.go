@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// LoginAttempts counts the failed logins for an account or a client address since the
+// last success or quiet period.
+type LoginAttempts struct {
+	Key          string    `db:"key"`
+	Failures     int       `db:"failures"`
+	LastFailedAt time.Time `db:"last_failed_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// OAuthClient is a third-party app registered by a user to act on behalf of others
+// through the OAuth 2.0 authorization code flow.
+type OAuthClient struct {
+	ID           string   `db:"id"`
+	UserID       UserID   `db:"user_id"`
+	Name         string   `db:"name"`
+	RedirectURIs []string `db:"redirect_uris"`
+	// SecretHash is empty for public clients, such as single-page and native apps, which
+	// can't keep a secret and rely on PKCE alone.
+	SecretHash string    `db:"secret_hash"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func (c OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// OAuthAuthorizationCode is issued when a user consents to a client's request, and is
+// exchanged once for tokens. Only its hash is stored.
+type OAuthAuthorizationCode struct {
+	CodeHash      string    `db:"code_hash"`
+	ClientID      string    `db:"client_id"`
+	UserID        UserID    `db:"user_id"`
+	RedirectURI   string    `db:"redirect_uri"`
+	CodeChallenge string    `db:"code_challenge"`
+	Scopes        []Scope   `db:"scopes"`
+	ExpiresAt     time.Time `db:"expires_at"`
+}
+
+type OAuthTokenID int64
+
+// OAuthToken is an access token issued to a client, with the refresh token it can be
+// renewed with. Refreshing revokes the pair and issues a new one.
+type OAuthToken struct {
+	ID               OAuthTokenID `db:"id"`
+	ClientID         string       `db:"client_id"`
+	UserID           UserID       `db:"user_id"`
+	Scopes           []Scope      `db:"scopes"`
+	AccessTokenHash  string       `db:"access_token_hash"`
+	RefreshTokenHash string       `db:"refresh_token_hash"`
+	AccessExpiresAt  time.Time    `db:"access_expires_at"`
+	RefreshExpiresAt time.Time    `db:"refresh_expires_at"`
+	RevokedAt        *time.Time   `db:"revoked_at"`
+	CreatedAt        time.Time    `db:"created_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+type PasskeyID int64
+
+// Passkey is a WebAuthn credential a user can log in with instead of a password.
+type Passkey struct {
+	ID              PasskeyID  `db:"id"`
+	UserID          UserID     `db:"user_id"`
+	Name            string     `db:"name"`
+	CredentialID    []byte     `db:"credential_id"`
+	PublicKey       []byte     `db:"public_key"`
+	AttestationType string     `db:"attestation_type"`
+	Transports      []string   `db:"transports"`
+	AAGUID          []byte     `db:"aaguid"`
+	Flags           uint8      `db:"flags"`
+	SignCount       uint32     `db:"sign_count"`
+	CloneWarning    bool       `db:"clone_warning"`
+	CreatedAt       time.Time  `db:"created_at"`
+	LastUsedAt      *time.Time `db:"last_used_at"`
+}
+
+// flagBackupState is the BS bit of the authenticator data flags.
+const flagBackupState = 0x10
+
+// BackedUp reports whether the passkey was synced, e.g. to a cloud keychain, when it
+// was registered.
+func (p Passkey) BackedUp() bool {
+	return p.Flags&flagBackupState != 0
+}
+
+type WebAuthnCeremony string
+
+const (
+	CeremonyRegistration WebAuthnCeremony = "registration"
+	CeremonyLogin        WebAuthnCeremony = "login"
+)
+
+// WebAuthnSession is the server side of a registration or login in progress. It is
+// stored by the hash of the session ID handed to the client, and used once.
+type WebAuthnSession struct {
+	SessionHash string           `db:"session_hash"`
+	Ceremony    WebAuthnCeremony `db:"ceremony"`
+	UserID      *UserID          `db:"user_id"`
+	Data        []byte           `db:"data"`
+	ExpiresAt   time.Time        `db:"expires_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type PasswordResetTokenID int64
+
+// PasswordResetToken is stored by hash only, and deleted once it is used.
+type PasswordResetToken struct {
+	ID        PasswordResetTokenID `db:"id"`
+	UserID    UserID               `db:"user_id"`
+	TokenHash string               `db:"token_hash"`
+	ExpiresAt time.Time            `db:"expires_at"`
+	CreatedAt time.Time            `db:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type RefreshTokenID int64
+
+// RefreshToken is stored by hash only. Each refresh replaces the token with a new one
+// of the same family; presenting a rotated token again means it was stolen, and the
+// whole family is revoked.
+type RefreshToken struct {
+	ID        RefreshTokenID `db:"id"`
+	UserID    UserID         `db:"user_id"`
+	FamilyID  string         `db:"family_id"`
+	TokenHash string         `db:"token_hash"`
+	ExpiresAt time.Time      `db:"expires_at"`
+	CreatedAt time.Time      `db:"created_at"`
+	RotatedAt *time.Time     `db:"rotated_at"`
+	RevokedAt *time.Time     `db:"revoked_at"`
+}
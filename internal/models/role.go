@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Role decides what a user may do beyond managing their own data.
+type Role string
+
+const (
+	RoleUser    Role = "user"
+	RoleSupport Role = "support"
+	RoleAdmin   Role = "admin"
+)
+
+var Roles = []Role{RoleUser, RoleSupport, RoleAdmin}
+
+func (r Role) Valid() bool {
+	return slices.Contains(Roles, r)
+}
+
+// Permission is granted by roles. The permissions of each role are stored in the
+// role_permissions table.
+type Permission string
+
+const (
+	PermissionUsersRead    Permission = "users:read"
+	PermissionUsersDisable Permission = "users:disable"
+	PermissionUsersDelete  Permission = "users:delete"
+	PermissionUsersRoles   Permission = "users:roles"
+	PermissionStatsRead    Permission = "stats:read"
+	PermissionAuditRead    Permission = "audit:read"
+)
+
+type AuditEntryID int64
+
+// AuditAction is what an admin did.
+type AuditAction string
+
+const (
+	AuditUsersList   AuditAction = "users.list"
+	AuditUserView    AuditAction = "user.view"
+	AuditUserDisable AuditAction = "user.disable"
+	AuditUserEnable  AuditAction = "user.enable"
+	AuditUserDelete  AuditAction = "user.delete"
+	AuditUserSetRole AuditAction = "user.set_role"
+	AuditStatsView   AuditAction = "stats.view"
+	AuditLogView     AuditAction = "audit_log.view"
+)
+
+// AuditEntry records an action of an admin.
+type AuditEntry struct {
+	ID      AuditEntryID `db:"id"`
+	ActorID UserID       `db:"actor_id"`
+	Action  AuditAction  `db:"action"`
+	// TargetUserID is the user the action was taken on, if any.
+	TargetUserID *UserID         `db:"target_user_id"`
+	Details      json.RawMessage `db:"details"`
+	CreatedAt    time.Time       `db:"created_at"`
+}
+
+// SystemStats are counts shown to admins.
+type SystemStats struct {
+	Users         int64
+	VerifiedUsers int64
+	DisabledUsers int64
+	UsersByRole   map[Role]int64
+	Todos         int64
+}
@@ -9,6 +9,7 @@ type ToDo struct {
 	UserID      UserID    `db:"user_id"`
 	Title       string    `db:"title"`
 	Description string    `db:"description"`
+	Completed   bool      `db:"completed"`
 	CreatedAt   time.Time `db:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// TOTPCredential is a user's RFC 6238 authenticator. Secret is encrypted.
+type TOTPCredential struct {
+	UserID         UserID     `db:"user_id"`
+	Secret         []byte     `db:"secret"`
+	ConfirmedAt    *time.Time `db:"confirmed_at"`
+	LastStep       int64      `db:"last_step"`
+	FailedAttempts int        `db:"failed_attempts"`
+	LastFailedAt   *time.Time `db:"last_failed_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+}
+
+func (c TOTPCredential) Confirmed() bool {
+	return c.ConfirmedAt != nil
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type UserID int64
 
 type User struct {
@@ -7,4 +9,23 @@ type User struct {
 	Username     string `db:"username"`
 	Email        string `db:"email"`
 	PasswordHash string `db:"password_hash"`
+	// EmailVerifiedAt is nil until the user follows the link mailed to Email.
+	EmailVerifiedAt *time.Time `db:"email_verified_at"`
+	Role            Role       `db:"role"`
+	// Permissions are those of Role.
+	Permissions []Permission `db:"-"`
+	// DisabledAt is set while an admin has disabled the user.
+	DisabledAt *time.Time `db:"disabled_at"`
+	// PasswordChangedAt is when the password was last reset, if ever. Access tokens of
+	// logins from before it are refused.
+	PasswordChangedAt *time.Time `db:"password_changed_at"`
+	CreatedAt         time.Time  `db:"created_at"`
+}
+
+func (u User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u User) Disabled() bool {
+	return u.DisabledAt != nil
 }
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookID int64
+
+type WebhookDeliveryID int64
+
+type Webhook struct {
+	ID                  WebhookID   `db:"id"`
+	UserID              UserID      `db:"user_id"`
+	URL                 string      `db:"url"`
+	Secret              string      `db:"secret"`
+	Events              []EventType `db:"events"`
+	Active              bool        `db:"active"`
+	ConsecutiveFailures int         `db:"consecutive_failures"`
+	DisabledAt          *time.Time  `db:"disabled_at"`
+	CreatedAt           time.Time   `db:"created_at"`
+	UpdatedAt           time.Time   `db:"updated_at"`
+}
+
+func (w Webhook) Subscribes(t EventType) bool {
+	for _, e := range w.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+type WebhookDelivery struct {
+	ID             WebhookDeliveryID `db:"id"`
+	WebhookID      WebhookID         `db:"webhook_id"`
+	EventID        string            `db:"event_id"`
+	EventType      EventType         `db:"event_type"`
+	Payload        json.RawMessage   `db:"payload"`
+	Status         DeliveryStatus    `db:"status"`
+	Attempts       int               `db:"attempts"`
+	NextAttemptAt  time.Time         `db:"next_attempt_at"`
+	LastAttemptAt  *time.Time        `db:"last_attempt_at"`
+	ResponseStatus int               `db:"response_status"`
+	ResponseBody   string            `db:"response_body"`
+	Error          string            `db:"error"`
+	// RedeliveredFrom is set on manual redeliveries and points at the original delivery.
+	RedeliveredFrom *WebhookDeliveryID `db:"redelivered_from"`
+	CreatedAt       time.Time          `db:"created_at"`
+}
@@ -0,0 +1,11 @@
+package events
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
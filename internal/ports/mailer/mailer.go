@@ -0,0 +1,14 @@
+package mailer
+
+import "context"
+
+type Message struct {
+	To      string
+	Subject string
+	// Body is plain text.
+	Body string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
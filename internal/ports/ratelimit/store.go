@@ -0,0 +1,16 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps the theoretical arrival time (TAT) of each key for GCRA: the time at
+// which its bucket is full again.
+type Store interface {
+	// Advance moves the TAT of key to max(TAT, now) + increment, unless that would put it
+	// later than now + limit. It returns the TAT, moved or not, and the store's time.
+	Advance(ctx context.Context, key string, increment, limit time.Duration) (tat, now time.Time, ok bool, err error)
+	// DeleteExpired forgets the keys whose TAT has passed, which are as good as unused.
+	DeleteExpired(ctx context.Context) error
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type AccessTokenRepository interface {
+	CreateAccessToken(ctx context.Context, token models.AccessToken) (models.AccessToken, error)
+	ListAccessTokens(ctx context.Context, userID models.UserID) ([]models.AccessToken, error)
+	DeleteAccessToken(ctx context.Context, userID models.UserID, id models.AccessTokenID) error
+	// GetAccessTokenByHash fails with ErrInvalidAccessToken if there is no token with
+	// tokenHash. Expired tokens are returned.
+	GetAccessTokenByHash(ctx context.Context, tokenHash string) (models.AccessToken, error)
+	// TouchAccessToken records that the token was used. Uses within a minute of the last
+	// recorded one aren't written.
+	TouchAccessToken(ctx context.Context, id models.AccessTokenID) error
+}
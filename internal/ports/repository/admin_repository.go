@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type AdminRepository interface {
+	Stats(ctx context.Context) (models.SystemStats, error)
+	RecordAuditEntry(ctx context.Context, entry models.AuditEntry) error
+	// ListAuditEntries returns the audit log, newest first.
+	ListAuditEntries(ctx context.Context, limit, offset int) ([]models.AuditEntry, error)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type IdentityRepository interface {
+	// GetIdentity returns the identity the provider knows by subject, or ErrUserNotFound
+	// if it isn't linked to a user.
+	GetIdentity(ctx context.Context, provider, subject string) (models.UserIdentity, error)
+	// LinkIdentity links the identity to its user. Linking an identity twice is a no-op.
+	LinkIdentity(ctx context.Context, identity models.UserIdentity) error
+	CreateOIDCState(ctx context.Context, state models.OIDCLoginState) error
+	// TakeOIDCState deletes and returns the unexpired login state of the provider with
+	// stateHash, so that each redirect is accepted at most once. It fails with
+	// ErrInvalidOIDCState if there is none.
+	TakeOIDCState(ctx context.Context, stateHash, provider string) (models.OIDCLoginState, error)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type ListMemberRepository interface {
+	// AddListMember shares the list of ownerID with userID. Sharing it again keeps the
+	// existing membership.
+	AddListMember(ctx context.Context, ownerID, userID models.UserID) (models.ListMember, error)
+	RemoveListMember(ctx context.Context, ownerID, userID models.UserID) error
+	ListListMembers(ctx context.Context, ownerID models.UserID) ([]models.ListMember, error)
+	ListSharedLists(ctx context.Context, userID models.UserID) ([]models.SharedList, error)
+	IsListMember(ctx context.Context, ownerID, userID models.UserID) (bool, error)
+}
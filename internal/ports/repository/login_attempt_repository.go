@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type LoginAttemptRepository interface {
+	// GetLoginAttempts returns the counts for those of keys that have failed within window.
+	GetLoginAttempts(ctx context.Context, keys []string, window time.Duration) ([]models.LoginAttempts, error)
+	// ReserveLoginAttempt counts a failure for key before the attempt is checked, unless key
+	// has limit failures within window. It reports false then, with the counts that lock
+	// the key. Counts start over if the last failure is older than window.
+	ReserveLoginAttempt(ctx context.Context, key string, limit int, window time.Duration) (models.LoginAttempts, bool, error)
+	// ReleaseLoginAttempt takes back a failure counted by ReserveLoginAttempt.
+	ReleaseLoginAttempt(ctx context.Context, key string) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+}
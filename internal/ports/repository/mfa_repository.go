@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type MFARepository interface {
+	// GetTOTP fails with ErrTOTPNotEnabled if the user has no credential, confirmed or not.
+	GetTOTP(ctx context.Context, userID models.UserID) (models.TOTPCredential, error)
+	// SaveTOTP stores an unconfirmed credential, replacing an earlier unconfirmed one. It
+	// fails with ErrTOTPAlreadyEnabled if the user has a confirmed credential.
+	SaveTOTP(ctx context.Context, cred models.TOTPCredential) error
+	// ConfirmTOTP enables the credential, records step as used and replaces the user's
+	// recovery codes with codeHashes. It fails with ErrTOTPNotEnabled if there is no
+	// unconfirmed credential.
+	ConfirmTOTP(ctx context.Context, userID models.UserID, step int64, codeHashes []string) error
+	DeleteTOTP(ctx context.Context, userID models.UserID) error
+	// UseTOTPStep records step as used if it is later than the last used one, and resets
+	// the failed attempts. It reports false for replayed codes.
+	UseTOTPStep(ctx context.Context, userID models.UserID, step int64) (bool, error)
+	// UseRecoveryCode marks the unused code with codeHash as used, and resets the failed
+	// attempts. It reports false if there is no such code.
+	UseRecoveryCode(ctx context.Context, userID models.UserID, codeHash string) (bool, error)
+	// ReserveMFAAttempt counts an attempt as failed before its code is checked, unless
+	// the user has maxFailures failed attempts, the last within lockout. It reports false
+	// then. Accepted codes reset the count.
+	ReserveMFAAttempt(ctx context.Context, userID models.UserID, maxFailures int, lockout time.Duration) (bool, error)
+	// UseMFAChallenge marks the MFA token with id as used until expiresAt. It reports
+	// false if it already was.
+	UseMFAChallenge(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client models.OAuthClient) (models.OAuthClient, error)
+	// GetClient fails with ErrOAuthClientNotFound if there is no client with id.
+	GetClient(ctx context.Context, id string) (models.OAuthClient, error)
+	ListClients(ctx context.Context, userID models.UserID) ([]models.OAuthClient, error)
+	// DeleteClient deletes a client of the user, which revokes its tokens.
+	DeleteClient(ctx context.Context, userID models.UserID, id string) error
+	CreateAuthorizationCode(ctx context.Context, code models.OAuthAuthorizationCode) error
+	// TakeAuthorizationCode deletes and returns the unexpired code with codeHash, so that
+	// each code is exchanged at most once. It fails with ErrInvalidGrant if there is none.
+	TakeAuthorizationCode(ctx context.Context, codeHash string) (models.OAuthAuthorizationCode, error)
+	CreateToken(ctx context.Context, token models.OAuthToken) error
+	// GetTokenByAccessHash and GetTokenByRefreshHash fail with ErrInvalidGrant if there is
+	// no such token. Revoked and expired tokens are returned.
+	GetTokenByAccessHash(ctx context.Context, hash string) (models.OAuthToken, error)
+	GetTokenByRefreshHash(ctx context.Context, hash string) (models.OAuthToken, error)
+	// RotateToken revokes the token with id and stores next in its place. It fails with
+	// ErrInvalidGrant if the token was already revoked.
+	RotateToken(ctx context.Context, id models.OAuthTokenID, next models.OAuthToken) error
+	RevokeToken(ctx context.Context, id models.OAuthTokenID) error
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type OutboxRepository interface {
+	ProcessBatch(ctx context.Context, limit int, fn func(models.Event) error) (int, error)
+	DeletePublishedBefore(ctx context.Context, age time.Duration) (int64, error)
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type PasskeyRepository interface {
+	// CreatePasskey fails with ErrPasskeyAlreadyExists if the credential is registered.
+	CreatePasskey(ctx context.Context, passkey models.Passkey) (models.Passkey, error)
+	ListPasskeys(ctx context.Context, userID models.UserID) ([]models.Passkey, error)
+	DeletePasskey(ctx context.Context, userID models.UserID, id models.PasskeyID) error
+	// UsePasskey records a login with signCount. It reports false if another login
+	// recorded a count as high since the passkey was read, so that a counter can't be
+	// used twice.
+	UsePasskey(ctx context.Context, id models.PasskeyID, signCount uint32) (bool, error)
+	// FlagClonedPasskey sets the clone warning of the passkey.
+	FlagClonedPasskey(ctx context.Context, id models.PasskeyID) error
+	CreateWebAuthnSession(ctx context.Context, session models.WebAuthnSession) error
+	// TakeWebAuthnSession deletes and returns the unexpired session of the ceremony with
+	// sessionHash, so that each challenge is answered at most once. It fails with
+	// ErrInvalidWebAuthnSession if there is none.
+	TakeWebAuthnSession(ctx context.Context, sessionHash string, ceremony models.WebAuthnCeremony) (models.WebAuthnSession, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type PasswordResetRepository interface {
+	CreatePasswordResetToken(ctx context.Context, token models.PasswordResetToken) error
+	// GetPasswordResetToken returns the unexpired token with tokenHash. It fails with
+	// ErrInvalidResetToken if there is no such token.
+	GetPasswordResetToken(ctx context.Context, tokenHash string) (models.PasswordResetToken, error)
+	// ResetPassword consumes the unexpired token with tokenHash, sets the password of its
+	// user and its change time and, in the same transaction, deletes the user's other
+	// reset tokens and revokes their refresh tokens and OAuth grants. It fails with
+	// ErrInvalidResetToken if there is no such token.
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (models.UserID, error)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (models.RefreshToken, error)
+	// RotateRefreshToken marks the token as rotated and stores next in the same
+	// transaction. It fails with ErrRefreshTokenReused if the token was already rotated
+	// or revoked, e.g. by a concurrent refresh with the same token.
+	RotateRefreshToken(ctx context.Context, id models.RefreshTokenID, next models.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	// RefreshTokenFamilyRevoked reports whether the login of familyID was logged out or
+	// revoked.
+	RefreshTokenFamilyRevoked(ctx context.Context, familyID string) (bool, error)
+}
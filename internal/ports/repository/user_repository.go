@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/mrxacker/go-to-do-app/internal/models"
 )
@@ -11,4 +12,20 @@ type UserRepository interface {
 	GetUserByEmail(ctx context.Context, email string) (models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (models.User, error)
 	GetUserByID(ctx context.Context, id models.UserID) (models.User, error)
+	GetUsersByIDs(ctx context.Context, ids []models.UserID) ([]models.User, error)
+	// ListUsers returns the users whose username or email contains query, ignoring case.
+	ListUsers(ctx context.Context, query string, limit, offset int) ([]models.User, error)
+	// UpdateEmail sets a new, unverified email. It fails with ErrUserAlreadyExists if
+	// another user has that email.
+	UpdateEmail(ctx context.Context, id models.UserID, email string) error
+	// MarkEmailVerified verifies email if it is still the user's email. It fails with
+	// ErrUserNotFound otherwise.
+	MarkEmailVerified(ctx context.Context, id models.UserID, email string) error
+	// ClaimVerificationEmail reports whether a verification link may be mailed to the
+	// user now, and if so records that one is being sent. It returns false for verified
+	// users and while the last link was sent less than interval ago.
+	ClaimVerificationEmail(ctx context.Context, id models.UserID, interval time.Duration) (bool, error)
+	SetDisabled(ctx context.Context, id models.UserID, disabled bool) error
+	SetRole(ctx context.Context, id models.UserID, role models.Role) error
+	DeleteUser(ctx context.Context, id models.UserID) error
 }
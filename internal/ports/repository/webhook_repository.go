@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, hook models.Webhook) (models.WebhookID, error)
+	GetWebhookByID(ctx context.Context, id models.WebhookID) (models.Webhook, error)
+	ListWebhooks(ctx context.Context, userID models.UserID) ([]models.Webhook, error)
+	ListActiveWebhooks(ctx context.Context, userID models.UserID, eventType models.EventType) ([]models.Webhook, error)
+	UpdateWebhook(ctx context.Context, hook models.Webhook) error
+	DeleteWebhookByID(ctx context.Context, id models.WebhookID) error
+	// RecordWebhookResult resets the failure counter on success, otherwise increments it
+	// and deactivates the webhook once it reaches disableAfter. It reports whether the
+	// webhook was disabled by this call.
+	RecordWebhookResult(ctx context.Context, id models.WebhookID, success bool, disableAfter int) (bool, error)
+
+	CreateDelivery(ctx context.Context, d models.WebhookDelivery) (models.WebhookDeliveryID, error)
+	GetDeliveryByID(ctx context.Context, id models.WebhookDeliveryID) (models.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, webhookID models.WebhookID, limit, offset int) ([]models.WebhookDelivery, error)
+	// ClaimDueDeliveries leases pending deliveries of active webhooks whose next attempt
+	// is due, so that concurrent dispatchers never pick the same delivery.
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, d models.WebhookDelivery) error
+}
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+)
+
+// accessTokenPrefix tells personal access tokens apart from JWTs, and lets secret
+// scanners find leaked ones.
+const accessTokenPrefix = "pat_"
+
+// AccessTokenUsecase manages personal access tokens, and authenticates requests made
+// with them, with the access tokens of OAuth clients or with the JWTs of a login.
+type AccessTokenUsecase struct {
+	repo       repository.AccessTokenRepository
+	userRepo   repository.UserRepository
+	sessions   repository.RefreshTokenRepository
+	oauth      *OAuthUsecase
+	jwtService *auth.JWTService
+	policy     VerificationPolicy
+}
+
+func NewAccessTokenUsecase(repo repository.AccessTokenRepository, userRepo repository.UserRepository, sessions repository.RefreshTokenRepository, oauth *OAuthUsecase, jwtService *auth.JWTService, policy VerificationPolicy) *AccessTokenUsecase {
+	return &AccessTokenUsecase{repo: repo, userRepo: userRepo, sessions: sessions, oauth: oauth, jwtService: jwtService, policy: policy}
+}
+
+// CreateAccessToken returns a new token with scopes, which is only ever shown here. A
+// nil expiresAt creates a token that doesn't expire.
+func (u *AccessTokenUsecase) CreateAccessToken(ctx context.Context, userID models.UserID, name string, scopes []models.Scope, expiresAt *time.Time) (string, models.AccessToken, error) {
+	if len(scopes) == 0 {
+		return "", models.AccessToken{}, e.ErrInvalidScope
+	}
+	for _, s := range scopes {
+		if !s.Valid() {
+			return "", models.AccessToken{}, e.ErrInvalidScope
+		}
+	}
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	token, hash, err := auth.GenerateOpaqueToken(accessTokenPrefix)
+	if err != nil {
+		return "", models.AccessToken{}, err
+	}
+
+	created, err := u.repo.CreateAccessToken(ctx, models.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", models.AccessToken{}, err
+	}
+
+	return token, created, nil
+}
+
+func (u *AccessTokenUsecase) ListAccessTokens(ctx context.Context, userID models.UserID) ([]models.AccessToken, error) {
+	return u.repo.ListAccessTokens(ctx, userID)
+}
+
+func (u *AccessTokenUsecase) DeleteAccessToken(ctx context.Context, userID models.UserID, id models.AccessTokenID) error {
+	return u.repo.DeleteAccessToken(ctx, userID, id)
+}
+
+// Authenticate returns the caller of a bearer token, which is a personal access token,
+// an OAuth access token or the access token of a login. It fails with
+// ErrInvalidAccessToken if the token is invalid or expired.
+func (u *AccessTokenUsecase) Authenticate(ctx context.Context, token string) (models.Caller, error) {
+	switch {
+	case strings.HasPrefix(token, accessTokenPrefix):
+		stored, err := u.repo.GetAccessTokenByHash(ctx, auth.HashOpaqueToken(token))
+		if err != nil {
+			return models.Caller{}, err
+		}
+		if stored.Expired() {
+			return models.Caller{}, e.ErrInvalidAccessToken
+		}
+
+		caller, err := u.caller(ctx, stored.UserID, stored.Scopes, fmt.Sprintf("pat:%d", stored.ID), time.Time{})
+		if err != nil {
+			return models.Caller{}, err
+		}
+		if err := u.repo.TouchAccessToken(ctx, stored.ID); err != nil {
+			return models.Caller{}, err
+		}
+		if stored.ExpiresAt != nil {
+			caller.ExpiresAt = *stored.ExpiresAt
+		}
+		return caller, nil
+	case strings.HasPrefix(token, oauthAccessTokenPrefix):
+		grant, err := u.oauth.AuthenticateAccessToken(ctx, token)
+		if err != nil {
+			return models.Caller{}, err
+		}
+		// Tokens are reissued on refresh, so the client's access for the user is the key.
+		caller, err := u.caller(ctx, grant.UserID, grant.Scopes, fmt.Sprintf("oauth:%s:%d", grant.ClientID, grant.UserID), time.Time{})
+		if err != nil {
+			return models.Caller{}, err
+		}
+		caller.ExpiresAt = grant.AccessExpiresAt
+		return caller, nil
+	default:
+		claims, err := u.jwtService.ParseToken(token)
+		if err != nil || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+			return models.Caller{}, e.ErrInvalidAccessToken
+		}
+		if claims.SessionID != "" {
+			revoked, err := u.sessions.RefreshTokenFamilyRevoked(ctx, claims.SessionID)
+			if err != nil {
+				return models.Caller{}, err
+			}
+			if revoked {
+				return models.Caller{}, e.ErrInvalidAccessToken
+			}
+		}
+		// The user is looked up like for the other tokens, rather than trusting the
+		// claims, so that disabling a user, changing their role or resetting their
+		// password takes effect before their tokens expire.
+		caller, err := u.caller(ctx, claims.UserID, nil, "", claims.IssuedAt.Time)
+		if err != nil {
+			return models.Caller{}, err
+		}
+		caller.ExpiresAt = claims.ExpiresAt.Time
+		return caller, nil
+	}
+}
+
+// caller returns the caller of a token that was issued to the user with scopes, as
+// identified by key. The verification policy is applied on every request, as the user's
+// email may have changed since the token was issued, and so are the disabling of users
+// and their current role. A non-zero issuedAt, which logins pass, must not be before
+// the user's password was last changed; personal access tokens survive the change, and
+// OAuth tokens are revoked with it.
+func (u *AccessTokenUsecase) caller(ctx context.Context, userID models.UserID, scopes []models.Scope, key string, issuedAt time.Time) (models.Caller, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, e.ErrUserNotFound) {
+			return models.Caller{}, e.ErrInvalidAccessToken
+		}
+		return models.Caller{}, err
+	}
+	if user.Disabled() {
+		return models.Caller{}, e.ErrUserDisabled
+	}
+	// Issue times have a precision of seconds.
+	if !issuedAt.IsZero() && user.PasswordChangedAt != nil && issuedAt.Before(user.PasswordChangedAt.Truncate(time.Second)) {
+		return models.Caller{}, e.ErrInvalidAccessToken
+	}
+	if u.policy == VerificationRequired && !user.EmailVerified() {
+		return models.Caller{}, e.ErrEmailNotVerified
+	}
+
+	return models.Caller{
+		UserID:      user.ID,
+		Email:       user.Email,
+		ReadOnly:    u.policy == VerificationReadOnly && !user.EmailVerified(),
+		Scopes:      scopes,
+		Key:         key,
+		Role:        user.Role,
+		Permissions: user.Permissions,
+	}, nil
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+)
+
+// AdminUsecase lets staff manage users. Every action, including reads, is recorded in
+// the audit log with the admin who took it. Permissions are checked by the transports.
+type AdminUsecase struct {
+	userRepo  repository.UserRepository
+	adminRepo repository.AdminRepository
+}
+
+func NewAdminUsecase(userRepo repository.UserRepository, adminRepo repository.AdminRepository) *AdminUsecase {
+	return &AdminUsecase{userRepo: userRepo, adminRepo: adminRepo}
+}
+
+// ListUsers returns the users whose username or email contains query.
+func (u *AdminUsecase) ListUsers(ctx context.Context, actor models.UserID, query string, limit, offset int) ([]models.User, error) {
+	users, err := u.userRepo.ListUsers(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{"query": query, "limit": limit, "offset": offset}
+	return users, u.audit(ctx, actor, models.AuditUsersList, nil, details)
+}
+
+func (u *AdminUsecase) GetUser(ctx context.Context, actor, id models.UserID) (models.User, error) {
+	user, err := u.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, u.audit(ctx, actor, models.AuditUserView, &id, nil)
+}
+
+// SetUserDisabled disables or enables a user. Disabled users can't log in or refresh
+// their sessions, and all of their access tokens stop working.
+func (u *AdminUsecase) SetUserDisabled(ctx context.Context, actor, id models.UserID, disabled bool) error {
+	if actor == id {
+		return e.ErrAdminSelfAction
+	}
+	if err := u.userRepo.SetDisabled(ctx, id, disabled); err != nil {
+		return err
+	}
+
+	action := models.AuditUserEnable
+	if disabled {
+		action = models.AuditUserDisable
+	}
+	return u.audit(ctx, actor, action, &id, nil)
+}
+
+// SetUserRole changes a user's role, which applies to their next request whatever
+// token they use.
+func (u *AdminUsecase) SetUserRole(ctx context.Context, actor, id models.UserID, role models.Role) error {
+	if !role.Valid() {
+		return e.ErrInvalidRole
+	}
+	if actor == id {
+		return e.ErrAdminSelfAction
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := u.userRepo.SetRole(ctx, id, role); err != nil {
+		return err
+	}
+
+	return u.audit(ctx, actor, models.AuditUserSetRole, &id, map[string]any{"from": user.Role, "to": role})
+}
+
+// DeleteUser deletes a user and all their data. The audit entry keeps their username
+// and email.
+func (u *AdminUsecase) DeleteUser(ctx context.Context, actor, id models.UserID) error {
+	if actor == id {
+		return e.ErrAdminSelfAction
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := u.userRepo.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	return u.audit(ctx, actor, models.AuditUserDelete, &id, map[string]any{"username": user.Username, "email": user.Email})
+}
+
+func (u *AdminUsecase) Stats(ctx context.Context, actor models.UserID) (models.SystemStats, error) {
+	stats, err := u.adminRepo.Stats(ctx)
+	if err != nil {
+		return models.SystemStats{}, err
+	}
+
+	return stats, u.audit(ctx, actor, models.AuditStatsView, nil, nil)
+}
+
+// ListAuditEntries returns the audit log, newest first.
+func (u *AdminUsecase) ListAuditEntries(ctx context.Context, actor models.UserID, limit, offset int) ([]models.AuditEntry, error) {
+	entries, err := u.adminRepo.ListAuditEntries(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, u.audit(ctx, actor, models.AuditLogView, nil, nil)
+}
+
+func (u *AdminUsecase) audit(ctx context.Context, actor models.UserID, action models.AuditAction, target *models.UserID, details map[string]any) error {
+	entry := models.AuditEntry{ActorID: actor, Action: action, TargetUserID: target}
+	if details != nil {
+		raw, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		entry.Details = raw
+	}
+
+	return u.adminRepo.RecordAuditEntry(ctx, entry)
+}
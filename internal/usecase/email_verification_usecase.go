@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/mailer"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	verifyTokenPurpose = "email_verification"
+	verifyMailTimeout  = 30 * time.Second
+)
+
+type verifyTokenData struct {
+	UserID models.UserID `json:"uid"`
+	Email  string        `json:"email"`
+}
+
+// EmailVerificationUsecase mails signed verification links when users register or change
+// their email. Links carry the address they were sent to, so they stop working once the
+// email changes again.
+type EmailVerificationUsecase struct {
+	userRepo repository.UserRepository
+	signer   *auth.TokenSigner
+	mailer   mailer.Mailer
+	// verifyURL is the page that takes the token from its query and submits it.
+	verifyURL string
+	ttl       time.Duration
+	// resendInterval is the least time between two links mailed to the same user.
+	resendInterval time.Duration
+	logger         *zap.Logger
+
+	wg sync.WaitGroup
+}
+
+func NewEmailVerificationUsecase(userRepo repository.UserRepository, signer *auth.TokenSigner, m mailer.Mailer, verifyURL string, ttl, resendInterval time.Duration, logger *zap.Logger) *EmailVerificationUsecase {
+	return &EmailVerificationUsecase{
+		userRepo:       userRepo,
+		signer:         signer,
+		mailer:         m,
+		verifyURL:      verifyURL,
+		ttl:            ttl,
+		resendInterval: resendInterval,
+		logger:         logger,
+	}
+}
+
+// Publish mails a link to users who registered or changed their email. Mail is sent in
+// the background so that the outbox relay isn't held up by the mail relay; a link that
+// fails to send can be requested again.
+func (u *EmailVerificationUsecase) Publish(ctx context.Context, event models.Event) error {
+	if event.Type != models.EventUserCreated && event.Type != models.EventUserEmailChanged {
+		return nil
+	}
+
+	u.background(ctx, func(ctx context.Context) error {
+		user, err := u.userRepo.GetUserByID(ctx, event.UserID)
+		if err != nil {
+			if errors.Is(err, e.ErrUserNotFound) {
+				return nil
+			}
+			return err
+		}
+		return u.sendLink(ctx, user)
+	})
+	return nil
+}
+
+// Resend mails a new link to email if it belongs to an unverified user, at most once
+// per resend interval. Like password resets, it doesn't reveal whether the email is
+// registered.
+func (u *EmailVerificationUsecase) Resend(ctx context.Context, email string) {
+	u.background(ctx, func(ctx context.Context) error {
+		user, err := u.userRepo.GetUserByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, e.ErrUserNotFound) {
+				return nil
+			}
+			return err
+		}
+		return u.sendLink(ctx, user)
+	})
+}
+
+// Verify marks the email in token as verified.
+func (u *EmailVerificationUsecase) Verify(ctx context.Context, token string) error {
+	var data verifyTokenData
+	if err := u.signer.Verify(verifyTokenPurpose, token, &data); err != nil {
+		return e.ErrInvalidVerifyToken
+	}
+
+	err := u.userRepo.MarkEmailVerified(ctx, data.UserID, data.Email)
+	if errors.Is(err, e.ErrUserNotFound) {
+		return e.ErrInvalidVerifyToken
+	}
+	return err
+}
+
+// Wait blocks until the links being sent are done.
+func (u *EmailVerificationUsecase) Wait() {
+	u.wg.Wait()
+}
+
+func (u *EmailVerificationUsecase) background(ctx context.Context, fn func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), verifyMailTimeout)
+
+	u.wg.Add(1)
+	go func() {
+		defer u.wg.Done()
+		defer cancel()
+
+		if err := fn(ctx); err != nil {
+			u.logger.Error("failed to send verification link", zap.Error(err))
+		}
+	}()
+}
+
+func (u *EmailVerificationUsecase) sendLink(ctx context.Context, user models.User) error {
+	if user.EmailVerified() {
+		return nil
+	}
+
+	ok, err := u.userRepo.ClaimVerificationEmail(ctx, user.ID, u.resendInterval)
+	if err != nil || !ok {
+		return err
+	}
+
+	token, err := u.signer.Sign(verifyTokenPurpose, verifyTokenData{UserID: user.ID, Email: user.Email}, u.ttl)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(u.verifyURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to verify your email address. "+
+			"It expires in %d hours.\n\n%s\n", user.Username, int(u.ttl.Hours()), link),
+	})
+}
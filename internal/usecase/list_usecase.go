@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+)
+
+// ListUsecase shares todo lists. Every user owns one list, identified by their user ID;
+// the members it is shared with can follow and change its todos over the WebSocket API.
+type ListUsecase struct {
+	repo     repository.ListMemberRepository
+	userRepo repository.UserRepository
+}
+
+func NewListUsecase(r repository.ListMemberRepository, userRepo repository.UserRepository) *ListUsecase {
+	return &ListUsecase{repo: r, userRepo: userRepo}
+}
+
+// ShareList shares the list of ownerID with the user registered with email. Emails
+// that don't belong to a user are ignored rather than reported, so that sharing can't
+// be used to find out who is registered.
+func (u *ListUsecase) ShareList(ctx context.Context, ownerID models.UserID, email string) error {
+	user, err := u.userRepo.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		if errors.Is(err, e.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.ID == ownerID {
+		return e.ErrShareWithOwner
+	}
+
+	_, err = u.repo.AddListMember(ctx, ownerID, user.ID)
+	return err
+}
+
+func (u *ListUsecase) UnshareList(ctx context.Context, ownerID, userID models.UserID) error {
+	return u.repo.RemoveListMember(ctx, ownerID, userID)
+}
+
+func (u *ListUsecase) ListMembers(ctx context.Context, ownerID models.UserID) ([]models.ListMember, error) {
+	return u.repo.ListListMembers(ctx, ownerID)
+}
+
+func (u *ListUsecase) ListSharedLists(ctx context.Context, userID models.UserID) ([]models.SharedList, error) {
+	return u.repo.ListSharedLists(ctx, userID)
+}
+
+// CanAccess reports whether userID owns the list of ownerID or is one of its members.
+func (u *ListUsecase) CanAccess(ctx context.Context, userID, ownerID models.UserID) (bool, error) {
+	if userID == ownerID {
+		return true, nil
+	}
+
+	return u.repo.IsListMember(ctx, ownerID, userID)
+}
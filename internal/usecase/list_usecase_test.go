@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+)
+
+type fakeListMemberRepo struct {
+	repository.ListMemberRepository
+	members map[[2]models.UserID]bool
+}
+
+func (r *fakeListMemberRepo) AddListMember(_ context.Context, ownerID, userID models.UserID) (models.ListMember, error) {
+	r.members[[2]models.UserID{ownerID, userID}] = true
+	return models.ListMember{OwnerID: ownerID, UserID: userID}, nil
+}
+
+func TestShareListDoesNotRevealRegisteredEmails(t *testing.T) {
+	members := &fakeListMemberRepo{members: make(map[[2]models.UserID]bool)}
+	users := &fakeUserRepo{users: map[models.UserID]models.User{
+		1: {ID: 1, Email: "alice@example.com"},
+		2: {ID: 2, Email: "bob@example.com"},
+	}}
+	uc := NewListUsecase(members, users)
+
+	// Sharing with a registered and an unknown email look the same to the owner.
+	if err := uc.ShareList(context.Background(), 1, "bob@example.com"); err != nil {
+		t.Fatalf("share with bob: %v", err)
+	}
+	if err := uc.ShareList(context.Background(), 1, "nobody@example.com"); err != nil {
+		t.Fatalf("share with unknown email: %v", err)
+	}
+	if len(members.members) != 1 || !members.members[[2]models.UserID{1, 2}] {
+		t.Fatalf("members = %v, want only bob", members.members)
+	}
+
+	if err := uc.ShareList(context.Background(), 1, " alice@example.com "); !errors.Is(err, e.ErrShareWithOwner) {
+		t.Fatalf("share with owner: err = %v, want ErrShareWithOwner", err)
+	}
+}
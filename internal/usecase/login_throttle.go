@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/mailer"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	unlockTokenPurpose = "account_unlock"
+	unlockMailTimeout  = 30 * time.Second
+
+	// After loginDelayAfter failed logins in a row, an account waits loginBaseDelay
+	// before the next attempt, doubling with each further failure.
+	loginDelayAfter = 3
+	loginBaseDelay  = time.Second
+)
+
+// unlockTokenData binds the token to the lockout it was sent for by the time of the
+// failure that locked the account, so that it can't unlock the account again.
+type unlockTokenData struct {
+	Email    string `json:"email"`
+	LockedAt int64  `json:"locked_at"`
+}
+
+// LoginThrottle counts failed logins per account and per client address. Accounts are
+// slowed down with growing delays and then locked, and addresses are locked, until no
+// login has failed for the lockout period. Locked accounts are mailed a link that
+// unlocks them early.
+//
+// Accounts are tracked by email whether or not a user has it, so that lockouts don't
+// reveal which emails are registered.
+type LoginThrottle struct {
+	repo     repository.LoginAttemptRepository
+	userRepo repository.UserRepository
+	signer   *auth.TokenSigner
+	mailer   mailer.Mailer
+	// unlockURL is the page that takes the token from its query and submits it.
+	unlockURL string
+	// maxFailures locks an account, and maxIPFailures a client address.
+	maxFailures   int
+	maxIPFailures int
+	lockout       time.Duration
+	logger        *zap.Logger
+
+	wg sync.WaitGroup
+}
+
+func NewLoginThrottle(repo repository.LoginAttemptRepository, userRepo repository.UserRepository, signer *auth.TokenSigner, m mailer.Mailer, unlockURL string, maxFailures, maxIPFailures int, lockout time.Duration, logger *zap.Logger) *LoginThrottle {
+	return &LoginThrottle{
+		repo:          repo,
+		userRepo:      userRepo,
+		signer:        signer,
+		mailer:        m,
+		unlockURL:     unlockURL,
+		maxFailures:   maxFailures,
+		maxIPFailures: maxIPFailures,
+		lockout:       lockout,
+		logger:        logger,
+	}
+}
+
+// Check fails with a RetryError for ErrLoginLocked if logins for email or from ip have to
+// wait. ip may be empty if it isn't known.
+func (t *LoginThrottle) Check(ctx context.Context, email, ip string) error {
+	keys := []string{accountKey(email)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+
+	attempts, err := t.repo.GetLoginAttempts(ctx, keys, t.lockout)
+	if err != nil {
+		return err
+	}
+
+	var wait time.Duration
+	for _, a := range attempts {
+		var until time.Time
+		if strings.HasPrefix(a.Key, "ip:") {
+			if a.Failures < t.maxIPFailures {
+				continue
+			}
+			until = a.LastFailedAt.Add(t.lockout)
+		} else {
+			until = a.LastFailedAt.Add(t.accountDelay(a.Failures))
+		}
+		wait = max(wait, time.Until(until))
+	}
+
+	if wait > 0 {
+		return &e.RetryError{Err: e.ErrLoginLocked, RetryAfter: wait}
+	}
+	return nil
+}
+
+// Attempt runs a login for email from ip, with verify checking the credentials. It
+// fails with a RetryError for ErrLoginLocked without calling verify if logins for email
+// or from ip have to wait, and with ErrInvalidIdentifier if verify reports false.
+//
+// The attempt is counted as failed before verify is called, and taken back if it
+// succeeds, so that concurrent attempts can't get past the lockout.
+func (t *LoginThrottle) Attempt(ctx context.Context, email, ip string, verify func() (bool, error)) error {
+	if err := t.Check(ctx, email, ip); err != nil {
+		return err
+	}
+
+	var keys []string
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	keys = append(keys, accountKey(email))
+
+	var account models.LoginAttempts
+	for i, key := range keys {
+		limit := t.maxFailures
+		if strings.HasPrefix(key, "ip:") {
+			limit = t.maxIPFailures
+		}
+
+		a, reserved, err := t.repo.ReserveLoginAttempt(ctx, key, limit, t.lockout)
+		if err == nil && !reserved {
+			err = &e.RetryError{Err: e.ErrLoginLocked, RetryAfter: time.Until(a.LastFailedAt.Add(t.lockout))}
+		}
+		if err != nil {
+			return errors.Join(err, t.release(ctx, keys[:i]))
+		}
+		account = a
+	}
+
+	ok, err := verify()
+	if err != nil {
+		return errors.Join(err, t.release(ctx, keys))
+	}
+	if !ok {
+		if account.Failures == t.maxFailures {
+			t.sendUnlockLink(ctx, email, account.LastFailedAt)
+		}
+		return e.ErrInvalidIdentifier
+	}
+
+	// The failures of the client address only expire, so that an attacker can't reset
+	// them with an account of their own.
+	if ip != "" {
+		if err := t.repo.ReleaseLoginAttempt(ctx, ipKey(ip)); err != nil {
+			return err
+		}
+	}
+	return t.repo.ResetLoginAttempts(ctx, accountKey(email))
+}
+
+// VerifyPassword checks the password that a signed-in user confirms a sensitive change
+// with. Wrong passwords count as failed logins of the user's account, so that a stolen
+// session can't be used to guess the password faster than logging in could.
+func (t *LoginThrottle) VerifyPassword(ctx context.Context, user models.User, password string) error {
+	return t.Attempt(ctx, user.Email, "", func() (bool, error) {
+		return auth.VerifyPassword(password, user.PasswordHash)
+	})
+}
+
+// release takes back the attempts reserved for keys.
+func (t *LoginThrottle) release(ctx context.Context, keys []string) error {
+	var errs []error
+	for _, key := range keys {
+		errs = append(errs, t.repo.ReleaseLoginAttempt(ctx, key))
+	}
+	return errors.Join(errs...)
+}
+
+// Unlock clears the failed logins of the account in token, if it is still locked by
+// the lockout the token was sent for.
+func (t *LoginThrottle) Unlock(ctx context.Context, token string) error {
+	var data unlockTokenData
+	if err := t.signer.Verify(unlockTokenPurpose, token, &data); err != nil {
+		return e.ErrInvalidUnlockToken
+	}
+
+	key := accountKey(data.Email)
+	attempts, err := t.repo.GetLoginAttempts(ctx, []string{key}, t.lockout)
+	if err != nil {
+		return err
+	}
+	if len(attempts) == 0 || attempts[0].Failures < t.maxFailures || attempts[0].LastFailedAt.UnixMicro() != data.LockedAt {
+		return e.ErrInvalidUnlockToken
+	}
+	return t.repo.ResetLoginAttempts(ctx, key)
+}
+
+// Wait blocks until the links being sent are done.
+func (t *LoginThrottle) Wait() {
+	t.wg.Wait()
+}
+
+// accountDelay is how long an account waits after its last failure.
+func (t *LoginThrottle) accountDelay(failures int) time.Duration {
+	if failures >= t.maxFailures {
+		return t.lockout
+	}
+	if failures < loginDelayAfter {
+		return 0
+	}
+	// Shifts past the lockout would overflow; the lockout caps the delay anyway.
+	shift := min(failures-loginDelayAfter, 30)
+	return min(loginBaseDelay<<shift, t.lockout)
+}
+
+// sendUnlockLink mails the link in the background, so that locking an account takes as
+// long whether or not a user has the email.
+func (t *LoginThrottle) sendUnlockLink(ctx context.Context, email string, lockedAt time.Time) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unlockMailTimeout)
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer cancel()
+
+		if err := t.mailUnlockLink(ctx, email, lockedAt); err != nil {
+			t.logger.Error("failed to send unlock link", zap.Error(err))
+		}
+	}()
+}
+
+func (t *LoginThrottle) mailUnlockLink(ctx context.Context, email string, lockedAt time.Time) error {
+	user, err := t.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, e.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := t.signer.Sign(unlockTokenPurpose, unlockTokenData{Email: email, LockedAt: lockedAt.UnixMicro()}, t.lockout)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(t.unlockURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return t.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf("Hi %s,\n\nThere were too many failed attempts to log in to your account, "+
+			"so logins are paused for %d minutes. If this was you, open the link below to unlock "+
+			"your account now. If not, consider changing your password.\n\n%s\n",
+			user.Username, int(t.lockout.Minutes()), link),
+	})
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
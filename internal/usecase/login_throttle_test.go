@@ -0,0 +1,292 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/mailer"
+	"go.uber.org/zap"
+)
+
+const (
+	testMaxFailures   = 5
+	testMaxIPFailures = 100
+	testLockout       = 15 * time.Minute
+)
+
+// fakeLoginAttemptRepo keeps its own clock, which advances a second with every failure,
+// so that lockouts are told apart like the microseconds of Postgres do. It starts a few
+// minutes back, so that the delays before a lockout have passed when it is checked.
+type fakeLoginAttemptRepo struct {
+	mu       sync.Mutex
+	now      time.Time
+	attempts map[string]models.LoginAttempts
+}
+
+func (r *fakeLoginAttemptRepo) GetLoginAttempts(_ context.Context, keys []string, window time.Duration) ([]models.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var attempts []models.LoginAttempts
+	for _, key := range keys {
+		if a, ok := r.attempts[key]; ok && a.LastFailedAt.After(r.now.Add(-window)) {
+			attempts = append(attempts, a)
+		}
+	}
+	return attempts, nil
+}
+
+func (r *fakeLoginAttemptRepo) ReserveLoginAttempt(_ context.Context, key string, limit int, window time.Duration) (models.LoginAttempts, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.attempts[key]
+	if ok && a.Failures >= limit && a.LastFailedAt.After(r.now.Add(-window)) {
+		return a, false, nil
+	}
+
+	r.now = r.now.Add(time.Second)
+	if !ok || !a.LastFailedAt.After(r.now.Add(-window)) {
+		a = models.LoginAttempts{Key: key}
+	}
+	a.Failures++
+	a.LastFailedAt = r.now
+	r.attempts[key] = a
+	return a, true, nil
+}
+
+func (r *fakeLoginAttemptRepo) ReleaseLoginAttempt(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a, ok := r.attempts[key]; ok && a.Failures > 0 {
+		a.Failures--
+		r.attempts[key] = a
+	}
+	return nil
+}
+
+func (r *fakeLoginAttemptRepo) failures(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.attempts[key].Failures
+}
+
+func (r *fakeLoginAttemptRepo) ResetLoginAttempts(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func newTestLoginThrottle() (*LoginThrottle, *fakeLoginAttemptRepo, *fakeMailer) {
+	repo := &fakeLoginAttemptRepo{
+		now:      time.Now().Add(-5 * time.Minute),
+		attempts: make(map[string]models.LoginAttempts),
+	}
+	users := &fakeUserRepo{users: map[models.UserID]models.User{
+		7: {ID: 7, Username: "alice", Email: "alice@example.com"},
+	}}
+	m := &fakeMailer{}
+	t := NewLoginThrottle(repo, users, auth.NewTokenSigner("secret"), m,
+		"https://app.example.com/unlock", testMaxFailures, testMaxIPFailures, testLockout, zap.NewNop())
+	return t, repo, m
+}
+
+func deny() (bool, error) {
+	return false, nil
+}
+
+// lockAccount fails logins for email until the account is locked, and returns the
+// token of the unlock link that was mailed.
+func lockAccount(t *testing.T, throttle *LoginThrottle, m *fakeMailer, email string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	for range testMaxFailures {
+		if err := throttle.Attempt(ctx, email, "", deny); !errors.Is(err, e.ErrInvalidIdentifier) {
+			t.Fatalf("got %v for a failed login, want %v", err, e.ErrInvalidIdentifier)
+		}
+	}
+	throttle.Wait()
+	if err := throttle.Check(ctx, email, ""); !errors.Is(err, e.ErrLoginLocked) {
+		t.Fatalf("got %v after %d failures, want %v", err, testMaxFailures, e.ErrLoginLocked)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.sent) == 0 {
+		t.Fatal("no unlock link was mailed")
+	}
+	// The link ends the body.
+	words := strings.Fields(m.sent[len(m.sent)-1].Body)
+	link, err := url.Parse(words[len(words)-1])
+	if err != nil {
+		t.Fatalf("parse unlock link: %v", err)
+	}
+	return link.Query().Get("token")
+}
+
+func TestLoginThrottleUnlock(t *testing.T) {
+	throttle, _, m := newTestLoginThrottle()
+	ctx := context.Background()
+
+	token := lockAccount(t, throttle, m, "alice@example.com")
+	if err := throttle.Unlock(ctx, "forged"); !errors.Is(err, e.ErrInvalidUnlockToken) {
+		t.Fatalf("got %v for a forged token, want %v", err, e.ErrInvalidUnlockToken)
+	}
+	if err := throttle.Unlock(ctx, token); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if err := throttle.Check(ctx, "alice@example.com", ""); err != nil {
+		t.Fatalf("still locked after unlocking: %v", err)
+	}
+}
+
+func TestLoginThrottleUnlockTokenIsSingleUse(t *testing.T) {
+	throttle, _, m := newTestLoginThrottle()
+	ctx := context.Background()
+
+	token := lockAccount(t, throttle, m, "alice@example.com")
+	if err := throttle.Unlock(ctx, token); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if err := throttle.Unlock(ctx, token); !errors.Is(err, e.ErrInvalidUnlockToken) {
+		t.Fatalf("got %v for a used token, want %v", err, e.ErrInvalidUnlockToken)
+	}
+
+	// An attacker who got hold of the link can't use it to lift the next lockout.
+	lockAccount(t, throttle, m, "alice@example.com")
+	if err := throttle.Unlock(ctx, token); !errors.Is(err, e.ErrInvalidUnlockToken) {
+		t.Fatalf("got %v for the token of an earlier lockout, want %v", err, e.ErrInvalidUnlockToken)
+	}
+	if err := throttle.Check(ctx, "alice@example.com", ""); !errors.Is(err, e.ErrLoginLocked) {
+		t.Fatalf("got %v, want the account to stay locked", err)
+	}
+}
+
+// attemptConcurrently runs n failing attempts, from ip, for emails picked by i. The
+// checks of the attempts that get through wait until every attempt has either reached
+// its check or been refused, so that none of them fails before all have begun. It
+// returns how many were checked.
+func attemptConcurrently(t *testing.T, throttle *LoginThrottle, n int, ip string, email func(i int) string) int {
+	t.Helper()
+	ctx := context.Background()
+
+	var (
+		mu      sync.Mutex
+		checked int
+		arrived sync.WaitGroup
+		done    sync.WaitGroup
+	)
+	release := make(chan struct{})
+	arrived.Add(n)
+	for i := range n {
+		done.Go(func() {
+			var reached bool
+			err := throttle.Attempt(ctx, email(i), ip, func() (bool, error) {
+				mu.Lock()
+				checked++
+				mu.Unlock()
+
+				reached = true
+				arrived.Done()
+				<-release
+				return false, nil
+			})
+			if !reached {
+				arrived.Done()
+			}
+			if !errors.Is(err, e.ErrInvalidIdentifier) && !errors.Is(err, e.ErrLoginLocked) {
+				t.Errorf("attempt: %v", err)
+			}
+		})
+	}
+	arrived.Wait()
+	close(release)
+	done.Wait()
+	throttle.Wait()
+
+	return checked
+}
+
+func TestLoginThrottleConcurrentAttempts(t *testing.T) {
+	t.Run("account", func(t *testing.T) {
+		throttle, _, _ := newTestLoginThrottle()
+
+		checked := attemptConcurrently(t, throttle, 4*testMaxFailures, "", func(int) string { return "alice@example.com" })
+		if checked == 0 || checked > testMaxFailures {
+			t.Fatalf("%d concurrent attempts were checked, want at most %d", checked, testMaxFailures)
+		}
+	})
+
+	t.Run("address", func(t *testing.T) {
+		throttle, _, _ := newTestLoginThrottle()
+
+		// Every attempt is for another account, so only the address can lock them out.
+		checked := attemptConcurrently(t, throttle, 2*testMaxIPFailures, "192.0.2.1", func(i int) string {
+			return "user" + strconv.Itoa(i) + "@example.com"
+		})
+		if checked != testMaxIPFailures {
+			t.Fatalf("%d concurrent attempts were checked, want %d", checked, testMaxIPFailures)
+		}
+	})
+}
+
+func TestLoginThrottleReleasesSuccessfulAttempts(t *testing.T) {
+	throttle, repo, _ := newTestLoginThrottle()
+	ctx := context.Background()
+	const ip = "192.0.2.1"
+
+	for range 2 {
+		if err := throttle.Attempt(ctx, "alice@example.com", ip, deny); !errors.Is(err, e.ErrInvalidIdentifier) {
+			t.Fatalf("got %v for a failed login, want %v", err, e.ErrInvalidIdentifier)
+		}
+	}
+
+	// A success clears the failures of the account, and takes back only its own
+	// attempt from the address.
+	err := throttle.Attempt(ctx, "alice@example.com", ip, func() (bool, error) { return true, nil })
+	if err != nil {
+		t.Fatalf("successful login: %v", err)
+	}
+	if got := repo.failures(accountKey("alice@example.com")); got != 0 {
+		t.Fatalf("account has %d failures after a success, want 0", got)
+	}
+	if got := repo.failures(ipKey(ip)); got != 2 {
+		t.Fatalf("address has %d failures after a success, want 2", got)
+	}
+
+	// Attempts that couldn't be checked don't count.
+	errCheck := errors.New("database is down")
+	if err := throttle.Attempt(ctx, "bob@example.com", ip, func() (bool, error) { return false, errCheck }); !errors.Is(err, errCheck) {
+		t.Fatalf("got %v, want %v", err, errCheck)
+	}
+	if a, b := repo.failures(accountKey("bob@example.com")), repo.failures(ipKey(ip)); a != 0 || b != 2 {
+		t.Fatalf("got %d failures for the account and %d for the address after an error, want 0 and 2", a, b)
+	}
+}
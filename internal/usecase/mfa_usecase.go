@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	mfaTokenPurpose = "mfa_challenge"
+	mfaTokenTTL     = 5 * time.Minute
+
+	totpPeriod = 30
+	// totpSkew accepts codes of the adjacent time steps, for clocks that drift.
+	totpSkew  = 1
+	totpQRPx  = 256
+	totpCodeN = 6
+
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 10
+
+	// After maxMFAFailures wrong codes in a row, codes are refused for mfaLockout.
+	maxMFAFailures = 5
+	mfaLockout     = 15 * time.Minute
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// mfaTokenData identifies the token by ID, so that it can be used only once.
+type mfaTokenData struct {
+	ID     string        `json:"jti"`
+	UserID models.UserID `json:"uid"`
+}
+
+// MFAUsecase manages TOTP authenticators and the second step of logging in. TOTP
+// secrets are encrypted at rest; without an encryption key, 2FA can't be enabled.
+type MFAUsecase struct {
+	repo     repository.MFARepository
+	userRepo repository.UserRepository
+	// cipher is nil when no encryption key is configured.
+	cipher *auth.Cipher
+	signer *auth.TokenSigner
+	// throttle counts wrong passwords when disabling 2FA.
+	throttle *LoginThrottle
+	// issuer names the app in authenticators.
+	issuer string
+}
+
+func NewMFAUsecase(repo repository.MFARepository, userRepo repository.UserRepository, cipher *auth.Cipher, signer *auth.TokenSigner, throttle *LoginThrottle, issuer string) *MFAUsecase {
+	return &MFAUsecase{repo: repo, userRepo: userRepo, cipher: cipher, signer: signer, throttle: throttle, issuer: issuer}
+}
+
+// EnrollTOTP generates a new secret for the user. It isn't asked for at login until
+// ConfirmTOTP proves the user's authenticator produces valid codes.
+func (u *MFAUsecase) EnrollTOTP(ctx context.Context, userID models.UserID) (dto.TOTPEnrollment, error) {
+	if u.cipher == nil {
+		return dto.TOTPEnrollment{}, e.ErrMFAUnavailable
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return dto.TOTPEnrollment{}, err
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      u.issuer,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return dto.TOTPEnrollment{}, err
+	}
+
+	secret, err := u.cipher.Seal([]byte(key.Secret()), secretAAD(userID))
+	if err != nil {
+		return dto.TOTPEnrollment{}, err
+	}
+	if err := u.repo.SaveTOTP(ctx, models.TOTPCredential{UserID: userID, Secret: secret}); err != nil {
+		return dto.TOTPEnrollment{}, err
+	}
+
+	img, err := key.Image(totpQRPx, totpQRPx)
+	if err != nil {
+		return dto.TOTPEnrollment{}, err
+	}
+	var qr bytes.Buffer
+	if err := png.Encode(&qr, img); err != nil {
+		return dto.TOTPEnrollment{}, err
+	}
+
+	return dto.TOTPEnrollment{Secret: key.Secret(), OTPAuthURI: key.URL(), QRCodePNG: qr.Bytes()}, nil
+}
+
+// ConfirmTOTP enables the enrolled authenticator with its first code, and returns the
+// recovery codes. They are only stored hashed, so this is the only time they are shown.
+func (u *MFAUsecase) ConfirmTOTP(ctx context.Context, userID models.UserID, code string) ([]string, error) {
+	cred, err := u.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if cred.Confirmed() {
+		return nil, e.ErrTOTPAlreadyEnabled
+	}
+
+	step, ok, err := u.matchCode(cred, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, e.ErrInvalidMFACode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = raw[:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:]
+		hashes[i] = auth.HashOpaqueToken(raw)
+	}
+
+	if err := u.repo.ConfirmTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP removes the user's authenticator and recovery codes. The user has to
+// confirm their password, which is throttled like logins.
+func (u *MFAUsecase) DisableTOTP(ctx context.Context, userID models.UserID, password string) error {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.throttle.VerifyPassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	return u.repo.DeleteTOTP(ctx, userID)
+}
+
+// Required reports whether logging in as the user takes a second factor.
+func (u *MFAUsecase) Required(ctx context.Context, userID models.UserID) (bool, error) {
+	cred, err := u.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, e.ErrTOTPNotEnabled) {
+			return false, nil
+		}
+		return false, err
+	}
+	return cred.Confirmed(), nil
+}
+
+// Challenge returns the token that proves the user passed the first step of logging in.
+func (u *MFAUsecase) Challenge(userID models.UserID) (string, error) {
+	id, err := auth.GenerateID()
+	if err != nil {
+		return "", err
+	}
+	return u.signer.Sign(mfaTokenPurpose, mfaTokenData{ID: id, UserID: userID}, mfaTokenTTL)
+}
+
+// VerifyChallenge checks a TOTP or recovery code against the user of token, and
+// returns the user. Codes and tokens can only be used once.
+//
+// Attempts are counted as failed before the code is checked, so that concurrent
+// guesses can't get past the lockout.
+func (u *MFAUsecase) VerifyChallenge(ctx context.Context, token, code string) (models.UserID, error) {
+	var data mfaTokenData
+	if err := u.signer.Verify(mfaTokenPurpose, token, &data); err != nil || data.ID == "" {
+		return 0, e.ErrInvalidMFAToken
+	}
+
+	cred, err := u.repo.GetTOTP(ctx, data.UserID)
+	if err != nil {
+		if errors.Is(err, e.ErrTOTPNotEnabled) {
+			return 0, e.ErrInvalidMFAToken
+		}
+		return 0, err
+	}
+	if !cred.Confirmed() {
+		return 0, e.ErrInvalidMFAToken
+	}
+
+	reserved, err := u.repo.ReserveMFAAttempt(ctx, data.UserID, maxMFAFailures, mfaLockout)
+	if err != nil {
+		return 0, err
+	}
+	if !reserved {
+		return 0, e.ErrMFALocked
+	}
+
+	used, err := u.useCode(ctx, cred, code)
+	if err != nil {
+		return 0, err
+	}
+	if !used {
+		return 0, e.ErrInvalidMFACode
+	}
+
+	// The token outlives its use by at most its TTL.
+	fresh, err := u.repo.UseMFAChallenge(ctx, data.ID, time.Now().Add(mfaTokenTTL))
+	if err != nil {
+		return 0, err
+	}
+	if !fresh {
+		return 0, e.ErrInvalidMFAToken
+	}
+
+	return data.UserID, nil
+}
+
+func (u *MFAUsecase) useCode(ctx context.Context, cred models.TOTPCredential, code string) (bool, error) {
+	if len(code) == totpCodeN {
+		step, ok, err := u.matchCode(cred, code)
+		if err != nil || !ok {
+			return false, err
+		}
+		return u.repo.UseTOTPStep(ctx, cred.UserID, step)
+	}
+
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return u.repo.UseRecoveryCode(ctx, cred.UserID, auth.HashOpaqueToken(normalized))
+}
+
+// matchCode returns the time step that code is valid for. Steps up to the last used
+// one are skipped, so a code can't be replayed.
+func (u *MFAUsecase) matchCode(cred models.TOTPCredential, code string) (int64, bool, error) {
+	if u.cipher == nil {
+		return 0, false, e.ErrMFAUnavailable
+	}
+
+	secret, err := u.cipher.Open(cred.Secret, secretAAD(cred.UserID))
+	if err != nil {
+		return 0, false, err
+	}
+
+	now := time.Now().Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if step <= cred.LastStep {
+			continue
+		}
+
+		want, err := hotp.GenerateCodeCustom(string(secret), uint64(step), hotp.ValidateOpts{
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func secretAAD(userID models.UserID) []byte {
+	return []byte(strconv.FormatInt(int64(userID), 10))
+}
@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"github.com/pquerna/otp/totp"
+)
+
+// testRecoveryCode is the recovery code newMFAFixture gives the user.
+const testRecoveryCode = "aaaa-bbbb-cccc-dddd"
+
+type fakeMFARepo struct {
+	repository.MFARepository
+
+	mu         sync.Mutex
+	creds      map[models.UserID]models.TOTPCredential
+	codes      map[string]bool
+	challenges map[string]bool
+}
+
+func (r *fakeMFARepo) GetTOTP(_ context.Context, userID models.UserID) (models.TOTPCredential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cred, ok := r.creds[userID]
+	if !ok {
+		return models.TOTPCredential{}, e.ErrTOTPNotEnabled
+	}
+	return cred, nil
+}
+
+func (r *fakeMFARepo) DeleteTOTP(_ context.Context, userID models.UserID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.creds[userID]; !ok {
+		return e.ErrTOTPNotEnabled
+	}
+	delete(r.creds, userID)
+	return nil
+}
+
+func (r *fakeMFARepo) UseTOTPStep(_ context.Context, userID models.UserID, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cred, ok := r.creds[userID]
+	if !ok || cred.LastStep >= step {
+		return false, nil
+	}
+	cred.LastStep = step
+	cred.FailedAttempts = 0
+	cred.LastFailedAt = nil
+	r.creds[userID] = cred
+	return true, nil
+}
+
+func (r *fakeMFARepo) UseRecoveryCode(_ context.Context, userID models.UserID, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.codes[codeHash] {
+		return false, nil
+	}
+	delete(r.codes, codeHash)
+	cred := r.creds[userID]
+	cred.FailedAttempts = 0
+	cred.LastFailedAt = nil
+	r.creds[userID] = cred
+	return true, nil
+}
+
+func (r *fakeMFARepo) ReserveMFAAttempt(_ context.Context, userID models.UserID, maxFailures int, lockout time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cred, ok := r.creds[userID]
+	if !ok || !cred.Confirmed() {
+		return false, nil
+	}
+	if cred.FailedAttempts >= maxFailures && cred.LastFailedAt != nil && time.Since(*cred.LastFailedAt) < lockout {
+		return false, nil
+	}
+	now := time.Now()
+	cred.FailedAttempts++
+	cred.LastFailedAt = &now
+	r.creds[userID] = cred
+	return true, nil
+}
+
+func (r *fakeMFARepo) UseMFAChallenge(_ context.Context, id string, _ time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.challenges[id] {
+		return false, nil
+	}
+	r.challenges[id] = true
+	return true, nil
+}
+
+type mfaFixture struct {
+	mfa  *MFAUsecase
+	repo *fakeMFARepo
+	// secret is the user's TOTP secret.
+	secret string
+}
+
+// newMFAFixture returns the usecase for user 7, alice@example.com, who has 2FA enabled
+// and the password "password".
+func newMFAFixture(t *testing.T) mfaFixture {
+	t.Helper()
+
+	cipher, err := auth.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "test", AccountName: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := cipher.Seal([]byte(key.Secret()), secretAAD(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := auth.HashPassword("password", auth.ArgonParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	confirmed := time.Now()
+	repo := &fakeMFARepo{
+		creds: map[models.UserID]models.TOTPCredential{
+			7: {UserID: 7, Secret: sealed, ConfirmedAt: &confirmed},
+		},
+		codes:      map[string]bool{auth.HashOpaqueToken("aaaabbbbccccdddd"): true},
+		challenges: make(map[string]bool),
+	}
+	users := &fakeUserRepo{users: map[models.UserID]models.User{
+		7: {ID: 7, Username: "alice", Email: "alice@example.com", PasswordHash: hash},
+	}}
+	throttle, _, _ := newTestLoginThrottle()
+
+	return mfaFixture{
+		mfa:    NewMFAUsecase(repo, users, cipher, auth.NewTokenSigner("secret"), throttle, "test"),
+		repo:   repo,
+		secret: key.Secret(),
+	}
+}
+
+func (f mfaFixture) code(t *testing.T) string {
+	t.Helper()
+
+	code, err := totp.GenerateCode(f.secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestVerifyChallengeTokenIsSingleUse(t *testing.T) {
+	f := newMFAFixture(t)
+	ctx := context.Background()
+
+	token, err := f.mfa.Challenge(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.mfa.VerifyChallenge(ctx, token, f.code(t)); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// A valid code doesn't make a used token log in again.
+	if _, err := f.mfa.VerifyChallenge(ctx, token, testRecoveryCode); !errors.Is(err, e.ErrInvalidMFAToken) {
+		t.Fatalf("got %v for a used token, want %v", err, e.ErrInvalidMFAToken)
+	}
+}
+
+func TestVerifyChallengeLocksOutConcurrentGuesses(t *testing.T) {
+	f := newMFAFixture(t)
+	ctx := context.Background()
+
+	token, err := f.mfa.Challenge(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const guesses = 4 * maxMFAFailures
+	errs := make(chan error, guesses)
+	var wg sync.WaitGroup
+	for range guesses {
+		wg.Go(func() {
+			// Not digits, so it never matches.
+			_, err := f.mfa.VerifyChallenge(ctx, token, "abcdef")
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	var checked int
+	for err := range errs {
+		switch {
+		case errors.Is(err, e.ErrInvalidMFACode):
+			checked++
+		case !errors.Is(err, e.ErrMFALocked):
+			t.Fatalf("guess: %v", err)
+		}
+	}
+	if checked != maxMFAFailures {
+		t.Fatalf("%d of %d concurrent guesses were checked, want %d", checked, guesses, maxMFAFailures)
+	}
+
+	if _, err := f.mfa.VerifyChallenge(ctx, token, f.code(t)); !errors.Is(err, e.ErrMFALocked) {
+		t.Fatalf("got %v for the right code during the lockout, want %v", err, e.ErrMFALocked)
+	}
+}
+
+func TestDisableTOTPThrottlesPasswords(t *testing.T) {
+	f := newMFAFixture(t)
+	ctx := context.Background()
+
+	// Wrong passwords are delayed like failed logins, and then refused.
+	failures := 0
+	for ; failures <= testMaxFailures; failures++ {
+		err := f.mfa.DisableTOTP(ctx, 7, "wrong")
+		if errors.Is(err, e.ErrLoginLocked) {
+			break
+		}
+		if !errors.Is(err, e.ErrInvalidIdentifier) {
+			t.Fatalf("got %v for a wrong password, want %v", err, e.ErrInvalidIdentifier)
+		}
+	}
+	if failures > testMaxFailures {
+		t.Fatalf("wrong passwords weren't throttled after %d failures", failures)
+	}
+	if err := f.mfa.DisableTOTP(ctx, 7, "password"); !errors.Is(err, e.ErrLoginLocked) {
+		t.Fatalf("got %v for the right password after %d wrong ones, want %v", err, failures, e.ErrLoginLocked)
+	}
+	if _, err := f.repo.GetTOTP(ctx, 7); err != nil {
+		t.Fatalf("2FA was disabled while the account was locked: %v", err)
+	}
+}
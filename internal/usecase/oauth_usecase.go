@@ -0,0 +1,483 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oauthAccessTokenPrefix  = "oat_"
+	oauthRefreshTokenPrefix = "ort_"
+	oauthCodePrefix         = "oac_"
+	oauthClientSecretPrefix = "ocs_"
+	oauthRequestPurpose     = "oauth-authorize"
+	// OAuthRequestTTL is how long the user has to consent to a client's request.
+	OAuthRequestTTL = 10 * time.Minute
+	oauthCodeTTL    = time.Minute
+)
+
+// oauthRequest is a validated authorization request, carried by the consent page in a
+// signed token until the user decides.
+type oauthRequest struct {
+	ClientID      string         `json:"client_id"`
+	RedirectURI   string         `json:"redirect_uri"`
+	Scopes        []models.Scope `json:"scopes"`
+	State         string         `json:"state"`
+	CodeChallenge string         `json:"code_challenge"`
+}
+
+// OAuthUsecase is an OAuth 2.0 authorization server for third-party clients. It
+// implements the authorization code flow with mandatory PKCE, refresh tokens that
+// rotate on use, revocation (RFC 7009) and introspection (RFC 7662). The access tokens
+// are limited to the scopes the user consented to.
+type OAuthUsecase struct {
+	repo       repository.OAuthRepository
+	signer     *auth.TokenSigner
+	consentURL string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewOAuthUsecase(repo repository.OAuthRepository, signer *auth.TokenSigner, consentURL string, accessTTL, refreshTTL time.Duration) *OAuthUsecase {
+	return &OAuthUsecase{repo: repo, signer: signer, consentURL: consentURL, accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
+
+// RegisterClient registers a client of the user. Confidential clients get a secret,
+// which is only returned here.
+func (u *OAuthUsecase) RegisterClient(ctx context.Context, userID models.UserID, name string, redirectURIs []string, confidential bool) (models.OAuthClient, string, error) {
+	for _, uri := range redirectURIs {
+		if !validRedirectURI(uri) {
+			return models.OAuthClient{}, "", e.ErrInvalidRedirectURI
+		}
+	}
+
+	id, err := auth.GenerateID()
+	if err != nil {
+		return models.OAuthClient{}, "", err
+	}
+	client := models.OAuthClient{ID: id, UserID: userID, Name: name, RedirectURIs: redirectURIs}
+
+	var secret string
+	if confidential {
+		secret, client.SecretHash, err = auth.GenerateOpaqueToken(oauthClientSecretPrefix)
+		if err != nil {
+			return models.OAuthClient{}, "", err
+		}
+	}
+
+	client, err = u.repo.CreateClient(ctx, client)
+	if err != nil {
+		return models.OAuthClient{}, "", err
+	}
+	return client, secret, nil
+}
+
+// validRedirectURI accepts absolute https URIs, and http ones on the loopback interface
+// for native apps. Fragments aren't allowed, as the code is appended to the query.
+func validRedirectURI(raw string) bool {
+	uri, err := url.Parse(raw)
+	if err != nil || uri.Host == "" || uri.Fragment != "" || uri.User != nil {
+		return false
+	}
+
+	switch uri.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := uri.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	default:
+		return false
+	}
+}
+
+func (u *OAuthUsecase) ListClients(ctx context.Context, userID models.UserID) ([]models.OAuthClient, error) {
+	return u.repo.ListClients(ctx, userID)
+}
+
+func (u *OAuthUsecase) DeleteClient(ctx context.Context, userID models.UserID, clientID string) error {
+	return u.repo.DeleteClient(ctx, userID, clientID)
+}
+
+// Authorize validates a client's authorization request and returns where to send the
+// user: the consent page, or back to the client with an error. Requests with an unknown
+// client or an unregistered redirect URI fail with ErrOAuthClientNotFound or
+// ErrInvalidRedirectURI, as the user must not be redirected to an unverified site.
+func (u *OAuthUsecase) Authorize(ctx context.Context, req dto.OAuthAuthorizeRequest) (string, error) {
+	client, err := u.repo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return "", e.ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != "code" {
+		return redirectURL(req.RedirectURI, url.Values{"error": {"unsupported_response_type"}, "state": {req.State}}), nil
+	}
+	if req.CodeChallengeMethod != "S256" || req.CodeChallenge == "" {
+		return redirectURL(req.RedirectURI, url.Values{
+			"error":             {"invalid_request"},
+			"error_description": {"PKCE with S256 is required"},
+			"state":             {req.State},
+		}), nil
+	}
+	scopes, err := parseScopes(req.Scope)
+	if err != nil {
+		return redirectURL(req.RedirectURI, url.Values{"error": {"invalid_scope"}, "state": {req.State}}), nil
+	}
+
+	token, err := u.signer.Sign(oauthRequestPurpose, oauthRequest{
+		ClientID:      client.ID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		State:         req.State,
+		CodeChallenge: req.CodeChallenge,
+	}, OAuthRequestTTL)
+	if err != nil {
+		return "", err
+	}
+
+	return redirectURL(u.consentURL, url.Values{"request": {token}}), nil
+}
+
+// DescribeRequest returns what the consent page shows the user about a request.
+func (u *OAuthUsecase) DescribeRequest(ctx context.Context, request string) (dto.OAuthConsentRequest, error) {
+	req, client, err := u.verifyRequest(ctx, request)
+	if err != nil {
+		return dto.OAuthConsentRequest{}, err
+	}
+
+	return dto.OAuthConsentRequest{ClientName: client.Name, RedirectURI: req.RedirectURI, Scopes: req.Scopes}, nil
+}
+
+// Consent records the user's decision on a request and returns where to send them
+// back to the client: with an authorization code if they approved, or with an
+// access_denied error.
+func (u *OAuthUsecase) Consent(ctx context.Context, userID models.UserID, request string, approve bool) (string, error) {
+	req, client, err := u.verifyRequest(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	if !approve {
+		return redirectURL(req.RedirectURI, url.Values{"error": {"access_denied"}, "state": {req.State}}), nil
+	}
+
+	code, hash, err := auth.GenerateOpaqueToken(oauthCodePrefix)
+	if err != nil {
+		return "", err
+	}
+	err = u.repo.CreateAuthorizationCode(ctx, models.OAuthAuthorizationCode{
+		CodeHash:      hash,
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		CodeChallenge: req.CodeChallenge,
+		Scopes:        req.Scopes,
+		ExpiresAt:     time.Now().Add(oauthCodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return redirectURL(req.RedirectURI, url.Values{"code": {code}, "state": {req.State}}), nil
+}
+
+func (u *OAuthUsecase) verifyRequest(ctx context.Context, request string) (oauthRequest, models.OAuthClient, error) {
+	var req oauthRequest
+	if err := u.signer.Verify(oauthRequestPurpose, request, &req); err != nil {
+		return oauthRequest{}, models.OAuthClient{}, e.ErrInvalidOAuthRequest
+	}
+
+	// The client may have been deleted, or its redirect URIs changed, since.
+	client, err := u.repo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, e.ErrOAuthClientNotFound) {
+			return oauthRequest{}, models.OAuthClient{}, e.ErrInvalidOAuthRequest
+		}
+		return oauthRequest{}, models.OAuthClient{}, err
+	}
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return oauthRequest{}, models.OAuthClient{}, e.ErrInvalidOAuthRequest
+	}
+
+	return req, client, nil
+}
+
+// ExchangeCode redeems an authorization code for tokens. The code verifier must match
+// the challenge of the authorization request, and the redirect URI must be the same.
+func (u *OAuthUsecase) ExchangeCode(ctx context.Context, clientID, clientSecret, code, redirectURI, verifier string) (dto.OAuthTokenResponse, error) {
+	client, err := u.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return dto.OAuthTokenResponse{}, err
+	}
+
+	stored, err := u.repo.TakeAuthorizationCode(ctx, auth.HashOpaqueToken(code))
+	if err != nil {
+		return dto.OAuthTokenResponse{}, err
+	}
+	// The redirect URI may have been removed from the client since the user consented.
+	if stored.ClientID != client.ID || stored.RedirectURI != redirectURI || !slices.Contains(client.RedirectURIs, redirectURI) {
+		return dto.OAuthTokenResponse{}, e.ErrInvalidGrant
+	}
+	challenge := oauth2.S256ChallengeFromVerifier(verifier)
+	if verifier == "" || subtle.ConstantTimeCompare([]byte(challenge), []byte(stored.CodeChallenge)) != 1 {
+		return dto.OAuthTokenResponse{}, e.ErrInvalidGrant
+	}
+
+	res, token, err := u.issueTokens(client.ID, stored.UserID, stored.Scopes)
+	if err != nil {
+		return dto.OAuthTokenResponse{}, err
+	}
+	if err := u.repo.CreateToken(ctx, token); err != nil {
+		return dto.OAuthTokenResponse{}, err
+	}
+
+	return res, nil
+}
+
+// RefreshToken rotates a refresh token. The new tokens may be limited to fewer scopes
+// by scope; they are never granted more than the user consented to.
+func (u *OAuthUsecase) RefreshToken(ctx context.Context, clientID, clientSecret, refreshToken, scope string) (dto.OAuthTokenResponse, error) {
+	client, err := u.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return dto.OAuthTokenResponse{}, err
+	}
+
+	current, err := u.repo.GetTokenByRefreshHash(ctx, auth.HashOpaqueToken(refreshToken))
+	if err != nil {
+		return dto.OAuthTokenResponse{}, err
+	}
+	if current.ClientID != client.ID || current.RevokedAt != nil || !time.Now().Before(current.RefreshExpiresAt) {
+		return dto.OAuthTokenResponse{}, e.ErrInvalidGrant
+	}
+
+	scopes := current.Scopes
+	if scope != "" {
+		scopes, err = parseScopes(scope)
+		if err != nil {
+			return dto.OAuthTokenResponse{}, err
+		}
+		for _, s := range scopes {
+			if !slices.Contains(current.Scopes, s) {
+				return dto.OAuthTokenResponse{}, e.ErrInvalidScope
+			}
+		}
+	}
+
+	res, next, err := u.issueTokens(client.ID, current.UserID, scopes)
+	if err != nil {
+		return dto.OAuthTokenResponse{}, err
+	}
+	// The refresh token's lifetime isn't extended, so that a grant ends eventually.
+	next.RefreshExpiresAt = current.RefreshExpiresAt
+	if err := u.repo.RotateToken(ctx, current.ID, next); err != nil {
+		return dto.OAuthTokenResponse{}, err
+	}
+
+	return res, nil
+}
+
+// Revoke revokes an access or refresh token of the client, together with its pair.
+// Unknown tokens and tokens of other clients are ignored, as RFC 7009 requires.
+func (u *OAuthUsecase) Revoke(ctx context.Context, clientID, clientSecret, token string) error {
+	client, err := u.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return err
+	}
+
+	stored, _, err := u.lookupToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, e.ErrInvalidGrant) {
+			return nil
+		}
+		return err
+	}
+	if stored.ClientID != client.ID {
+		return nil
+	}
+
+	return u.repo.RevokeToken(ctx, stored.ID)
+}
+
+// Introspect describes a token of the client. Tokens that are unknown, revoked,
+// expired or issued to other clients are reported as inactive.
+func (u *OAuthUsecase) Introspect(ctx context.Context, clientID, clientSecret, token string) (dto.OAuthIntrospection, error) {
+	client, err := u.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return dto.OAuthIntrospection{}, err
+	}
+
+	stored, refresh, err := u.lookupToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, e.ErrInvalidGrant) {
+			return dto.OAuthIntrospection{}, nil
+		}
+		return dto.OAuthIntrospection{}, err
+	}
+
+	expiresAt, tokenType := stored.AccessExpiresAt, "Bearer"
+	if refresh {
+		expiresAt, tokenType = stored.RefreshExpiresAt, "refresh_token"
+	}
+	if stored.ClientID != client.ID || stored.RevokedAt != nil || !time.Now().Before(expiresAt) {
+		return dto.OAuthIntrospection{}, nil
+	}
+
+	return dto.OAuthIntrospection{
+		Active:    true,
+		Scope:     formatScopes(stored.Scopes),
+		ClientID:  stored.ClientID,
+		TokenType: tokenType,
+		Subject:   strconv.FormatInt(int64(stored.UserID), 10),
+		ExpiresAt: expiresAt.Unix(),
+		IssuedAt:  stored.CreatedAt.Unix(),
+	}, nil
+}
+
+// AuthenticateAccessToken returns the grant of an access token issued to a client. It
+// fails with ErrInvalidAccessToken if the token is unknown, revoked or
+// expired.
+func (u *OAuthUsecase) AuthenticateAccessToken(ctx context.Context, token string) (models.OAuthToken, error) {
+	stored, err := u.repo.GetTokenByAccessHash(ctx, auth.HashOpaqueToken(token))
+	if err != nil {
+		if errors.Is(err, e.ErrInvalidGrant) {
+			return models.OAuthToken{}, e.ErrInvalidAccessToken
+		}
+		return models.OAuthToken{}, err
+	}
+	if stored.RevokedAt != nil || !time.Now().Before(stored.AccessExpiresAt) {
+		return models.OAuthToken{}, e.ErrInvalidAccessToken
+	}
+
+	return stored, nil
+}
+
+// lookupToken finds an access or refresh token by its prefix, and reports whether it
+// is a refresh token.
+func (u *OAuthUsecase) lookupToken(ctx context.Context, token string) (models.OAuthToken, bool, error) {
+	switch {
+	case strings.HasPrefix(token, oauthAccessTokenPrefix):
+		stored, err := u.repo.GetTokenByAccessHash(ctx, auth.HashOpaqueToken(token))
+		return stored, false, err
+	case strings.HasPrefix(token, oauthRefreshTokenPrefix):
+		stored, err := u.repo.GetTokenByRefreshHash(ctx, auth.HashOpaqueToken(token))
+		return stored, true, err
+	default:
+		return models.OAuthToken{}, false, e.ErrInvalidGrant
+	}
+}
+
+// authenticateClient checks the secret of confidential clients. Public clients send
+// none, and are identified by their ID alone.
+func (u *OAuthUsecase) authenticateClient(ctx context.Context, clientID, clientSecret string) (models.OAuthClient, error) {
+	client, err := u.repo.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, e.ErrOAuthClientNotFound) {
+			return models.OAuthClient{}, e.ErrInvalidClient
+		}
+		return models.OAuthClient{}, err
+	}
+
+	if !client.Confidential() {
+		if clientSecret != "" {
+			return models.OAuthClient{}, e.ErrInvalidClient
+		}
+		return client, nil
+	}
+	hash := auth.HashOpaqueToken(clientSecret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash)) != 1 {
+		return models.OAuthClient{}, e.ErrInvalidClient
+	}
+	return client, nil
+}
+
+func (u *OAuthUsecase) issueTokens(clientID string, userID models.UserID, scopes []models.Scope) (dto.OAuthTokenResponse, models.OAuthToken, error) {
+	accessToken, accessHash, err := auth.GenerateOpaqueToken(oauthAccessTokenPrefix)
+	if err != nil {
+		return dto.OAuthTokenResponse{}, models.OAuthToken{}, err
+	}
+	refreshToken, refreshHash, err := auth.GenerateOpaqueToken(oauthRefreshTokenPrefix)
+	if err != nil {
+		return dto.OAuthTokenResponse{}, models.OAuthToken{}, err
+	}
+
+	now := time.Now()
+	res := dto.OAuthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(u.accessTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        formatScopes(scopes),
+	}
+	token := models.OAuthToken{
+		ClientID:         clientID,
+		UserID:           userID,
+		Scopes:           scopes,
+		AccessTokenHash:  accessHash,
+		RefreshTokenHash: refreshHash,
+		AccessExpiresAt:  now.Add(u.accessTTL),
+		RefreshExpiresAt: now.Add(u.refreshTTL),
+	}
+	return res, token, nil
+}
+
+// parseScopes parses a space-separated scope parameter. At least one scope is required.
+func parseScopes(scope string) ([]models.Scope, error) {
+	fields := strings.Fields(scope)
+	if len(fields) == 0 {
+		return nil, e.ErrInvalidScope
+	}
+
+	scopes := make([]models.Scope, len(fields))
+	for i, f := range fields {
+		scopes[i] = models.Scope(f)
+		if !scopes[i].Valid() {
+			return nil, e.ErrInvalidScope
+		}
+	}
+	slices.Sort(scopes)
+	return slices.Compact(scopes), nil
+}
+
+func formatScopes(scopes []models.Scope) string {
+	values := make([]string, len(scopes))
+	for i, s := range scopes {
+		values[i] = string(s)
+	}
+	return strings.Join(values, " ")
+}
+
+// redirectURL adds params to the query of uri, keeping the query it has. Empty values
+// are left out.
+func redirectURL(uri string, params url.Values) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(key, values[0])
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
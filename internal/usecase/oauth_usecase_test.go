@@ -0,0 +1,329 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testConsentURL  = "https://app.example.com/consent"
+	testRedirectURI = "https://client.example.com/callback"
+)
+
+type fakeOAuthRepo struct {
+	repository.OAuthRepository
+
+	mu      sync.Mutex
+	clients map[string]models.OAuthClient
+	codes   map[string]models.OAuthAuthorizationCode
+	tokens  []models.OAuthToken
+}
+
+func (r *fakeOAuthRepo) GetClient(_ context.Context, id string) (models.OAuthClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	client, ok := r.clients[id]
+	if !ok {
+		return models.OAuthClient{}, e.ErrOAuthClientNotFound
+	}
+	return client, nil
+}
+
+func (r *fakeOAuthRepo) CreateAuthorizationCode(_ context.Context, code models.OAuthAuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.codes[code.CodeHash] = code
+	return nil
+}
+
+func (r *fakeOAuthRepo) TakeAuthorizationCode(_ context.Context, codeHash string) (models.OAuthAuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code, ok := r.codes[codeHash]
+	delete(r.codes, codeHash)
+	if !ok || !time.Now().Before(code.ExpiresAt) {
+		return models.OAuthAuthorizationCode{}, e.ErrInvalidGrant
+	}
+	return code, nil
+}
+
+func (r *fakeOAuthRepo) CreateToken(_ context.Context, token models.OAuthToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+// oauthFixture has a public client, "spa", registered with testRedirectURI.
+type oauthFixture struct {
+	oauth *OAuthUsecase
+	repo  *fakeOAuthRepo
+}
+
+func newOAuthFixture() oauthFixture {
+	repo := &fakeOAuthRepo{
+		clients: map[string]models.OAuthClient{
+			"spa":   {ID: "spa", UserID: 1, Name: "SPA", RedirectURIs: []string{testRedirectURI}},
+			"other": {ID: "other", UserID: 1, Name: "Other", RedirectURIs: []string{testRedirectURI}},
+		},
+		codes: make(map[string]models.OAuthAuthorizationCode),
+	}
+	return oauthFixture{
+		oauth: NewOAuthUsecase(repo, auth.NewTokenSigner("secret"), testConsentURL, time.Hour, 24*time.Hour),
+		repo:  repo,
+	}
+}
+
+// authorize starts an authorization request of the client with the challenge of
+// verifier, and returns the request token of the consent page.
+func (f oauthFixture) authorize(t *testing.T, clientID, verifier string) string {
+	t.Helper()
+
+	to, err := f.oauth.Authorize(context.Background(), dto.OAuthAuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            clientID,
+		RedirectURI:         testRedirectURI,
+		Scope:               "todos:read",
+		State:               "xyz",
+		CodeChallenge:       oauth2.S256ChallengeFromVerifier(verifier),
+		CodeChallengeMethod: "S256",
+	})
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	return redirectParam(t, to, testConsentURL, "request")
+}
+
+// consent approves request as user 7, and returns the authorization code.
+func (f oauthFixture) consent(t *testing.T, request string) string {
+	t.Helper()
+
+	to, err := f.oauth.Consent(context.Background(), 7, request, true)
+	if err != nil {
+		t.Fatalf("consent: %v", err)
+	}
+	return redirectParam(t, to, testRedirectURI, "code")
+}
+
+func (f oauthFixture) setRedirectURIs(clientID string, uris ...string) {
+	f.repo.mu.Lock()
+	defer f.repo.mu.Unlock()
+
+	client := f.repo.clients[clientID]
+	client.RedirectURIs = uris
+	f.repo.clients[clientID] = client
+}
+
+func (f oauthFixture) deleteClient(clientID string) {
+	f.repo.mu.Lock()
+	defer f.repo.mu.Unlock()
+
+	delete(f.repo.clients, clientID)
+}
+
+// redirectParam checks that to leads to base and returns its query parameter key.
+func redirectParam(t *testing.T, to, base, key string) string {
+	t.Helper()
+
+	u, err := url.Parse(to)
+	if err != nil {
+		t.Fatalf("parse %q: %v", to, err)
+	}
+	query := u.Query()
+	u.RawQuery = ""
+	if u.String() != base {
+		t.Fatalf("redirected to %s, want %s", u, base)
+	}
+	if query.Get(key) == "" {
+		t.Fatalf("redirect %s has no %s", to, key)
+	}
+	return query.Get(key)
+}
+
+func TestExchangeCodeIsSingleUse(t *testing.T) {
+	f := newOAuthFixture()
+	ctx := context.Background()
+	verifier := oauth2.GenerateVerifier()
+
+	code := f.consent(t, f.authorize(t, "spa", verifier))
+	res, err := f.oauth.ExchangeCode(ctx, "spa", "", code, testRedirectURI, verifier)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if res.AccessToken == "" || res.RefreshToken == "" || res.Scope != "todos:read" {
+		t.Fatalf("got %+v", res)
+	}
+	if len(f.repo.tokens) != 1 || f.repo.tokens[0].UserID != 7 || f.repo.tokens[0].ClientID != "spa" {
+		t.Fatalf("stored %+v", f.repo.tokens)
+	}
+
+	if _, err := f.oauth.ExchangeCode(ctx, "spa", "", code, testRedirectURI, verifier); !errors.Is(err, e.ErrInvalidGrant) {
+		t.Fatalf("got %v for a used code, want %v", err, e.ErrInvalidGrant)
+	}
+	if len(f.repo.tokens) != 1 {
+		t.Fatalf("a used code issued %d more tokens", len(f.repo.tokens)-1)
+	}
+}
+
+func TestExchangeCodeRefusesMismatches(t *testing.T) {
+	verifier := oauth2.GenerateVerifier()
+
+	tests := []struct {
+		name        string
+		clientID    string
+		redirectURI string
+		verifier    string
+	}{
+		{"trailing slash", "spa", testRedirectURI + "/", verifier},
+		{"extra query", "spa", testRedirectURI + "?next=1", verifier},
+		{"other case", "spa", "https://client.example.com/Callback", verifier},
+		{"other scheme", "spa", "http://client.example.com/callback", verifier},
+		{"no redirect URI", "spa", "", verifier},
+		{"wrong verifier", "spa", testRedirectURI, oauth2.GenerateVerifier()},
+		// The challenge itself isn't a valid verifier, as it would be for the plain method.
+		{"challenge as verifier", "spa", testRedirectURI, oauth2.S256ChallengeFromVerifier(verifier)},
+		{"no verifier", "spa", testRedirectURI, ""},
+		{"other client", "other", testRedirectURI, verifier},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOAuthFixture()
+			ctx := context.Background()
+
+			code := f.consent(t, f.authorize(t, "spa", verifier))
+			if _, err := f.oauth.ExchangeCode(ctx, tt.clientID, "", code, tt.redirectURI, tt.verifier); !errors.Is(err, e.ErrInvalidGrant) {
+				t.Fatalf("got %v, want %v", err, e.ErrInvalidGrant)
+			}
+
+			// A failed exchange uses the code up, so that it can't be retried.
+			if _, err := f.oauth.ExchangeCode(ctx, "spa", "", code, testRedirectURI, verifier); !errors.Is(err, e.ErrInvalidGrant) {
+				t.Fatalf("got %v for the right request after a failed one, want %v", err, e.ErrInvalidGrant)
+			}
+			if len(f.repo.tokens) != 0 {
+				t.Fatalf("issued %d tokens", len(f.repo.tokens))
+			}
+		})
+	}
+}
+
+func TestAuthorizeRefusesUnregisteredRedirectURIs(t *testing.T) {
+	f := newOAuthFixture()
+	challenge := oauth2.S256ChallengeFromVerifier(oauth2.GenerateVerifier())
+
+	// The user isn't sent to a redirect URI that isn't registered, not even with an error.
+	for _, uri := range []string{
+		testRedirectURI + "/",
+		testRedirectURI + "?next=https://evil.example.com",
+		"https://client.example.com.evil.example.com/callback",
+		"https://evil.example.com/callback",
+	} {
+		_, err := f.oauth.Authorize(context.Background(), dto.OAuthAuthorizeRequest{
+			ResponseType:        "code",
+			ClientID:            "spa",
+			RedirectURI:         uri,
+			Scope:               "todos:read",
+			CodeChallenge:       challenge,
+			CodeChallengeMethod: "S256",
+		})
+		if !errors.Is(err, e.ErrInvalidRedirectURI) {
+			t.Errorf("%s: got %v, want %v", uri, err, e.ErrInvalidRedirectURI)
+		}
+	}
+}
+
+func TestAuthorizeRequiresS256(t *testing.T) {
+	f := newOAuthFixture()
+	challenge := oauth2.S256ChallengeFromVerifier(oauth2.GenerateVerifier())
+
+	for _, method := range []string{"", "plain", "s256"} {
+		to, err := f.oauth.Authorize(context.Background(), dto.OAuthAuthorizeRequest{
+			ResponseType:        "code",
+			ClientID:            "spa",
+			RedirectURI:         testRedirectURI,
+			Scope:               "todos:read",
+			State:               "xyz",
+			CodeChallenge:       challenge,
+			CodeChallengeMethod: method,
+		})
+		if err != nil {
+			t.Fatalf("%q: %v", method, err)
+		}
+		if got := redirectParam(t, to, testRedirectURI, "error"); got != "invalid_request" {
+			t.Errorf("%q: got error %q, want invalid_request", method, got)
+		}
+	}
+}
+
+func TestOAuthClientChangesAfterAuthorize(t *testing.T) {
+	verifier := oauth2.GenerateVerifier()
+
+	tests := []struct {
+		name string
+		// change is made to the client between the step before and the step of the test.
+		change func(f oauthFixture)
+	}{
+		{"client deleted", func(f oauthFixture) { f.deleteClient("spa") }},
+		{"redirect URI removed", func(f oauthFixture) { f.setRedirectURIs("spa", "https://client.example.com/other") }},
+	}
+
+	for _, tt := range tests {
+		t.Run("before consent/"+tt.name, func(t *testing.T) {
+			f := newOAuthFixture()
+			ctx := context.Background()
+
+			request := f.authorize(t, "spa", verifier)
+			tt.change(f)
+			if _, err := f.oauth.DescribeRequest(ctx, request); !errors.Is(err, e.ErrInvalidOAuthRequest) {
+				t.Fatalf("describe: got %v, want %v", err, e.ErrInvalidOAuthRequest)
+			}
+			if _, err := f.oauth.Consent(ctx, 7, request, true); !errors.Is(err, e.ErrInvalidOAuthRequest) {
+				t.Fatalf("consent: got %v, want %v", err, e.ErrInvalidOAuthRequest)
+			}
+			if len(f.repo.codes) != 0 {
+				t.Fatalf("issued %d codes", len(f.repo.codes))
+			}
+		})
+
+		t.Run("before exchange/"+tt.name, func(t *testing.T) {
+			f := newOAuthFixture()
+
+			code := f.consent(t, f.authorize(t, "spa", verifier))
+			tt.change(f)
+			_, err := f.oauth.ExchangeCode(context.Background(), "spa", "", code, testRedirectURI, verifier)
+			if !errors.Is(err, e.ErrInvalidGrant) && !errors.Is(err, e.ErrInvalidClient) {
+				t.Fatalf("got %v, want %v or %v", err, e.ErrInvalidGrant, e.ErrInvalidClient)
+			}
+			if len(f.repo.tokens) != 0 {
+				t.Fatalf("issued %d tokens", len(f.repo.tokens))
+			}
+		})
+	}
+
+	// Adding a redirect URI doesn't affect codes issued for another.
+	f := newOAuthFixture()
+	code := f.consent(t, f.authorize(t, "spa", verifier))
+	f.setRedirectURIs("spa", testRedirectURI, "https://client.example.com/other")
+	if _, err := f.oauth.ExchangeCode(context.Background(), "spa", "", code, testRedirectURI, verifier); err != nil {
+		t.Fatalf("exchange after adding a redirect URI: %v", err)
+	}
+	if !slices.ContainsFunc(f.repo.tokens, func(tok models.OAuthToken) bool { return tok.ClientID == "spa" }) {
+		t.Fatal("no token was stored")
+	}
+}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStatePrefix = "ost_"
+	// OIDCLoginTimeout is how long the user has to log in at the provider.
+	OIDCLoginTimeout = 10 * time.Minute
+)
+
+// OIDCUsecase logs users in at external OpenID Connect providers. An identity is linked
+// to the user with the same email on its first login, provided both the provider and
+// we have verified the email.
+type OIDCUsecase struct {
+	providers map[string]*auth.OIDCProvider
+	repo      repository.IdentityRepository
+	userRepo  repository.UserRepository
+}
+
+func NewOIDCUsecase(providers map[string]*auth.OIDCProvider, repo repository.IdentityRepository, userRepo repository.UserRepository) *OIDCUsecase {
+	return &OIDCUsecase{providers: providers, repo: repo, userRepo: userRepo}
+}
+
+// BeginLogin returns the URL of the provider's login page. The provider redirects back
+// with the state and a code for FinishLogin.
+func (u *OIDCUsecase) BeginLogin(ctx context.Context, providerName string) (string, error) {
+	provider, ok := u.providers[providerName]
+	if !ok {
+		return "", e.ErrUnknownOIDCProvider
+	}
+
+	state, hash, err := auth.GenerateOpaqueToken(oidcStatePrefix)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := auth.GenerateID()
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+
+	err = u.repo.CreateOIDCState(ctx, models.OIDCLoginState{
+		StateHash:    hash,
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(OIDCLoginTimeout),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// FinishLogin redeems the code the provider redirected back with and returns the user
+// the identity belongs to.
+func (u *OIDCUsecase) FinishLogin(ctx context.Context, providerName, state, code string) (models.UserID, error) {
+	provider, ok := u.providers[providerName]
+	if !ok {
+		return 0, e.ErrUnknownOIDCProvider
+	}
+
+	stored, err := u.repo.TakeOIDCState(ctx, auth.HashOpaqueToken(state), providerName)
+	if err != nil {
+		return 0, err
+	}
+
+	identity, err := provider.Exchange(ctx, code, stored.CodeVerifier, stored.Nonce)
+	if err != nil {
+		return 0, e.ErrOIDCLoginFailed
+	}
+
+	linked, err := u.repo.GetIdentity(ctx, providerName, identity.Subject)
+	if err == nil {
+		return linked.UserID, nil
+	}
+	if !errors.Is(err, e.ErrUserNotFound) {
+		return 0, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return 0, e.ErrOIDCEmailNotVerified
+	}
+	user, err := u.userRepo.GetUserByEmail(ctx, identity.Email)
+	if err != nil {
+		if errors.Is(err, e.ErrUserNotFound) {
+			return 0, e.ErrOIDCNoAccount
+		}
+		return 0, err
+	}
+	// Whoever registered the email without verifying it may not own it, and mustn't end
+	// up with access to the account of the provider's user.
+	if !user.EmailVerified() {
+		return 0, e.ErrEmailNotVerified
+	}
+
+	err = u.repo.LinkIdentity(ctx, models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    user.Email,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return user.ID, nil
+}
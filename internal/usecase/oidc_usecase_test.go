@@ -0,0 +1,306 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc/oidctest"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+)
+
+const testOIDCClientID = "todos"
+
+type fakeIdentityRepo struct {
+	repository.IdentityRepository
+
+	mu         sync.Mutex
+	identities []models.UserIdentity
+	states     map[string]models.OIDCLoginState
+}
+
+func (r *fakeIdentityRepo) GetIdentity(_ context.Context, provider, subject string) (models.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return models.UserIdentity{}, e.ErrUserNotFound
+}
+
+func (r *fakeIdentityRepo) LinkIdentity(_ context.Context, identity models.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *fakeIdentityRepo) CreateOIDCState(_ context.Context, state models.OIDCLoginState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.states[state.StateHash] = state
+	return nil
+}
+
+func (r *fakeIdentityRepo) TakeOIDCState(_ context.Context, hash, provider string) (models.OIDCLoginState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[hash]
+	delete(r.states, hash)
+	if !ok || state.Provider != provider || time.Now().After(state.ExpiresAt) {
+		return models.OIDCLoginState{}, e.ErrInvalidOIDCState
+	}
+	return state, nil
+}
+
+// fakeIssuer is an OpenID Connect provider. Discovery and the JWKS are served by
+// oidctest; the token endpoint redeems the codes authorize hands out, checking their
+// PKCE verifier like a real provider does.
+type fakeIssuer struct {
+	t   *testing.T
+	url string
+	key *ecdsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]issuedCode
+	// refused counts the token requests the endpoint refused.
+	refused int
+}
+
+type issuedCode struct {
+	challenge string
+	claims    map[string]any
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	p := &fakeIssuer{t: t, key: key, codes: make(map[string]issuedCode)}
+
+	discovery := &oidctest.Server{
+		PublicKeys: []oidctest.PublicKey{{PublicKey: key.Public(), KeyID: "k1", Algorithm: "ES256"}},
+		Algorithms: []string{"ES256"},
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", discovery)
+	mux.HandleFunc("POST /token", p.token)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	discovery.SetIssuer(srv.URL)
+	p.url = srv.URL
+	return p
+}
+
+// authorize logs in at authURL as the user of claims, and returns the state and code
+// the provider redirects back with. Claims the provider sets itself are added.
+func (p *fakeIssuer) authorize(authURL string, claims map[string]any) (state, code string) {
+	p.t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatalf("parse auth URL: %v", err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		p.t.Fatalf("auth URL without an S256 code challenge: %s", authURL)
+	}
+
+	full := map[string]any{
+		"iss":   p.url,
+		"aud":   query.Get("client_id"),
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for k, v := range claims {
+		full[k] = v
+	}
+
+	code, err = auth.GenerateID()
+	if err != nil {
+		p.t.Fatalf("generate code: %v", err)
+	}
+	p.mu.Lock()
+	p.codes[code] = issuedCode{challenge: query.Get("code_challenge"), claims: full}
+	p.mu.Unlock()
+	return query.Get("state"), code
+}
+
+func (p *fakeIssuer) token(w http.ResponseWriter, r *http.Request) {
+	code := r.PostFormValue("code")
+	verifierHash := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	p.mu.Lock()
+	issued, ok := p.codes[code]
+	delete(p.codes, code)
+	refused := !ok || base64.RawURLEncoding.EncodeToString(verifierHash[:]) != issued.challenge
+	if refused {
+		p.refused++
+	}
+	p.mu.Unlock()
+
+	if refused {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	claims, err := json.Marshal(issued.claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "at",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     oidctest.SignIDToken(p.key, "k1", "ES256", string(claims)),
+	})
+}
+
+func newTestOIDCUsecase(t *testing.T) (*OIDCUsecase, *fakeIssuer, *fakeIdentityRepo) {
+	t.Helper()
+
+	issuer := newFakeIssuer(t)
+	provider := auth.NewOIDCProvider(issuer.url, testOIDCClientID, "secret", "https://app.example.com/login/oidc/test")
+	identities := &fakeIdentityRepo{states: make(map[string]models.OIDCLoginState)}
+	verified := time.Now()
+	users := &fakeUserRepo{users: map[models.UserID]models.User{
+		7: {ID: 7, Email: "alice@example.com", EmailVerifiedAt: &verified},
+		8: {ID: 8, Email: "mallory@example.com"},
+	}}
+	return NewOIDCUsecase(map[string]*auth.OIDCProvider{"test": provider}, identities, users), issuer, identities
+}
+
+func beginOIDCLogin(t *testing.T, u *OIDCUsecase) string {
+	t.Helper()
+
+	authURL, err := u.BeginLogin(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	return authURL
+}
+
+func TestOIDCLogin(t *testing.T) {
+	u, issuer, identities := newTestOIDCUsecase(t)
+	ctx := context.Background()
+
+	// The first login links the identity by its verified email.
+	state, code := issuer.authorize(beginOIDCLogin(t, u), map[string]any{
+		"sub": "alice-sub", "email": "alice@example.com", "email_verified": true,
+	})
+	userID, err := u.FinishLogin(ctx, "test", state, code)
+	if err != nil || userID != 7 {
+		t.Fatalf("got %d, %v", userID, err)
+	}
+	if len(identities.identities) != 1 || identities.identities[0].Subject != "alice-sub" {
+		t.Fatalf("linked %v", identities.identities)
+	}
+
+	// Later logins go by the subject, whatever the email is by then.
+	state, code = issuer.authorize(beginOIDCLogin(t, u), map[string]any{"sub": "alice-sub", "email": "alice@new.example.com"})
+	if userID, err := u.FinishLogin(ctx, "test", state, code); err != nil || userID != 7 {
+		t.Fatalf("got %d, %v", userID, err)
+	}
+}
+
+func TestOIDCLoginStateAndNonce(t *testing.T) {
+	u, issuer, _ := newTestOIDCUsecase(t)
+	ctx := context.Background()
+	alice := map[string]any{"sub": "alice-sub", "email": "alice@example.com", "email_verified": true}
+
+	_, code := issuer.authorize(beginOIDCLogin(t, u), alice)
+	if _, err := u.FinishLogin(ctx, "test", "ost_forged", code); !errors.Is(err, e.ErrInvalidOIDCState) {
+		t.Fatalf("got %v for an unknown state, want %v", err, e.ErrInvalidOIDCState)
+	}
+
+	state, code := issuer.authorize(beginOIDCLogin(t, u), alice)
+	if _, err := u.FinishLogin(ctx, "test", state, code); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := u.FinishLogin(ctx, "test", state, code); !errors.Is(err, e.ErrInvalidOIDCState) {
+		t.Fatalf("got %v for a used state, want %v", err, e.ErrInvalidOIDCState)
+	}
+
+	// An ID token issued for another login doesn't match this login's nonce.
+	replayed := map[string]any{"nonce": "nonce-of-another-login"}
+	for k, v := range alice {
+		replayed[k] = v
+	}
+	state, code = issuer.authorize(beginOIDCLogin(t, u), replayed)
+	if _, err := u.FinishLogin(ctx, "test", state, code); !errors.Is(err, e.ErrOIDCLoginFailed) {
+		t.Fatalf("got %v for a nonce mismatch, want %v", err, e.ErrOIDCLoginFailed)
+	}
+}
+
+func TestOIDCLoginPKCE(t *testing.T) {
+	u, issuer, _ := newTestOIDCUsecase(t)
+	ctx := context.Background()
+
+	// An attacker who intercepted the victim's code injects it into a login of their
+	// own. The provider refuses it, as the attacker's verifier doesn't match the
+	// challenge of the victim's login, before the nonce could give it away.
+	_, victimCode := issuer.authorize(beginOIDCLogin(t, u), map[string]any{
+		"sub": "alice-sub", "email": "alice@example.com", "email_verified": true,
+	})
+	attackerState, _ := issuer.authorize(beginOIDCLogin(t, u), map[string]any{"sub": "mallory-sub"})
+	if _, err := u.FinishLogin(ctx, "test", attackerState, victimCode); !errors.Is(err, e.ErrOIDCLoginFailed) {
+		t.Fatalf("got %v for an injected code, want %v", err, e.ErrOIDCLoginFailed)
+	}
+	if issuer.refused == 0 {
+		t.Fatal("token endpoint accepted the injected code")
+	}
+}
+
+func TestOIDCLoginUnverifiedEmail(t *testing.T) {
+	u, issuer, identities := newTestOIDCUsecase(t)
+	ctx := context.Background()
+
+	// The provider hasn't verified the email, so it says nothing about who owns it.
+	state, code := issuer.authorize(beginOIDCLogin(t, u), map[string]any{
+		"sub": "attacker-sub", "email": "alice@example.com", "email_verified": false,
+	})
+	if _, err := u.FinishLogin(ctx, "test", state, code); !errors.Is(err, e.ErrOIDCEmailNotVerified) {
+		t.Fatalf("got %v, want %v", err, e.ErrOIDCEmailNotVerified)
+	}
+
+	// We haven't verified the email of the account, so whoever registered it may not
+	// own it.
+	state, code = issuer.authorize(beginOIDCLogin(t, u), map[string]any{
+		"sub": "mallory-sub", "email": "mallory@example.com", "email_verified": true,
+	})
+	if _, err := u.FinishLogin(ctx, "test", state, code); !errors.Is(err, e.ErrEmailNotVerified) {
+		t.Fatalf("got %v, want %v", err, e.ErrEmailNotVerified)
+	}
+
+	if len(identities.identities) != 0 {
+		t.Fatalf("linked %v", identities.identities)
+	}
+}
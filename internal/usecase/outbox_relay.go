@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/events"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+)
+
+const outboxRelayBatch = 100
+
+// OutboxRelay publishes events recorded in the outbox. Delivery is at least once: an
+// event is marked as published only after the publisher accepted it.
+type OutboxRelay struct {
+	repo      repository.OutboxRepository
+	publisher events.EventPublisher
+	retention time.Duration
+}
+
+// NewOutboxRelay keeps published events for retention, during which instances that
+// lost their event listener connection reload them from the outbox.
+func NewOutboxRelay(r repository.OutboxRepository, publisher events.EventPublisher, retention time.Duration) *OutboxRelay {
+	return &OutboxRelay{repo: r, publisher: publisher, retention: retention}
+}
+
+// RelayBatch publishes one batch of pending events and returns how many were published.
+func (u *OutboxRelay) RelayBatch(ctx context.Context) (int, error) {
+	return u.repo.ProcessBatch(ctx, outboxRelayBatch, func(event models.Event) error {
+		return u.publisher.Publish(ctx, event)
+	})
+}
+
+// Purge deletes the events published longer ago than the retention and returns how
+// many were deleted.
+func (u *OutboxRelay) Purge(ctx context.Context) (int64, error) {
+	return u.repo.DeletePublishedBefore(ctx, u.retention)
+}
@@ -0,0 +1,295 @@
+package usecase
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+)
+
+const (
+	webAuthnSessionPrefix = "was_"
+	// PasskeyCeremonyTimeout is how long the user has to answer a registration or login.
+	PasskeyCeremonyTimeout = 5 * time.Minute
+)
+
+// PasskeyUsecase registers passkeys and verifies logins with them. Passkeys are
+// discoverable and require user verification, so a login with one stands in for both
+// the password and the second factor.
+type PasskeyUsecase struct {
+	repo     repository.PasskeyRepository
+	userRepo repository.UserRepository
+	webAuthn *webauthn.WebAuthn
+}
+
+func NewPasskeyUsecase(repo repository.PasskeyRepository, userRepo repository.UserRepository, webAuthn *webauthn.WebAuthn) *PasskeyUsecase {
+	return &PasskeyUsecase{repo: repo, userRepo: userRepo, webAuthn: webAuthn}
+}
+
+// BeginRegistration returns the options for navigator.credentials.create. Passkeys the
+// user already has are excluded, so an authenticator isn't registered twice.
+func (u *PasskeyUsecase) BeginRegistration(ctx context.Context, userID models.UserID) (dto.PasskeyCeremony, error) {
+	user, err := u.loadUser(ctx, userID)
+	if err != nil {
+		return dto.PasskeyCeremony{}, err
+	}
+
+	creation, session, err := u.webAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()))
+	if err != nil {
+		return dto.PasskeyCeremony{}, err
+	}
+
+	return u.startCeremony(ctx, models.CeremonyRegistration, &userID, session, creation)
+}
+
+// FinishRegistration verifies the browser's response to BeginRegistration and stores the
+// new passkey.
+func (u *PasskeyUsecase) FinishRegistration(ctx context.Context, userID models.UserID, sessionID, name string, response []byte) (models.Passkey, error) {
+	session, err := u.takeSession(ctx, sessionID, models.CeremonyRegistration)
+	if err != nil {
+		return models.Passkey{}, err
+	}
+
+	user, err := u.loadUser(ctx, userID)
+	if err != nil {
+		return models.Passkey{}, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return models.Passkey{}, e.ErrInvalidPasskey
+	}
+	// CreateCredential also checks that the session was started by this user.
+	cred, err := u.webAuthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		return models.Passkey{}, e.ErrInvalidPasskey
+	}
+
+	transports := make([]string, len(cred.Transport))
+	for i, t := range cred.Transport {
+		transports[i] = string(t)
+	}
+
+	return u.repo.CreatePasskey(ctx, models.Passkey{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    cred.ID,
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		Transports:      transports,
+		AAGUID:          cred.Authenticator.AAGUID,
+		Flags:           uint8(cred.Flags.ProtocolValue()),
+		SignCount:       cred.Authenticator.SignCount,
+	})
+}
+
+func (u *PasskeyUsecase) ListPasskeys(ctx context.Context, userID models.UserID) ([]models.Passkey, error) {
+	return u.repo.ListPasskeys(ctx, userID)
+}
+
+func (u *PasskeyUsecase) DeletePasskey(ctx context.Context, userID models.UserID, id models.PasskeyID) error {
+	return u.repo.DeletePasskey(ctx, userID, id)
+}
+
+// BeginLogin returns the options for navigator.credentials.get. The browser offers
+// every passkey it has for the site, so the user doesn't have to be known yet.
+func (u *PasskeyUsecase) BeginLogin(ctx context.Context) (dto.PasskeyCeremony, error) {
+	assertion, session, err := u.webAuthn.BeginDiscoverableLogin()
+	if err != nil {
+		return dto.PasskeyCeremony{}, err
+	}
+
+	return u.startCeremony(ctx, models.CeremonyLogin, nil, session, assertion)
+}
+
+// FinishLogin verifies the browser's response to BeginLogin and returns the user it
+// logs in. A passkey whose signature counter went backwards may have been cloned; it is
+// flagged and refused from then on.
+func (u *PasskeyUsecase) FinishLogin(ctx context.Context, sessionID string, response []byte) (models.UserID, error) {
+	session, err := u.takeSession(ctx, sessionID, models.CeremonyLogin)
+	if err != nil {
+		return 0, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return 0, e.ErrInvalidPasskey
+	}
+
+	var owner *webAuthnUser
+	findUser := func(_, userHandle []byte) (webauthn.User, error) {
+		userID, ok := userIDFromHandle(userHandle)
+		if !ok {
+			return nil, e.ErrInvalidPasskey
+		}
+		user, err := u.loadUser(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		owner = user
+		return user, nil
+	}
+
+	_, cred, err := u.webAuthn.ValidatePasskeyLogin(findUser, session, parsed)
+	if err != nil {
+		return 0, e.ErrInvalidPasskey
+	}
+
+	passkey, ok := owner.passkey(cred.ID)
+	if !ok || passkey.CloneWarning {
+		return 0, e.ErrInvalidPasskey
+	}
+	if cred.Authenticator.CloneWarning {
+		if err := u.repo.FlagClonedPasskey(ctx, passkey.ID); err != nil {
+			return 0, err
+		}
+		return 0, e.ErrInvalidPasskey
+	}
+
+	used, err := u.repo.UsePasskey(ctx, passkey.ID, cred.Authenticator.SignCount)
+	if err != nil {
+		return 0, err
+	}
+	if !used {
+		return 0, e.ErrInvalidPasskey
+	}
+
+	return owner.user.ID, nil
+}
+
+// startCeremony stores the session of a ceremony and returns its ID with the options
+// for the browser.
+func (u *PasskeyUsecase) startCeremony(ctx context.Context, ceremony models.WebAuthnCeremony, userID *models.UserID, session *webauthn.SessionData, options any) (dto.PasskeyCeremony, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return dto.PasskeyCeremony{}, err
+	}
+
+	sessionID, hash, err := auth.GenerateOpaqueToken(webAuthnSessionPrefix)
+	if err != nil {
+		return dto.PasskeyCeremony{}, err
+	}
+
+	err = u.repo.CreateWebAuthnSession(ctx, models.WebAuthnSession{
+		SessionHash: hash,
+		Ceremony:    ceremony,
+		UserID:      userID,
+		Data:        data,
+		ExpiresAt:   time.Now().Add(PasskeyCeremonyTimeout),
+	})
+	if err != nil {
+		return dto.PasskeyCeremony{}, err
+	}
+
+	// The options are handed out as a plain JSON object, with binary values base64url
+	// encoded the way the library marshals them.
+	b, err := json.Marshal(options)
+	if err != nil {
+		return dto.PasskeyCeremony{}, err
+	}
+	var opts map[string]any
+	if err := json.Unmarshal(b, &opts); err != nil {
+		return dto.PasskeyCeremony{}, err
+	}
+
+	return dto.PasskeyCeremony{SessionID: sessionID, Options: opts}, nil
+}
+
+func (u *PasskeyUsecase) takeSession(ctx context.Context, sessionID string, ceremony models.WebAuthnCeremony) (webauthn.SessionData, error) {
+	stored, err := u.repo.TakeWebAuthnSession(ctx, auth.HashOpaqueToken(sessionID), ceremony)
+	if err != nil {
+		return webauthn.SessionData{}, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(stored.Data, &session); err != nil {
+		return webauthn.SessionData{}, err
+	}
+	return session, nil
+}
+
+func (u *PasskeyUsecase) loadUser(ctx context.Context, userID models.UserID) (*webAuthnUser, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, e.ErrUserNotFound) {
+			return nil, e.ErrInvalidPasskey
+		}
+		return nil, err
+	}
+
+	passkeys, err := u.repo.ListPasskeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webAuthnUser{user: user, passkeys: passkeys}, nil
+}
+
+// webAuthnUser presents a user and their passkeys to the webauthn library. The user
+// handle is the user ID, which is no secret as the API returns it on registration.
+type webAuthnUser struct {
+	user     models.User
+	passkeys []models.Passkey
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(u.user.ID))
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	creds := make([]webauthn.Credential, len(u.passkeys))
+	for i, p := range u.passkeys {
+		transports := make([]protocol.AuthenticatorTransport, len(p.Transports))
+		for j, t := range p.Transports {
+			transports[j] = protocol.AuthenticatorTransport(t)
+		}
+
+		creds[i] = webauthn.Credential{
+			ID:              p.CredentialID,
+			PublicKey:       p.PublicKey,
+			AttestationType: p.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(p.Flags)),
+			Authenticator: webauthn.Authenticator{
+				AAGUID:       p.AAGUID,
+				SignCount:    p.SignCount,
+				CloneWarning: p.CloneWarning,
+			},
+		}
+	}
+	return creds
+}
+
+func (u *webAuthnUser) passkey(credentialID []byte) (models.Passkey, bool) {
+	for _, p := range u.passkeys {
+		if string(p.CredentialID) == string(credentialID) {
+			return p, true
+		}
+	}
+	return models.Passkey{}, false
+}
+
+func userIDFromHandle(handle []byte) (models.UserID, bool) {
+	if len(handle) != 8 {
+		return 0, false
+	}
+	return models.UserID(binary.BigEndian.Uint64(handle)), true
+}
@@ -0,0 +1,387 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+type fakePasskeyRepo struct {
+	repository.PasskeyRepository
+
+	mu       sync.Mutex
+	passkeys []models.Passkey
+	sessions map[string]models.WebAuthnSession
+}
+
+func (r *fakePasskeyRepo) CreatePasskey(_ context.Context, p models.Passkey) (models.Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.passkeys {
+		if string(existing.CredentialID) == string(p.CredentialID) {
+			return models.Passkey{}, e.ErrPasskeyAlreadyExists
+		}
+	}
+	p.ID = models.PasskeyID(len(r.passkeys) + 1)
+	p.CreatedAt = time.Now()
+	r.passkeys = append(r.passkeys, p)
+	return p, nil
+}
+
+func (r *fakePasskeyRepo) ListPasskeys(_ context.Context, userID models.UserID) ([]models.Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var passkeys []models.Passkey
+	for _, p := range r.passkeys {
+		if p.UserID == userID {
+			passkeys = append(passkeys, p)
+		}
+	}
+	return passkeys, nil
+}
+
+func (r *fakePasskeyRepo) UsePasskey(_ context.Context, id models.PasskeyID, signCount uint32) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := &r.passkeys[id-1]
+	if p.CloneWarning || (p.SignCount >= signCount && signCount != 0) {
+		return false, nil
+	}
+	p.SignCount = signCount
+	return true, nil
+}
+
+func (r *fakePasskeyRepo) FlagClonedPasskey(_ context.Context, id models.PasskeyID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.passkeys[id-1].CloneWarning = true
+	return nil
+}
+
+func (r *fakePasskeyRepo) CreateWebAuthnSession(_ context.Context, s models.WebAuthnSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions[s.SessionHash] = s
+	return nil
+}
+
+func (r *fakePasskeyRepo) TakeWebAuthnSession(_ context.Context, hash string, ceremony models.WebAuthnCeremony) (models.WebAuthnSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.sessions[hash]
+	delete(r.sessions, hash)
+	if !ok || s.Ceremony != ceremony || time.Now().After(s.ExpiresAt) {
+		return models.WebAuthnSession{}, e.ErrInvalidWebAuthnSession
+	}
+	return s, nil
+}
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[models.UserID]models.User
+}
+
+func (r *fakeUserRepo) GetUserByID(_ context.Context, id models.UserID) (models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, e.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepo) GetUserByEmail(_ context.Context, email string) (models.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, e.ErrUserNotFound
+}
+
+// softAuthenticator is a platform authenticator in software. It holds a single
+// discoverable ES256 credential, attests with "none" and answers ceremonies for origin.
+type softAuthenticator struct {
+	t          *testing.T
+	key        *ecdsa.PrivateKey
+	credID     []byte
+	userHandle []byte
+	signCount  uint32
+	origin     string
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	credID := make([]byte, 16)
+	_, _ = rand.Read(credID)
+	return &softAuthenticator{t: t, key: key, credID: credID, origin: testOrigin}
+}
+
+// authenticatorFlags are UP and UV. Registrations add AT for the attested credential
+// data.
+const authenticatorFlags = 0x01 | 0x04
+
+func (a *softAuthenticator) authData(attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	flags := byte(authenticatorFlags)
+	if attested != nil {
+		flags |= 0x40
+	}
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(typ string, options map[string]any) []byte {
+	challenge, _ := options["publicKey"].(map[string]any)["challenge"].(string)
+	b, err := json.Marshal(map[string]any{"type": typ, "challenge": challenge, "origin": a.origin})
+	if err != nil {
+		a.t.Fatalf("marshal client data: %v", err)
+	}
+	return b
+}
+
+// create answers navigator.credentials.create.
+func (a *softAuthenticator) create(options map[string]any) []byte {
+	a.t.Helper()
+
+	user, _ := options["publicKey"].(map[string]any)["user"].(map[string]any)
+	handle, err := base64.RawURLEncoding.DecodeString(user["id"].(string))
+	if err != nil {
+		a.t.Fatalf("decode user handle: %v", err)
+	}
+	a.userHandle = handle
+
+	pub, err := a.key.PublicKey.ECDH()
+	if err != nil {
+		a.t.Fatalf("convert key: %v", err)
+	}
+	point := pub.Bytes()
+	coseKey, err := cbor.Marshal(map[int]any{1: 2, 3: -7, -1: 1, -2: point[1:33], -3: point[33:]})
+	if err != nil {
+		a.t.Fatalf("marshal key: %v", err)
+	}
+	attested := make([]byte, 16) // The AAGUID, zero for "none" attestation.
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credID)))
+	attested = append(attested, a.credID...)
+	attested = append(attested, coseKey...)
+
+	attestation, err := cbor.Marshal(map[string]any{"fmt": "none", "attStmt": map[string]any{}, "authData": a.authData(attested)})
+	if err != nil {
+		a.t.Fatalf("marshal attestation: %v", err)
+	}
+	return a.response(map[string]any{
+		"clientDataJSON":    a.clientData("webauthn.create", options),
+		"attestationObject": attestation,
+	})
+}
+
+// get answers navigator.credentials.get with the next signature count.
+func (a *softAuthenticator) get(options map[string]any) []byte {
+	a.t.Helper()
+
+	a.signCount++
+	authData := a.authData(nil)
+	clientData := a.clientData("webauthn.get", options)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatalf("sign: %v", err)
+	}
+	return a.response(map[string]any{
+		"clientDataJSON":    clientData,
+		"authenticatorData": authData,
+		"signature":         sig,
+		"userHandle":        a.userHandle,
+	})
+}
+
+func (a *softAuthenticator) response(fields map[string]any) []byte {
+	encoded := make(map[string]string, len(fields))
+	for k, v := range fields {
+		encoded[k] = base64.RawURLEncoding.EncodeToString(v.([]byte))
+	}
+	id := base64.RawURLEncoding.EncodeToString(a.credID)
+	b, err := json.Marshal(map[string]any{"id": id, "rawId": id, "type": "public-key", "response": encoded})
+	if err != nil {
+		a.t.Fatalf("marshal response: %v", err)
+	}
+	return b
+}
+
+func newTestPasskeyUsecase(t *testing.T) (*PasskeyUsecase, *fakePasskeyRepo) {
+	t.Helper()
+
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          testRPID,
+		RPDisplayName: "Todos",
+		RPOrigins:     []string{testOrigin},
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		},
+	})
+	if err != nil {
+		t.Fatalf("configure webauthn: %v", err)
+	}
+
+	repo := &fakePasskeyRepo{sessions: make(map[string]models.WebAuthnSession)}
+	users := &fakeUserRepo{users: map[models.UserID]models.User{
+		7: {ID: 7, Username: "alice", Email: "alice@example.com"},
+	}}
+	return NewPasskeyUsecase(repo, users, w), repo
+}
+
+func registerPasskey(t *testing.T, u *PasskeyUsecase, a *softAuthenticator) (models.Passkey, error) {
+	t.Helper()
+
+	ceremony, err := u.BeginRegistration(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	return u.FinishRegistration(context.Background(), 7, ceremony.SessionID, "laptop", a.create(ceremony.Options))
+}
+
+func loginWithPasskey(t *testing.T, u *PasskeyUsecase, a *softAuthenticator) (models.UserID, error) {
+	t.Helper()
+
+	ceremony, err := u.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	return u.FinishLogin(context.Background(), ceremony.SessionID, a.get(ceremony.Options))
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	u, repo := newTestPasskeyUsecase(t)
+	a := newSoftAuthenticator(t)
+
+	passkey, err := registerPasskey(t, u, a)
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if passkey.UserID != 7 || passkey.Name != "laptop" || string(passkey.CredentialID) != string(a.credID) {
+		t.Fatalf("got %+v", passkey)
+	}
+
+	for i := 0; i < 2; i++ {
+		userID, err := loginWithPasskey(t, u, a)
+		if err != nil || userID != 7 {
+			t.Fatalf("login %d: got %d, %v", i, userID, err)
+		}
+	}
+	if repo.passkeys[0].SignCount != a.signCount {
+		t.Fatalf("stored sign count %d, want %d", repo.passkeys[0].SignCount, a.signCount)
+	}
+
+	// The same authenticator can't be registered twice.
+	if _, err := registerPasskey(t, u, a); !errors.Is(err, e.ErrPasskeyAlreadyExists) {
+		t.Fatalf("got %v registering twice", err)
+	}
+}
+
+func TestPasskeyLoginSessionIsSingleUse(t *testing.T) {
+	u, _ := newTestPasskeyUsecase(t)
+	a := newSoftAuthenticator(t)
+	if _, err := registerPasskey(t, u, a); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	ceremony, err := u.BeginLogin(context.Background())
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	response := a.get(ceremony.Options)
+	if _, err := u.FinishLogin(context.Background(), ceremony.SessionID, response); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := u.FinishLogin(context.Background(), ceremony.SessionID, response); !errors.Is(err, e.ErrInvalidWebAuthnSession) {
+		t.Fatalf("got %v replaying the login, want %v", err, e.ErrInvalidWebAuthnSession)
+	}
+}
+
+func TestPasskeySignCounterRegression(t *testing.T) {
+	u, repo := newTestPasskeyUsecase(t)
+	a := newSoftAuthenticator(t)
+	if _, err := registerPasskey(t, u, a); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	a.signCount = 9
+	if _, err := loginWithPasskey(t, u, a); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// A clone that signs with an older counter is refused and gets the passkey
+	// flagged, which also locks out the original.
+	a.signCount = 4
+	if _, err := loginWithPasskey(t, u, a); !errors.Is(err, e.ErrInvalidPasskey) {
+		t.Fatalf("got %v for a counter that went backwards, want %v", err, e.ErrInvalidPasskey)
+	}
+	if !repo.passkeys[0].CloneWarning {
+		t.Fatal("passkey wasn't flagged")
+	}
+
+	a.signCount = 20
+	if _, err := loginWithPasskey(t, u, a); !errors.Is(err, e.ErrInvalidPasskey) {
+		t.Fatalf("got %v for a flagged passkey, want %v", err, e.ErrInvalidPasskey)
+	}
+}
+
+func TestPasskeyWrongOrigin(t *testing.T) {
+	u, repo := newTestPasskeyUsecase(t)
+	a := newSoftAuthenticator(t)
+
+	a.origin = "https://example.com.evil.test"
+	if _, err := registerPasskey(t, u, a); !errors.Is(err, e.ErrInvalidPasskey) {
+		t.Fatalf("got %v registering from another origin, want %v", err, e.ErrInvalidPasskey)
+	}
+	if len(repo.passkeys) != 0 {
+		t.Fatalf("stored %v", repo.passkeys)
+	}
+
+	a.origin = testOrigin
+	if _, err := registerPasskey(t, u, a); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	a.origin = "https://evil.test"
+	if _, err := loginWithPasskey(t, u, a); !errors.Is(err, e.ErrInvalidPasskey) {
+		t.Fatalf("got %v logging in from another origin, want %v", err, e.ErrInvalidPasskey)
+	}
+	if repo.passkeys[0].SignCount != 0 {
+		t.Fatalf("sign count recorded for a refused login: %d", repo.passkeys[0].SignCount)
+	}
+}
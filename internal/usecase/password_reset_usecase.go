@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/mailer"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	resetTokenPrefix = "prt_"
+	resetMailTimeout = 30 * time.Second
+)
+
+type PasswordResetUsecase struct {
+	userRepo  repository.UserRepository
+	resetRepo repository.PasswordResetRepository
+	mailer    mailer.Mailer
+	// resetURL is the page that takes the token from its query and submits the new
+	// password.
+	resetURL string
+	ttl      time.Duration
+	logger   *zap.Logger
+
+	wg sync.WaitGroup
+}
+
+func NewPasswordResetUsecase(userRepo repository.UserRepository, resetRepo repository.PasswordResetRepository, m mailer.Mailer, resetURL string, ttl time.Duration, logger *zap.Logger) *PasswordResetUsecase {
+	return &PasswordResetUsecase{
+		userRepo:  userRepo,
+		resetRepo: resetRepo,
+		mailer:    m,
+		resetURL:  resetURL,
+		ttl:       ttl,
+		logger:    logger,
+	}
+}
+
+// RequestReset mails a reset link to email if it belongs to a user. The work is done in
+// the background, so that neither the result nor the response time tells the caller
+// whether the email is registered.
+func (u *PasswordResetUsecase) RequestReset(ctx context.Context, email string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), resetMailTimeout)
+
+	u.wg.Add(1)
+	go func() {
+		defer u.wg.Done()
+		defer cancel()
+
+		if err := u.sendResetLink(ctx, email); err != nil {
+			u.logger.Error("failed to send password reset link", zap.Error(err))
+		}
+	}()
+}
+
+func (u *PasswordResetUsecase) sendResetLink(ctx context.Context, email string) error {
+	user, err := u.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, e.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, hash, err := auth.GenerateOpaqueToken(resetTokenPrefix)
+	if err != nil {
+		return err
+	}
+
+	err = u.resetRepo.CreatePasswordResetToken(ctx, models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(u.ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(u.resetURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to choose a new password. "+
+			"It expires in %d minutes and can be used once.\n\n%s\n\n"+
+			"If you didn't ask to reset your password, you can ignore this email.\n",
+			user.Username, int(u.ttl.Minutes()), link),
+	})
+}
+
+// ResetPassword sets the password of the user the token was issued to, ends all of
+// their sessions, including the access tokens of their logins, and revokes the access
+// of their OAuth clients. Personal access tokens keep working: users create them for
+// their own scripts, and can review them at /api/v1/users/me/tokens.
+//
+// The token is looked up before the password is hashed, so that guessing tokens doesn't
+// cost an Argon2 hash each. It is only consumed with the new password, which fails if
+// a concurrent reset used it in the meantime.
+func (u *PasswordResetUsecase) ResetPassword(ctx context.Context, token, password string) error {
+	tokenHash := auth.HashOpaqueToken(token)
+	if _, err := u.resetRepo.GetPasswordResetToken(ctx, tokenHash); err != nil {
+		return err
+	}
+
+	hashedPassword, err := auth.HashPassword(password, auth.DefaultArgonParams)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.resetRepo.ResetPassword(ctx, tokenHash, hashedPassword)
+	return err
+}
+
+// Wait blocks until the reset links being sent are done.
+func (u *PasswordResetUsecase) Wait() {
+	u.wg.Wait()
+}
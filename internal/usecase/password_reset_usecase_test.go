@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+)
+
+type fakePasswordResetRepo struct {
+	repository.PasswordResetRepository
+	tokens map[string]models.PasswordResetToken
+	// resets are the password hashes ResetPassword was called with.
+	resets []string
+}
+
+func (r *fakePasswordResetRepo) GetPasswordResetToken(_ context.Context, tokenHash string) (models.PasswordResetToken, error) {
+	token, ok := r.tokens[tokenHash]
+	if !ok || !time.Now().Before(token.ExpiresAt) {
+		return models.PasswordResetToken{}, e.ErrInvalidResetToken
+	}
+	return token, nil
+}
+
+func (r *fakePasswordResetRepo) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (models.UserID, error) {
+	r.resets = append(r.resets, passwordHash)
+	token, err := r.GetPasswordResetToken(ctx, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+	delete(r.tokens, tokenHash)
+	return token.UserID, nil
+}
+
+func TestResetPasswordChecksTokenBeforeHashing(t *testing.T) {
+	live, liveHash, err := auth.GenerateOpaqueToken(resetTokenPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, expiredHash, err := auth.GenerateOpaqueToken(resetTokenPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &fakePasswordResetRepo{tokens: map[string]models.PasswordResetToken{
+		liveHash:    {UserID: 1, TokenHash: liveHash, ExpiresAt: time.Now().Add(time.Hour)},
+		expiredHash: {UserID: 1, TokenHash: expiredHash, ExpiresAt: time.Now().Add(-time.Minute)},
+	}}
+	uc := NewPasswordResetUsecase(nil, repo, nil, "", time.Hour, nil)
+
+	// Unknown and expired tokens are refused before the password is hashed.
+	for _, token := range []string{"prt_unknown", expired} {
+		if err := uc.ResetPassword(context.Background(), token, "new password"); !errors.Is(err, e.ErrInvalidResetToken) {
+			t.Fatalf("ResetPassword(%q) err = %v, want ErrInvalidResetToken", token, err)
+		}
+	}
+	if len(repo.resets) != 0 {
+		t.Fatalf("hashed the password for %d invalid tokens", len(repo.resets))
+	}
+
+	if err := uc.ResetPassword(context.Background(), live, "new password"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if len(repo.resets) != 1 {
+		t.Fatalf("%d resets, want 1", len(repo.resets))
+	}
+	if ok, err := auth.VerifyPassword("new password", repo.resets[0]); err != nil || !ok {
+		t.Fatalf("stored hash doesn't verify the new password: %v", err)
+	}
+
+	// The token is single-use.
+	if err := uc.ResetPassword(context.Background(), live, "another password"); !errors.Is(err, e.ErrInvalidResetToken) {
+		t.Fatalf("reusing the token: err = %v, want ErrInvalidResetToken", err)
+	}
+}
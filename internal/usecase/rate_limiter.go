@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/ratelimit"
+	"go.uber.org/zap"
+)
+
+// Rate limits apply to groups of routes.
+const (
+	// RateLimitGroupAuth are the public routes that log in, register or use emailed
+	// tokens.
+	RateLimitGroupAuth = "auth"
+	// RateLimitGroupAPI are the other routes.
+	RateLimitGroupAPI = "api"
+)
+
+// RateLimitKind is what a rate limit counts requests by.
+type RateLimitKind string
+
+const (
+	// RateLimitUser counts the requests a user makes with the access tokens of logins.
+	RateLimitUser RateLimitKind = "user"
+	// RateLimitKey counts the requests made with a personal access token, or by an OAuth
+	// client for a user.
+	RateLimitKey RateLimitKind = "key"
+	// RateLimitIP counts the requests from a client address, whoever makes them.
+	RateLimitIP RateLimitKind = "ip"
+)
+
+// RateLimit allows Requests per Period to a route group, in bursts of up to Requests.
+type RateLimit struct {
+	Group    string
+	Kind     RateLimitKind
+	Requests int
+	Period   time.Duration
+}
+
+// interval is the time it takes for one request to be allowed again.
+func (l RateLimit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// RateLimitResult describes the most restrictive limit that applied to a request. Limit
+// is zero if none did.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Period    time.Duration
+	Remaining int
+	// Reset is how long until the limit is fully available again.
+	Reset time.Duration
+	// RetryAfter is how long until a refused request would be allowed.
+	RetryAfter time.Duration
+}
+
+// Headers returns the RateLimit-* header fields describing r, sent by the REST API and
+// as gRPC metadata.
+func (r RateLimitResult) Headers() map[string]string {
+	return map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(r.Limit),
+		"RateLimit-Remaining": strconv.Itoa(r.Remaining),
+		"RateLimit-Reset":     strconv.FormatInt(ceilSeconds(r.Reset), 10),
+		"RateLimit-Policy":    fmt.Sprintf("%d;w=%d", r.Limit, ceilSeconds(r.Period)),
+	}
+}
+
+// Combine returns the more restrictive of r and other, as Allow does for the limits it
+// applies. It joins the results of limits applied at different stages of a request.
+func (r RateLimitResult) Combine(other RateLimitResult) RateLimitResult {
+	if other.Limit != 0 && (r.Limit == 0 || moreRestrictive(other, r)) {
+		return other
+	}
+	return r
+}
+
+// RetryAfterSeconds is the Retry-After of a refused request.
+func (r RateLimitResult) RetryAfterSeconds() int64 {
+	return ceilSeconds(r.RetryAfter)
+}
+
+// RateLimiter applies rate limits with GCRA, a token bucket that only has to store the
+// time at which each bucket is full again.
+type RateLimiter struct {
+	store  ratelimit.Store
+	limits map[string][]RateLimit
+	logger *zap.Logger
+}
+
+func NewRateLimiter(store ratelimit.Store, limits []RateLimit, logger *zap.Logger) *RateLimiter {
+	byGroup := make(map[string][]RateLimit)
+	for _, l := range limits {
+		if l.Requests > 0 && l.interval() > 0 {
+			byGroup[l.Group] = append(byGroup[l.Group], l)
+		}
+	}
+	return &RateLimiter{store: store, limits: byGroup, logger: logger}
+}
+
+// Allow counts a request to group by caller, which is nil for unauthenticated requests,
+// from ip, which may be empty. Only the limits of the subjects given apply, so that the
+// limits by address can be applied before the request is authenticated, and those by
+// caller after. A request refused by one limit may still have been counted by another. Requests are allowed if the store fails, so that an outage of the
+// database doesn't take the API down with it.
+func (r *RateLimiter) Allow(ctx context.Context, group string, caller *models.Caller, ip string) RateLimitResult {
+	result := RateLimitResult{Allowed: true}
+	for _, l := range r.limits[group] {
+		id := subjectID(l.Kind, caller, ip)
+		if id == "" {
+			continue
+		}
+
+		res, err := r.take(ctx, l, group+":"+string(l.Kind)+":"+id)
+		if err != nil {
+			r.logger.Warn("failed to apply rate limit", zap.String("group", group), zap.Error(err))
+			continue
+		}
+		result = result.Combine(res)
+	}
+	return result
+}
+
+// Sweep forgets the keys whose limits are fully available again.
+func (r *RateLimiter) Sweep(ctx context.Context) error {
+	return r.store.DeleteExpired(ctx)
+}
+
+func (r *RateLimiter) take(ctx context.Context, l RateLimit, key string) (RateLimitResult, error) {
+	interval := l.interval()
+	tat, now, ok, err := r.store.Advance(ctx, key, interval, l.Period)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	res := RateLimitResult{
+		Allowed: ok,
+		Limit:   l.Requests,
+		Period:  l.Period,
+		Reset:   tat.Sub(now),
+	}
+	if ok {
+		res.Remaining = int(now.Add(l.Period).Sub(tat) / interval)
+	} else {
+		res.RetryAfter = tat.Add(interval).Sub(now.Add(l.Period))
+	}
+	return res, nil
+}
+
+func subjectID(kind RateLimitKind, caller *models.Caller, ip string) string {
+	switch kind {
+	case RateLimitUser:
+		if caller != nil && caller.Key == "" {
+			return strconv.FormatInt(int64(caller.UserID), 10)
+		}
+	case RateLimitKey:
+		if caller != nil {
+			return caller.Key
+		}
+	case RateLimitIP:
+		return ip
+	}
+	return ""
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// moreRestrictive reports whether a is closer to being refused than b.
+func moreRestrictive(a, b RateLimitResult) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
@@ -2,7 +2,6 @@ package usecase
 
 import (
 	"context"
-	"errors"
 	"strings"
 
 	"github.com/mrxacker/go-to-do-app/internal/dto"
@@ -11,6 +10,8 @@ import (
 	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
 )
 
+// TodoUsecase doesn't publish events itself: the repository records them in the outbox
+// in the same transaction as each write.
 type TodoUsecase struct {
 	repo repository.TodoRepository
 }
@@ -19,13 +20,21 @@ func NewTodoUsecase(r repository.TodoRepository) *TodoUsecase {
 	return &TodoUsecase{repo: r}
 }
 
-func (u *TodoUsecase) CreateTodo(ctx context.Context, req dto.CreateTodoRequest) (models.ToDoID, error) {
-	if strings.TrimSpace(req.Title) == "" {
-		return 0, errors.New("title is required")
+func validateTitle(title string) error {
+	if strings.TrimSpace(title) == "" {
+		return e.ErrTodoTitleRequired
+	}
+
+	if len(title) > 200 {
+		return e.ErrTodoTitleTooLong
 	}
 
-	if len(req.Title) > 200 {
-		return 0, errors.New("title is too long")
+	return nil
+}
+
+func (u *TodoUsecase) CreateTodo(ctx context.Context, req dto.CreateTodoRequest) (models.ToDoID, error) {
+	if err := validateTitle(req.Title); err != nil {
+		return 0, err
 	}
 
 	todo := models.ToDo{
@@ -37,6 +46,43 @@ func (u *TodoUsecase) CreateTodo(ctx context.Context, req dto.CreateTodoRequest)
 	return u.repo.CreateTodo(ctx, todo)
 }
 
+// ImportTodos creates the valid todos of reqs in one transaction and rejects the others.
+// The results are in the order of reqs. An error means that none were created.
+func (u *TodoUsecase) ImportTodos(ctx context.Context, reqs []dto.CreateTodoRequest) ([]dto.ImportTodoResult, error) {
+	results := make([]dto.ImportTodoResult, len(reqs))
+	todos := make([]models.ToDo, 0, len(reqs))
+	for i, req := range reqs {
+		if err := validateTitle(req.Title); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		todos = append(todos, models.ToDo{
+			UserID:      req.UserID,
+			Title:       req.Title,
+			Description: req.Description,
+			Completed:   req.Completed,
+		})
+	}
+
+	if len(todos) == 0 {
+		return results, nil
+	}
+
+	ids, err := u.repo.CreateTodos(ctx, todos)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].ID, ids = ids[0], ids[1:]
+		}
+	}
+
+	return results, nil
+}
+
 func (u *TodoUsecase) GetTodoByID(ctx context.Context, id models.ToDoID) (models.ToDo, error) {
 	todo, err := u.repo.GetTodoByID(ctx, id)
 	if err != nil {
@@ -46,6 +92,10 @@ func (u *TodoUsecase) GetTodoByID(ctx context.Context, id models.ToDoID) (models
 	return todo, nil
 }
 
+func (u *TodoUsecase) GetTodosByIDs(ctx context.Context, ids []models.ToDoID) ([]models.ToDo, error) {
+	return u.repo.GetTodosByIDs(ctx, ids)
+}
+
 func (u *TodoUsecase) ListTodos(ctx context.Context, req dto.GetListTodosRequest) ([]models.ToDo, error) {
 	return u.repo.ListTodos(ctx, req.UserID, req.Limit, req.Offset)
 }
@@ -77,5 +127,6 @@ func (u *TodoUsecase) UpdateTodo(ctx context.Context, req models.ToDo) error {
 		ID:          req.ID,
 		Title:       req.Title,
 		Description: req.Description,
+		Completed:   req.Completed,
 	})
 }
@@ -3,20 +3,54 @@ package usecase
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
 
+	"github.com/mrxacker/go-to-do-app/internal/dto"
 	e "github.com/mrxacker/go-to-do-app/internal/errors"
 	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
 	"github.com/mrxacker/go-to-do-app/internal/models"
 	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
 )
 
+const refreshTokenPrefix = "rt_"
+
+// dummyPasswordHash is checked against when logging in with an unknown email, so that
+// it takes as long as a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("", auth.DefaultArgonParams)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// VerificationPolicy decides what users can do before they verify their email.
+type VerificationPolicy string
+
+const (
+	// VerificationOptional doesn't restrict unverified users.
+	VerificationOptional VerificationPolicy = "optional"
+	// VerificationRequired refuses to log in unverified users.
+	VerificationRequired VerificationPolicy = "required"
+	// VerificationReadOnly issues read-only access tokens to unverified users.
+	VerificationReadOnly VerificationPolicy = "read_only"
+)
+
 type UserUseCase struct {
 	userRepo   repository.UserRepository
+	tokenRepo  repository.RefreshTokenRepository
+	mfa        *MFAUsecase
+	passkeys   *PasskeyUsecase
+	oidc       *OIDCUsecase
+	throttle   *LoginThrottle
 	jwtService *auth.JWTService
+	refreshTTL time.Duration
+	policy     VerificationPolicy
 }
 
-func NewUserUseCase(r repository.UserRepository, jwtService *auth.JWTService) *UserUseCase {
-	return &UserUseCase{userRepo: r, jwtService: jwtService}
+func NewUserUseCase(r repository.UserRepository, tokenRepo repository.RefreshTokenRepository, mfa *MFAUsecase, passkeys *PasskeyUsecase, oidc *OIDCUsecase, throttle *LoginThrottle, jwtService *auth.JWTService, refreshTTL time.Duration, policy VerificationPolicy) *UserUseCase {
+	return &UserUseCase{userRepo: r, tokenRepo: tokenRepo, mfa: mfa, passkeys: passkeys, oidc: oidc, throttle: throttle, jwtService: jwtService, refreshTTL: refreshTTL, policy: policy}
 }
 
 func (u *UserUseCase) CreateUser(ctx context.Context, user models.User) (models.UserID, error) {
@@ -47,25 +81,246 @@ func (u *UserUseCase) CreateUser(ctx context.Context, user models.User) (models.
 	return u.userRepo.CreateUser(ctx, user)
 }
 
-func (u *UserUseCase) LoginUser(ctx context.Context, email, password string) (string, error) {
-	user, err := u.userRepo.GetUserByEmail(ctx, email)
+func (u *UserUseCase) GetUsersByIDs(ctx context.Context, ids []models.UserID) ([]models.User, error) {
+	return u.userRepo.GetUsersByIDs(ctx, ids)
+}
+
+// LoginUser checks the user's password. For users with two-factor authentication it
+// returns only an MFA token, to be exchanged with a code by CompleteMFALogin.
+//
+// Failed logins are throttled per email and per client address ip, which may be empty.
+// Unknown emails fail with ErrInvalidIdentifier after the same work as wrong passwords.
+func (u *UserUseCase) LoginUser(ctx context.Context, email, password, ip string) (dto.AuthResponse, error) {
+	var user models.User
+	err := u.throttle.Attempt(ctx, email, ip, func() (bool, error) {
+		var err error
+		user, err = u.userRepo.GetUserByEmail(ctx, email)
+		found := err == nil
+		if err != nil && !errors.Is(err, e.ErrUserNotFound) {
+			return false, err
+		}
+
+		hash := user.PasswordHash
+		if !found {
+			hash = dummyPasswordHash()
+		}
+		ok, err := auth.VerifyPassword(password, hash)
+		return ok && found, err
+	})
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	return u.startSessionOrChallenge(ctx, user)
+}
+
+// LoginWithOIDC logs in with the code an OpenID Connect provider redirected back with,
+// after OIDCUsecase.BeginLogin. Like LoginUser, it returns only an MFA token for users
+// with two-factor authentication.
+func (u *UserUseCase) LoginWithOIDC(ctx context.Context, provider, state, code string) (dto.AuthResponse, error) {
+	userID, err := u.oidc.FinishLogin(ctx, provider, state, code)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, e.ErrUserNotFound) {
+			return dto.AuthResponse{}, e.ErrOIDCLoginFailed
+		}
+		return dto.AuthResponse{}, err
+	}
+
+	return u.startSessionOrChallenge(ctx, user)
+}
+
+// CompleteMFALogin finishes logging in with the MFA token from LoginUser and a TOTP or
+// recovery code.
+func (u *UserUseCase) CompleteMFALogin(ctx context.Context, mfaToken, code string) (dto.AuthResponse, error) {
+	userID, err := u.mfa.VerifyChallenge(ctx, mfaToken, code)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, e.ErrUserNotFound) {
+			return dto.AuthResponse{}, e.ErrInvalidMFAToken
+		}
+		return dto.AuthResponse{}, err
+	}
+
+	return u.startSession(ctx, user)
+}
+
+// LoginWithPasskey logs in with the browser's response to a challenge from
+// PasskeyUsecase.BeginLogin, as an alternative to LoginUser. Passkeys verify the user
+// themselves, so no TOTP code is asked for.
+func (u *UserUseCase) LoginWithPasskey(ctx context.Context, sessionID string, response []byte) (dto.AuthResponse, error) {
+	userID, err := u.passkeys.FinishLogin(ctx, sessionID, response)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, e.ErrUserNotFound) {
+			return dto.AuthResponse{}, e.ErrInvalidPasskey
+		}
+		return dto.AuthResponse{}, err
+	}
+
+	return u.startSession(ctx, user)
+}
+
+// startSessionOrChallenge starts a session for users without two-factor authentication,
+// and returns an MFA token for the others.
+func (u *UserUseCase) startSessionOrChallenge(ctx context.Context, user models.User) (dto.AuthResponse, error) {
+	mfaRequired, err := u.mfa.Required(ctx, user.ID)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+	if mfaRequired {
+		token, err := u.mfa.Challenge(user.ID)
+		if err != nil {
+			return dto.AuthResponse{}, err
+		}
+		return dto.AuthResponse{MFAToken: token}, nil
+	}
+
+	return u.startSession(ctx, user)
+}
+
+// startSession issues the tokens of a new login.
+func (u *UserUseCase) startSession(ctx context.Context, user models.User) (dto.AuthResponse, error) {
+	familyID, err := auth.GenerateID()
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	res, token, err := u.issueTokens(user, familyID)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	if err := u.tokenRepo.CreateRefreshToken(ctx, token); err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	return res, nil
+}
+
+// RefreshTokens rotates refreshToken. A token that was already rotated is being reused,
+// by an attacker or by the client after it was stolen, so every token of its family is
+// revoked and the user has to log in again.
+func (u *UserUseCase) RefreshTokens(ctx context.Context, refreshToken string) (dto.AuthResponse, error) {
+	current, err := u.tokenRepo.GetRefreshTokenByHash(ctx, auth.HashOpaqueToken(refreshToken))
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	if current.RevokedAt != nil || !time.Now().Before(current.ExpiresAt) {
+		return dto.AuthResponse{}, e.ErrInvalidRefreshToken
+	}
+	if current.RotatedAt != nil {
+		return dto.AuthResponse{}, u.revokeReused(ctx, current)
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, current.UserID)
+	if err != nil {
+		if errors.Is(err, e.ErrUserNotFound) {
+			return dto.AuthResponse{}, e.ErrInvalidRefreshToken
+		}
+		return dto.AuthResponse{}, err
+	}
+
+	res, next, err := u.issueTokens(user, current.FamilyID)
+	if err != nil {
+		return dto.AuthResponse{}, err
+	}
+
+	if err := u.tokenRepo.RotateRefreshToken(ctx, current.ID, next); err != nil {
+		if errors.Is(err, e.ErrRefreshTokenReused) {
+			return dto.AuthResponse{}, u.revokeReused(ctx, current)
+		}
+		return dto.AuthResponse{}, err
+	}
+
+	return res, nil
+}
+
+func (u *UserUseCase) revokeReused(ctx context.Context, token models.RefreshToken) error {
+	if err := u.tokenRepo.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	return e.ErrRefreshTokenReused
+}
+
+// Logout revokes the family of refreshToken, which ends the session on every device it
+// was refreshed on. Unknown tokens are ignored, so logging out twice succeeds.
+func (u *UserUseCase) Logout(ctx context.Context, refreshToken string) error {
+	token, err := u.tokenRepo.GetRefreshTokenByHash(ctx, auth.HashOpaqueToken(refreshToken))
 	if err != nil {
-		return "", err
+		if errors.Is(err, e.ErrInvalidRefreshToken) {
+			return nil
+		}
+		return err
 	}
 
-	// Here you would normally check the password hash
-	ok, err := auth.VerifyPassword(password, user.PasswordHash)
+	return u.tokenRepo.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+}
+
+// ChangeEmail sets a new email for the user, who has to confirm their password. The new
+// email has to be verified again; a link is mailed to it once the change is published.
+func (u *UserUseCase) ChangeEmail(ctx context.Context, id models.UserID, password, email string) error {
+	user, err := u.userRepo.GetUserByID(ctx, id)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	if err := u.throttle.VerifyPassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	if email == user.Email {
+		return nil
+	}
+	return u.userRepo.UpdateEmail(ctx, id, email)
+}
+
+// issueTokens returns a new access token and a refresh token of familyID, which the
+// caller has to store. The verification policy is applied here, so that it also holds
+// for sessions that began before the user's email changed, as is the disabling of users.
+func (u *UserUseCase) issueTokens(user models.User, familyID string) (dto.AuthResponse, models.RefreshToken, error) {
+	if user.Disabled() {
+		return dto.AuthResponse{}, models.RefreshToken{}, e.ErrUserDisabled
 	}
-	if !ok {
-		return "", e.ErrInvalidIdentifier
+	if u.policy == VerificationRequired && !user.EmailVerified() {
+		return dto.AuthResponse{}, models.RefreshToken{}, e.ErrEmailNotVerified
 	}
+	readOnly := u.policy == VerificationReadOnly && !user.EmailVerified()
 
-	token, err := u.jwtService.GenerateToken(user)
+	accessToken, err := u.jwtService.GenerateToken(user, readOnly, familyID)
 	if err != nil {
-		return "", err
+		return dto.AuthResponse{}, models.RefreshToken{}, err
 	}
 
-	return token, nil
+	refreshToken, hash, err := auth.GenerateOpaqueToken(refreshTokenPrefix)
+	if err != nil {
+		return dto.AuthResponse{}, models.RefreshToken{}, err
+	}
+
+	res := dto.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(u.jwtService.TTL().Seconds()),
+	}
+	token := models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(u.refreshTTL),
+	}
+	return res, token, nil
 }
@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrxacker/go-to-do-app/internal/dto"
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/auth"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+)
+
+type fakeRefreshTokenRepo struct {
+	repository.RefreshTokenRepository
+
+	mu     sync.Mutex
+	tokens map[string]models.RefreshToken
+	nextID models.RefreshTokenID
+}
+
+func newFakeRefreshTokenRepo() *fakeRefreshTokenRepo {
+	return &fakeRefreshTokenRepo{tokens: make(map[string]models.RefreshToken)}
+}
+
+func (r *fakeRefreshTokenRepo) CreateRefreshToken(_ context.Context, token models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.create(token)
+	return nil
+}
+
+func (r *fakeRefreshTokenRepo) create(token models.RefreshToken) {
+	r.nextID++
+	token.ID = r.nextID
+	token.CreatedAt = time.Now()
+	r.tokens[token.TokenHash] = token
+}
+
+func (r *fakeRefreshTokenRepo) GetRefreshTokenByHash(_ context.Context, hash string) (models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[hash]
+	if !ok {
+		return models.RefreshToken{}, e.ErrInvalidRefreshToken
+	}
+	return token, nil
+}
+
+// RotateRefreshToken checks and marks the token under one lock, like the conditional
+// UPDATE of Postgres does.
+func (r *fakeRefreshTokenRepo) RotateRefreshToken(_ context.Context, id models.RefreshTokenID, next models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, token := range r.tokens {
+		if token.ID != id {
+			continue
+		}
+		if token.RotatedAt != nil || token.RevokedAt != nil {
+			return e.ErrRefreshTokenReused
+		}
+		now := time.Now()
+		token.RotatedAt = &now
+		r.tokens[hash] = token
+		r.create(next)
+		return nil
+	}
+	return e.ErrRefreshTokenReused
+}
+
+func (r *fakeRefreshTokenRepo) RevokeRefreshTokenFamily(_ context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for hash, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.tokens[hash] = token
+		}
+	}
+	return nil
+}
+
+func (r *fakeRefreshTokenRepo) RefreshTokenFamilyRevoked(_ context.Context, familyID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type refreshFixture struct {
+	users  *UserUseCase
+	access *AccessTokenUsecase
+	user   models.User
+}
+
+func newRefreshFixture() refreshFixture {
+	user := models.User{ID: 1, Email: "alice@example.com", Role: models.RoleUser}
+	users := &fakeUserRepo{users: map[models.UserID]models.User{user.ID: user}}
+	tokens := newFakeRefreshTokenRepo()
+	jwt := auth.NewJWTService(auth.NewHMACKeySet("test-secret"), "test", "test", time.Hour)
+
+	return refreshFixture{
+		users:  NewUserUseCase(users, tokens, nil, nil, nil, nil, jwt, time.Hour, VerificationOptional),
+		access: NewAccessTokenUsecase(nil, users, tokens, nil, jwt, VerificationOptional),
+		user:   user,
+	}
+}
+
+func (f refreshFixture) login(t *testing.T) dto.AuthResponse {
+	t.Helper()
+
+	res, err := f.users.startSession(context.Background(), f.user)
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	return res
+}
+
+func TestRefreshTokensDetectsReuse(t *testing.T) {
+	f := newRefreshFixture()
+	ctx := context.Background()
+	login := f.login(t)
+	other := f.login(t)
+
+	rotated, err := f.users.RefreshTokens(ctx, login.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if rotated.RefreshToken == login.RefreshToken {
+		t.Fatal("refresh returned the same refresh token")
+	}
+
+	// The first token was rotated, so presenting it again means it was stolen.
+	if _, err := f.users.RefreshTokens(ctx, login.RefreshToken); !errors.Is(err, e.ErrRefreshTokenReused) {
+		t.Fatalf("reuse: err = %v, want ErrRefreshTokenReused", err)
+	}
+
+	// The whole family is revoked: the token the legitimate client holds, and the
+	// access tokens issued with the family.
+	if _, err := f.users.RefreshTokens(ctx, rotated.RefreshToken); !errors.Is(err, e.ErrInvalidRefreshToken) {
+		t.Fatalf("refresh after reuse: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	for _, token := range []string{login.AccessToken, rotated.AccessToken} {
+		if _, err := f.access.Authenticate(ctx, token); !errors.Is(err, e.ErrInvalidAccessToken) {
+			t.Fatalf("access token of revoked family: err = %v, want ErrInvalidAccessToken", err)
+		}
+	}
+
+	// Other logins of the user are left alone.
+	if _, err := f.access.Authenticate(ctx, other.AccessToken); err != nil {
+		t.Fatalf("access token of another login: %v", err)
+	}
+	if _, err := f.users.RefreshTokens(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("refresh another login: %v", err)
+	}
+}
+
+func TestRefreshTokensConcurrentReuse(t *testing.T) {
+	f := newRefreshFixture()
+	ctx := context.Background()
+	login := f.login(t)
+
+	// Only one request can rotate the token. The other one presents it again, whether
+	// it looked the token up before or after the rotation, and is treated as reuse.
+	const n = 2
+	errs := make([]error, n)
+	res := make([]dto.AuthResponse, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res[i], errs[i] = f.users.RefreshTokens(ctx, login.RefreshToken)
+		}()
+	}
+	wg.Wait()
+
+	var reused int
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			// The family was revoked by the losing request, so the winner's token
+			// doesn't survive either.
+			if _, err := f.users.RefreshTokens(ctx, res[i].RefreshToken); !errors.Is(err, e.ErrInvalidRefreshToken) {
+				t.Errorf("refresh with the winning token: err = %v, want ErrInvalidRefreshToken", err)
+			}
+		case errors.Is(err, e.ErrRefreshTokenReused):
+			reused++
+		default:
+			t.Fatalf("refresh: %v", err)
+		}
+	}
+	if reused != 1 {
+		t.Fatalf("%d of %d concurrent refreshes were reuse, want 1", reused, n)
+	}
+}
+
+func TestLogoutRevokesFamily(t *testing.T) {
+	f := newRefreshFixture()
+	ctx := context.Background()
+	login := f.login(t)
+
+	rotated, err := f.users.RefreshTokens(ctx, login.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if err := f.users.Logout(ctx, rotated.RefreshToken); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+
+	if _, err := f.users.RefreshTokens(ctx, rotated.RefreshToken); !errors.Is(err, e.ErrInvalidRefreshToken) {
+		t.Fatalf("refresh after logout: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := f.access.Authenticate(ctx, rotated.AccessToken); !errors.Is(err, e.ErrInvalidAccessToken) {
+		t.Fatalf("access token after logout: err = %v, want ErrInvalidAccessToken", err)
+	}
+	if err := f.users.Logout(ctx, rotated.RefreshToken); err != nil {
+		t.Fatalf("second logout: %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net/url"
+	"time"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/infrastructure/webhook"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+	"github.com/mrxacker/go-to-do-app/internal/ports/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	webhookMaxAttempts  = 8
+	webhookDisableAfter = 15
+	webhookBaseBackoff  = 10 * time.Second
+	webhookMaxBackoff   = time.Hour
+	webhookClaimBatch   = 50
+	webhookClaimLease   = time.Minute
+)
+
+type WebhookUsecase struct {
+	repo   repository.WebhookRepository
+	client *webhook.Client
+	logger *zap.Logger
+}
+
+func NewWebhookUsecase(r repository.WebhookRepository, client *webhook.Client, logger *zap.Logger) *WebhookUsecase {
+	return &WebhookUsecase{repo: r, client: client, logger: logger}
+}
+
+type webhookEnvelope struct {
+	ID        string           `json:"id"`
+	Type      models.EventType `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Data      json.RawMessage  `json:"data"`
+}
+
+// validateWebhook also refuses URLs whose host resolves to a loopback, private or
+// link-local address. The client checks the address again when it connects.
+func validateWebhook(ctx context.Context, rawURL string, events []models.EventType) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return e.ErrInvalidWebhookURL
+	}
+	if err := webhook.CheckHost(ctx, u.Hostname()); err != nil {
+		return e.ErrInvalidWebhookURL
+	}
+
+	for _, ev := range events {
+		valid := false
+		for _, known := range models.TodoEventTypes {
+			if ev == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return e.ErrInvalidWebhookEvent
+		}
+	}
+
+	return nil
+}
+
+func (u *WebhookUsecase) CreateWebhook(ctx context.Context, userID models.UserID, rawURL string, events []models.EventType) (models.Webhook, error) {
+	if err := validateWebhook(ctx, rawURL, events); err != nil {
+		return models.Webhook{}, err
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	id, err := u.repo.CreateWebhook(ctx, models.Webhook{
+		UserID: userID,
+		URL:    rawURL,
+		Secret: secret,
+		Events: events,
+	})
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	return u.repo.GetWebhookByID(ctx, id)
+}
+
+// GetWebhook returns the webhook only if it belongs to userID, so that other users'
+// webhooks are indistinguishable from missing ones.
+func (u *WebhookUsecase) GetWebhook(ctx context.Context, userID models.UserID, id models.WebhookID) (models.Webhook, error) {
+	hook, err := u.repo.GetWebhookByID(ctx, id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	if hook.UserID != userID {
+		return models.Webhook{}, e.ErrWebhookNotFound
+	}
+
+	return hook, nil
+}
+
+func (u *WebhookUsecase) ListWebhooks(ctx context.Context, userID models.UserID) ([]models.Webhook, error) {
+	return u.repo.ListWebhooks(ctx, userID)
+}
+
+func (u *WebhookUsecase) UpdateWebhook(ctx context.Context, userID models.UserID, req models.Webhook) (models.Webhook, error) {
+	if err := validateWebhook(ctx, req.URL, req.Events); err != nil {
+		return models.Webhook{}, err
+	}
+
+	hook, err := u.GetWebhook(ctx, userID, req.ID)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	hook.URL = req.URL
+	hook.Events = req.Events
+	hook.Active = req.Active
+	if err := u.repo.UpdateWebhook(ctx, hook); err != nil {
+		return models.Webhook{}, err
+	}
+
+	return u.repo.GetWebhookByID(ctx, hook.ID)
+}
+
+func (u *WebhookUsecase) DeleteWebhook(ctx context.Context, userID models.UserID, id models.WebhookID) error {
+	if _, err := u.GetWebhook(ctx, userID, id); err != nil {
+		return err
+	}
+
+	return u.repo.DeleteWebhookByID(ctx, id)
+}
+
+func (u *WebhookUsecase) ListDeliveries(ctx context.Context, userID models.UserID, id models.WebhookID, limit, offset int) ([]models.WebhookDelivery, error) {
+	if _, err := u.GetWebhook(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	return u.repo.ListDeliveries(ctx, id, limit, offset)
+}
+
+// Redeliver queues a fresh copy of a past delivery with the original payload.
+func (u *WebhookUsecase) Redeliver(ctx context.Context, userID models.UserID, id models.WebhookID, deliveryID models.WebhookDeliveryID) (models.WebhookDelivery, error) {
+	if _, err := u.GetWebhook(ctx, userID, id); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	d, err := u.repo.GetDeliveryByID(ctx, deliveryID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	if d.WebhookID != id {
+		return models.WebhookDelivery{}, e.ErrWebhookDeliveryNotFound
+	}
+
+	newID, err := u.repo.CreateDelivery(ctx, models.WebhookDelivery{
+		WebhookID:       d.WebhookID,
+		EventID:         d.EventID,
+		EventType:       d.EventType,
+		Payload:         d.Payload,
+		RedeliveredFrom: &d.ID,
+	})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	return u.repo.GetDeliveryByID(ctx, newID)
+}
+
+// Publish queues a delivery for every active webhook of the event owner that subscribes
+// to the event type. It implements events.EventPublisher and is safe to call more than
+// once for the same event.
+func (u *WebhookUsecase) Publish(ctx context.Context, event models.Event) error {
+	if event.AggregateType != models.AggregateTodo {
+		return nil
+	}
+
+	hooks, err := u.repo.ListActiveWebhooks(ctx, event.UserID, event.Type)
+	if err != nil {
+		return err
+	}
+
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(webhookEnvelope{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.OccurredAt,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, hook := range hooks {
+		_, err := u.repo.CreateDelivery(ctx, models.WebhookDelivery{
+			WebhookID: hook.ID,
+			EventID:   event.ID,
+			EventType: event.Type,
+			Payload:   payload,
+		})
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// DispatchDue sends one batch of due deliveries and returns how many were attempted.
+func (u *WebhookUsecase) DispatchDue(ctx context.Context) (int, error) {
+	deliveries, err := u.repo.ClaimDueDeliveries(ctx, webhookClaimBatch, webhookClaimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+		u.attempt(ctx, d)
+	}
+
+	return len(deliveries), nil
+}
+
+func (u *WebhookUsecase) attempt(ctx context.Context, d models.WebhookDelivery) {
+	log := u.logger.With(zap.Int64("webhook_id", int64(d.WebhookID)), zap.Int64("delivery_id", int64(d.ID)))
+
+	hook, err := u.repo.GetWebhookByID(ctx, d.WebhookID)
+	if err != nil {
+		log.Error("failed to load webhook for delivery", zap.Error(err))
+		return
+	}
+
+	res := u.client.Send(ctx, hook, d)
+
+	now := time.Now()
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.ResponseStatus = res.StatusCode
+	d.ResponseBody = res.ResponseBody
+	d.Error = ""
+	if res.Err != nil {
+		d.Error = res.Err.Error()
+	}
+
+	switch {
+	case res.Success():
+		d.Status = models.DeliverySucceeded
+	case d.Attempts >= webhookMaxAttempts:
+		d.Status = models.DeliveryFailed
+	default:
+		d.NextAttemptAt = now.Add(webhookBackoff(d.Attempts))
+	}
+
+	if err := u.repo.UpdateDelivery(ctx, d); err != nil {
+		log.Error("failed to record webhook delivery attempt", zap.Error(err))
+	}
+
+	disabled, err := u.repo.RecordWebhookResult(ctx, hook.ID, res.Success(), webhookDisableAfter)
+	if err != nil {
+		log.Error("failed to record webhook result", zap.Error(err))
+		return
+	}
+
+	if disabled {
+		log.Warn("webhook disabled after repeated failures", zap.Int("failures", webhookDisableAfter))
+	}
+}
+
+// webhookBackoff doubles the delay for every attempt, capped at webhookMaxBackoff, and
+// spreads retries over the upper half of that window.
+func webhookBackoff(attempt int) time.Duration {
+	delay := webhookMaxBackoff
+	if attempt < 20 {
+		delay = min(webhookBaseBackoff<<(attempt-1), webhookMaxBackoff)
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
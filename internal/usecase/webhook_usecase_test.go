@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	e "github.com/mrxacker/go-to-do-app/internal/errors"
+	"github.com/mrxacker/go-to-do-app/internal/models"
+)
+
+func TestValidateWebhookRefusesInternalTargets(t *testing.T) {
+	events := []models.EventType{models.EventTodoCreated}
+
+	for _, rawURL := range []string{
+		"http://localhost/hook",
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"https://10.0.0.5/hook",
+		"https://172.20.1.1/hook",
+		"https://192.168.0.10/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[fd00:ec2::254]/",
+		"http://[::ffff:127.0.0.1]/hook",
+		"ftp://93.184.216.34/hook",
+		"http:///hook",
+	} {
+		if err := validateWebhook(context.Background(), rawURL, events); !errors.Is(err, e.ErrInvalidWebhookURL) {
+			t.Errorf("validateWebhook(%q) = %v, want ErrInvalidWebhookURL", rawURL, err)
+		}
+	}
+
+	if err := validateWebhook(context.Background(), "https://93.184.216.34/hook", events); err != nil {
+		t.Errorf("validateWebhook(public) = %v, want nil", err)
+	}
+}
+
+func TestCreateWebhookRefusesInternalTargets(t *testing.T) {
+	// The repository is nil: a refused URL must not get as far as storing the webhook.
+	uc := NewWebhookUsecase(nil, nil, nil)
+
+	_, err := uc.CreateWebhook(context.Background(), 1, "http://169.254.169.254/", []models.EventType{models.EventTodoCreated})
+	if !errors.Is(err, e.ErrInvalidWebhookURL) {
+		t.Fatalf("CreateWebhook err = %v, want ErrInvalidWebhookURL", err)
+	}
+}
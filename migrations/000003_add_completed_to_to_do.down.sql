@@ -0,0 +1 @@
+ALTER TABLE to_do DROP COLUMN IF EXISTS completed;
\ No newline at end of file
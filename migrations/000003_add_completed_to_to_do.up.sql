@@ -0,0 +1 @@
+ALTER TABLE to_do ADD COLUMN completed BOOLEAN NOT NULL DEFAULT FALSE;
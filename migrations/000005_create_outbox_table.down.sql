@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_event;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS redelivered_from;
+DROP TABLE IF EXISTS outbox;
\ No newline at end of file
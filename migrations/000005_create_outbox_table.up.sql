@@ -0,0 +1,18 @@
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL UNIQUE,
+    event_type VARCHAR(50) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL;
+
+ALTER TABLE webhook_deliveries ADD COLUMN redelivered_from BIGINT REFERENCES webhook_deliveries(id) ON DELETE SET NULL;
+
+-- The relay delivers events at least once, so a replayed event must not queue a second delivery.
+CREATE UNIQUE INDEX idx_webhook_deliveries_event ON webhook_deliveries (webhook_id, event_id) WHERE redelivered_from IS NULL;
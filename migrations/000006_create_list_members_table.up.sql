@@ -0,0 +1,10 @@
+-- list_members are the users a todo list is shared with. Every user owns one list,
+-- identified by the owner's user ID.
+CREATE TABLE list_members (
+    owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (owner_id, user_id)
+);
+
+CREATE INDEX idx_list_members_user ON list_members (user_id);
@@ -0,0 +1,7 @@
+ALTER TABLE users
+    ADD COLUMN email_verified_at TIMESTAMPTZ,
+    -- email_verification_sent_at throttles how often verification links are mailed.
+    ADD COLUMN email_verification_sent_at TIMESTAMPTZ;
+
+-- Accounts created before verification existed were active already.
+UPDATE users SET email_verified_at = created_at;
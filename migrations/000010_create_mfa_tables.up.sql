@@ -0,0 +1,23 @@
+CREATE TABLE totp_credentials (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- secret is encrypted with AES-GCM, with the user ID as additional data.
+    secret BYTEA NOT NULL,
+    -- confirmed_at is set once the user proves their authenticator works; until then
+    -- logins don't ask for a code.
+    confirmed_at TIMESTAMPTZ,
+    -- last_step is the time step of the last accepted code, which can't be used again.
+    last_step BIGINT NOT NULL DEFAULT 0,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_recovery_codes_user ON recovery_codes (user_id);
@@ -0,0 +1,37 @@
+CREATE TABLE passkeys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    credential_id BYTEA NOT NULL UNIQUE,
+    -- public_key is COSE encoded.
+    public_key BYTEA NOT NULL,
+    attestation_type VARCHAR(32) NOT NULL,
+    transports TEXT[] NOT NULL DEFAULT '{}',
+    aaguid BYTEA NOT NULL,
+    -- flags are the authenticator data flags of the registration.
+    flags SMALLINT NOT NULL,
+    -- sign_count is the highest signature counter seen. Authenticators that don't keep a
+    -- counter always report 0.
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    -- clone_warning is set when a login reported a counter that didn't increase; the
+    -- passkey is refused from then on.
+    clone_warning BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_passkeys_user ON passkeys (user_id);
+
+-- webauthn_sessions hold the challenge of a registration or login until it is answered.
+CREATE TABLE webauthn_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    session_hash VARCHAR(64) NOT NULL UNIQUE,
+    ceremony VARCHAR(16) NOT NULL,
+    -- user_id is NULL for logins where the browser picks the passkey.
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    data JSONB NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webauthn_sessions_expires ON webauthn_sessions (expires_at);
@@ -0,0 +1,28 @@
+-- user_identities link users to their accounts at external OpenID Connect providers.
+CREATE TABLE user_identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(64) NOT NULL,
+    -- subject is the provider's stable ID of the user.
+    subject VARCHAR(255) NOT NULL,
+    -- email is the one the identity was linked by.
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX idx_user_identities_user ON user_identities (user_id);
+
+-- oidc_login_states hold the PKCE verifier and nonce of a login until the provider
+-- redirects back.
+CREATE TABLE oidc_login_states (
+    id BIGSERIAL PRIMARY KEY,
+    state_hash VARCHAR(64) NOT NULL UNIQUE,
+    provider VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_oidc_login_states_expires ON oidc_login_states (expires_at);
@@ -0,0 +1,15 @@
+-- access_tokens are personal access tokens, which scripts use instead of a password.
+-- Only the SHA-256 hash of a token is stored.
+CREATE TABLE access_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    -- expires_at is NULL for tokens that don't expire.
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_access_tokens_user ON access_tokens (user_id);
@@ -0,0 +1,44 @@
+-- oauth_clients are third-party apps registered by users.
+CREATE TABLE oauth_clients (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    redirect_uris TEXT[] NOT NULL,
+    -- secret_hash is NULL for public clients, which rely on PKCE alone.
+    secret_hash VARCHAR(64),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_oauth_clients_user ON oauth_clients (user_id);
+
+-- oauth_authorization_codes are exchanged once for tokens.
+CREATE TABLE oauth_authorization_codes (
+    id BIGSERIAL PRIMARY KEY,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_oauth_authorization_codes_expires ON oauth_authorization_codes (expires_at);
+
+-- oauth_tokens are the access and refresh tokens issued to clients. Only hashes of the
+-- tokens are stored.
+CREATE TABLE oauth_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    access_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    access_expires_at TIMESTAMPTZ NOT NULL,
+    refresh_expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_oauth_tokens_refresh_expires ON oauth_tokens (refresh_expires_at);
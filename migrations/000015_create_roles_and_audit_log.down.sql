@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS audit_log;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS role;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,41 @@
+-- roles are assigned to users; each grants a set of permissions.
+CREATE TABLE roles (
+    name VARCHAR(32) PRIMARY KEY
+);
+
+CREATE TABLE role_permissions (
+    role VARCHAR(32) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name) VALUES ('user'), ('support'), ('admin');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('support', 'users:read'),
+    ('support', 'users:disable'),
+    ('support', 'stats:read'),
+    ('admin', 'users:read'),
+    ('admin', 'users:disable'),
+    ('admin', 'users:delete'),
+    ('admin', 'users:roles'),
+    ('admin', 'stats:read'),
+    ('admin', 'audit:read');
+
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'user' REFERENCES roles(name),
+    -- disabled_at is set while an admin has disabled the user, who can't log in.
+    ADD COLUMN disabled_at TIMESTAMPTZ;
+
+-- audit_log records the actions of admins. It has no foreign keys, so that entries
+-- outlive the users they mention.
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id BIGINT NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    target_user_id BIGINT,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_target_user ON audit_log (target_user_id);
@@ -0,0 +1,10 @@
+-- login_attempts counts the failed logins for an account ('account:<email>') or a
+-- client address ('ip:<address>'). Counts start over after a quiet period, so rows older
+-- than that are removed as new failures come in.
+CREATE TABLE login_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INT NOT NULL,
+    last_failed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_login_attempts_last_failed_at ON login_attempts (last_failed_at);
@@ -0,0 +1,8 @@
+-- rate_limits holds the theoretical arrival time of each rate limit key: the time at
+-- which its bucket is full again. Rows whose time has passed are deleted periodically.
+CREATE TABLE rate_limits (
+    key VARCHAR(320) PRIMARY KEY,
+    tat TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_tat ON rate_limits (tat);
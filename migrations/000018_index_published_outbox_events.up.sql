@@ -0,0 +1,2 @@
+-- Published events are deleted once they are older than the outbox retention.
+CREATE INDEX idx_outbox_published ON outbox (published_at) WHERE published_at IS NOT NULL;
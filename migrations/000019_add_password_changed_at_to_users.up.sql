@@ -0,0 +1,3 @@
+-- password_changed_at is when the password was last reset. Access tokens of logins
+-- issued before it are refused.
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMPTZ;
@@ -0,0 +1,8 @@
+-- used_mfa_challenges holds the IDs of MFA tokens that completed a login, so that a
+-- token can't be used again. Rows are kept until the token would have expired anyway.
+CREATE TABLE used_mfa_challenges (
+    id VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_used_mfa_challenges_expires_at ON used_mfa_challenges (expires_at);